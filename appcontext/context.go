@@ -26,6 +26,7 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/command"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/metadata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/google/uuid"
@@ -37,6 +38,24 @@ import (
 // AppFunction is a type alias for func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{})
 type AppFunction = func(edgexcontext *Context, params ...interface{}) (bool, interface{})
 
+// SplitResult is returned by a pipeline function, along with continuePipeline true, to run the rest of the pipeline
+// once for each of the items, i.e. once for each reading of a split Event. The items are processed in order, each
+// using its own copy of the Context. The output data of each item is published by the MQTT and message bus triggers,
+// while the HTTP trigger returns the output of the last item as the response.
+type SplitResult []interface{}
+
+// PersistentStore persists data for pipeline functions, i.e. state which must survive restarts, to the Store and
+// Forward database. The data is grouped by a store key, which must not be the service key used by Store and Forward
+// for the data to retry.
+type PersistentStore interface {
+	// Store persists the data under the storeKey and returns the id assigned to it
+	Store(storeKey string, data []byte) (id string, err error)
+	// Retrieve returns the data persisted under the storeKey, keyed by id
+	Retrieve(storeKey string) (map[string][]byte, error)
+	// Remove removes the data with the id persisted under the storeKey
+	Remove(storeKey string, id string) error
+}
+
 // Context ...
 type Context struct {
 	// ID of the EdgeX Event -- will be filled for a received JSON Event
@@ -59,12 +78,33 @@ type Context struct {
 	CommandClient command.CommandClient
 	// NotificationsClient exposes Support Notification's Notifications API
 	NotificationsClient notifications.NotificationsClient
+	// DeviceClient exposes Core Metadata's Device API
+	DeviceClient metadata.DeviceClient
 	// RetryData holds the data to be stored for later retry when the pipeline function returns an error
 	RetryData []byte
 	// SecretProvider exposes the support for getting and storing secrets
 	SecretProvider security.SecretProvider
+	// PersistentStore persists data to the Store and Forward database. Only set when Store and Forward is enabled.
+	PersistentStore PersistentStore
+	// ReceivedTopic is the topic the message was received on, set by the MQTT and message bus triggers
+	ReceivedTopic string
 	// ResponseContentType is used for holding custom response type for HTTP trigger
 	ResponseContentType string
+	// ResumePipeline executes the pipeline functions following the currently executing function with the result,
+	// using a new Context. It is set by the runtime for functions which complete asynchronously, i.e. batching by
+	// time. Any OutputData set by the resumed pipeline is published to the trigger's publish topic by the MQTT and
+	// message bus triggers. The HTTP trigger can't publish it since there is no request to respond to. An error is
+	// returned when the pipeline functions fail, unless the data has been stored for retry, or publishing fails.
+	ResumePipeline func(result interface{}) error
+	// CanPublishOutput is set by the runtime when the trigger publishes the OutputData set by a resumed pipeline,
+	// i.e. it is false for the HTTP trigger, which can only return output as the response to its request.
+	CanPublishOutput bool
+	// OnShutdown registers a handler called when the service shuts down, i.e. to flush buffered data.
+	// It is set by the runtime.
+	OnShutdown func(handler func())
+	// contextData holds the metadata values associated with the message being processed,
+	// i.e. MQTT 5 user properties received by the MQTT trigger
+	contextData map[string]string
 }
 
 // Complete is optional and provides a way to return the specified data.
@@ -143,3 +183,35 @@ func (context *Context) PushToCoreData(deviceName string, readingName string, va
 func (context *Context) GetSecrets(path string, keys ...string) (map[string]string, error) {
 	return context.SecretProvider.GetSecrets(path, keys...)
 }
+
+// AddValue stores a metadata value for the message being processed. Values added here are available to
+// all the functions in the pipeline and are sent along with the output where the trigger or export supports it,
+// i.e. as MQTT 5 user properties.
+func (context *Context) AddValue(key string, value string) {
+	if context.contextData == nil {
+		context.contextData = make(map[string]string)
+	}
+
+	context.contextData[key] = value
+}
+
+// GetValue returns the metadata value for the specified key and whether or not it was found.
+func (context *Context) GetValue(key string) (string, bool) {
+	value, ok := context.contextData[key]
+	return value, ok
+}
+
+// RemoveValue deletes the metadata value for the specified key.
+func (context *Context) RemoveValue(key string) {
+	delete(context.contextData, key)
+}
+
+// GetAllValues returns a copy of all the metadata values for the message being processed.
+func (context *Context) GetAllValues() map[string]string {
+	values := make(map[string]string, len(context.contextData))
+	for key, value := range context.contextData {
+		values[key] = value
+	}
+
+	return values
+}
@@ -172,3 +172,31 @@ func TestSetRetryData(t *testing.T) {
 	ctx.SetRetryData([]byte(testData))
 	assert.Equal(t, []byte(testData), ctx.RetryData)
 }
+
+func TestContextValues(t *testing.T) {
+	ctx := Context{}
+
+	_, found := ctx.GetValue("key1")
+	assert.False(t, found)
+	assert.Empty(t, ctx.GetAllValues())
+
+	ctx.AddValue("key1", "value1")
+	ctx.AddValue("key2", "value2")
+
+	value, found := ctx.GetValue("key1")
+	require.True(t, found)
+	assert.Equal(t, "value1", value)
+
+	values := ctx.GetAllValues()
+	assert.Equal(t, map[string]string{"key1": "value1", "key2": "value2"}, values)
+
+	// Modifying the copy must not change the context's values
+	values["key3"] = "value3"
+	_, found = ctx.GetValue("key3")
+	assert.False(t, found)
+
+	ctx.RemoveValue("key1")
+	_, found = ctx.GetValue("key1")
+	assert.False(t, found)
+	assert.Len(t, ctx.GetAllValues(), 1)
+}
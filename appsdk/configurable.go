@@ -23,6 +23,8 @@ import (
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/store"
+	"github.com/jcerato/app-functions-sdk-go/pkg/secure"
 	"github.com/jcerato/app-functions-sdk-go/pkg/transforms"
 	"github.com/jcerato/app-functions-sdk-go/pkg/util"
 )
@@ -46,6 +48,7 @@ const (
 	Rule                = "rule"
 	BatchThreshold      = "batchthreshold"
 	TimeInterval        = "timeinterval"
+	ByteThreshold       = "bytethreshold"
 	SecretHeaderName    = "secretheadername"
 	SecretPath          = "secretpath"
 	BrokerAddress       = "brokeraddress"
@@ -54,6 +57,53 @@ const (
 	AuthMode            = "authmode"
 	Tags                = "tags"
 	ResponseContentType = "responsecontenttype"
+	ProtocolVersion     = "protocolversion"
+	ResponseTopic       = "responsetopic"
+	RawBinary           = "rawbinary"
+	SecretName          = "secretname"
+	Algorithm           = "algorithm"
+	SignatureMode       = "signaturemode"
+	Columns             = "columns"
+	Delimiter           = "delimiter"
+	Header              = "header"
+	Template            = "template"
+	TemplateFile        = "templatefile"
+	Mappings            = "mappings"
+	Rules               = "rules"
+	WindowSize          = "windowsize"
+	Slide               = "slide"
+	Statistics          = "statistics"
+	KeyType             = "keytype"
+	MaxEntries          = "maxentries"
+	Persist             = "persist"
+	PersistFile         = "persistfile"
+	Limit               = "limit"
+	Mode                = "mode"
+	MaxBacklog          = "maxbacklog"
+	Deadband            = "deadband"
+	Heartbeat           = "heartbeat"
+	ReadingNames        = "readingnames"
+	Regex               = "regex"
+	ValueTypes          = "valuetypes"
+	Conditions          = "conditions"
+	Tolerance           = "tolerance"
+	Method              = "method"
+	Threshold           = "threshold"
+	Action              = "action"
+	Samples             = "samples"
+	MinSamples          = "minsamples"
+	Alpha               = "alpha"
+	MinStdDev           = "minstddev"
+	Cooldown            = "cooldown"
+	Fields              = "fields"
+	Target              = "target"
+	CacheTTL            = "cachettl"
+	LookupFile          = "lookupfile"
+	KeyColumn           = "keycolumn"
+	KeyTag              = "keytag"
+	ReloadInterval      = "reloadinterval"
+	Remove              = "remove"
+	Rename              = "rename"
 )
 
 // AppFunctionsSDKConfigurable contains the helper functions that return the function pointers for building the configurable function pipeline.
@@ -134,22 +184,643 @@ func (dynamic AppFunctionsSDKConfigurable) FilterByValueDescriptor(parameters ma
 	return transform.FilterByValueDescriptor
 }
 
+// FilterByDeviceNamePattern - Specify glob or regular expression patterns, i.e. "Random-*-Device", of the devices of
+// interest to filter for data coming from certain sensors. The devicenames parameter is required and the patterns are
+// regular expressions when the regex parameter is true. When filterout is true the matching devices are filtered out.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByDeviceNamePattern(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newPatternFilter(parameters, DeviceNames)
+	if transform == nil {
+		return nil
+	}
+
+	return transform.FilterByDeviceNamePattern
+}
+
+// FilterByReadingNamePattern - Specify glob or regular expression patterns, i.e. "temp*", of the reading names of
+// interest, removing the readings whose name doesn't match. The readingnames parameter is required and the patterns are
+// regular expressions when the regex parameter is true. When filterout is true the matching readings are removed.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByReadingNamePattern(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newPatternFilter(parameters, ReadingNames)
+	if transform == nil {
+		return nil
+	}
+
+	return transform.FilterByReadingNamePattern
+}
+
+func (dynamic AppFunctionsSDKConfigurable) newPatternFilter(parameters map[string]string, patternsParameter string) *transforms.PatternFilter {
+	patterns, ok := parameters[patternsParameter]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + patternsParameter)
+		return nil
+	}
+
+	filterOut, ok := dynamic.parseFilterOut(parameters)
+	if !ok {
+		return nil
+	}
+
+	regex := false
+	if value, ok := parameters[Regex]; ok {
+		var err error
+		regex, err = strconv.ParseBool(value)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error("Could not convert regex value to bool " + value)
+			return nil
+		}
+	}
+
+	patternsCleaned := util.DeleteEmptyAndTrim(strings.FieldsFunc(patterns, util.SplitComma))
+	transform, err := transforms.NewPatternFilter(patternsCleaned, regex, filterOut)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	dynamic.Sdk.LoggingClient.Debug("Pattern Filters", patternsParameter, strings.Join(patternsCleaned, ","))
+
+	return transform
+}
+
+// FilterByTags - Specify the tags, as a comma separated list of 'key:value', the Events of interest must have. The
+// values may be glob patterns, i.e. "line:*". When filterout is true the Events with the tags are filtered out.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByTags(parameters map[string]string) appcontext.AppFunction {
+	tagsSpec, ok := parameters[Tags]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not find '%s' parameter", Tags))
+		return nil
+	}
+
+	filterOut, ok := dynamic.parseFilterOut(parameters)
+	if !ok {
+		return nil
+	}
+
+	tags, ok := dynamic.parseTags(tagsSpec)
+	if !ok {
+		return nil
+	}
+
+	transform, err := transforms.NewTagFilter(tags, filterOut)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	dynamic.Sdk.LoggingClient.Debug("Tag Filters", Tags, fmt.Sprintf("%v", tags))
+
+	return transform.FilterByTags
+}
+
+// FilterByValueType - Specify the value types of interest, i.e. "Int32, Float64", removing the readings of other
+// value types. When filterout is true the readings of the specified value types are removed instead.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByValueType(parameters map[string]string) appcontext.AppFunction {
+	valueTypes, ok := parameters[ValueTypes]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + ValueTypes)
+		return nil
+	}
+
+	filterOut, ok := dynamic.parseFilterOut(parameters)
+	if !ok {
+		return nil
+	}
+
+	valueTypesCleaned := util.DeleteEmptyAndTrim(strings.FieldsFunc(valueTypes, util.SplitComma))
+	transform := transforms.Filter{
+		FilterValues: valueTypesCleaned,
+		FilterOut:    filterOut,
+	}
+	dynamic.Sdk.LoggingClient.Debug("Value Type Filters", ValueTypes, strings.Join(valueTypesCleaned, ","))
+
+	return transform.FilterByValueType
+}
+
+// FilterByValue - Specify conditions on the numeric reading values, i.e. "temperature > 80, humidity <= 20", removing
+// the readings which don't satisfy the conditions for their name. The operators are >, >=, <, <=, == and != and a
+// reading name of '*' applies to all readings. When filterout is true the readings satisfying the conditions are removed.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByValue(parameters map[string]string) appcontext.AppFunction {
+	conditionsSpec, ok := parameters[Conditions]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Conditions)
+		return nil
+	}
+
+	filterOut, ok := dynamic.parseFilterOut(parameters)
+	if !ok {
+		return nil
+	}
+
+	conditions, err := transforms.ParseValueConditions(conditionsSpec)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	transform, err := transforms.NewValueFilter(conditions, filterOut)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.FilterByValue
+}
+
+// parseFilterOut returns the value of the optional filterout parameter, which defaults to false
+func (dynamic AppFunctionsSDKConfigurable) parseFilterOut(parameters map[string]string) (bool, bool) {
+	filterOut, ok := parameters[FilterOut]
+	if !ok {
+		return false, true
+	}
+
+	filterOutBool, err := strconv.ParseBool(filterOut)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error("Could not convert filterOut value to bool " + filterOut)
+		return false, false
+	}
+
+	return filterOutBool, true
+}
+
+// FilterByDeadband removes readings whose value hasn't changed by more than the deadband since the value last forwarded
+// for the device and reading, dropping the Event when no readings remain. The deadband parameter is required and is an
+// absolute amount, i.e. "0.5", or when suffixed with '%' a percentage of the last forwarded value, i.e. "5%".
+// The optional heartbeat parameter, i.e. "15m", forces a reading to be forwarded when it hasn't been for the interval.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) FilterByDeadband(parameters map[string]string) appcontext.AppFunction {
+	deadbandValue, ok := parameters[Deadband]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Deadband)
+		return nil
+	}
+
+	deadbandValue = strings.TrimSpace(deadbandValue)
+	percent := strings.HasSuffix(deadbandValue, "%")
+	deadband, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(deadbandValue, "%")), 64)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to a number for '%s' parameter", deadbandValue, Deadband), "error", err)
+		return nil
+	}
+
+	transform, err := transforms.NewDeadband(deadband, percent, strings.TrimSpace(parameters[Heartbeat]))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.FilterByDeadband
+}
+
+// DetectAnomalies keeps rolling statistics of the numeric reading values per device and reading and takes an action
+// for the values detected as anomalies. The threshold parameter is required. The method parameter is zscore (the
+// default), which flags values more than threshold standard deviations from the mean of the last samples values, or
+// ewma, which flags values deviating more than threshold from the moving average weighted by alpha. The action
+// parameter is tag (the default), which adds the "anomaly" tag listing the anomalous readings, filter, which keeps
+// only the anomalous readings, or notify, which sends a notification at most once per device every cooldown, one
+// minute by default. Values aren't checked until minsamples values have been seen. The optional minstddev is the
+// minimum standard deviation of the zscore method, by default 1% of the mean, so small changes of a constant value
+// aren't anomalies. The statistics are kept when the pipeline is reloaded without changes to these parameters.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) DetectAnomalies(parameters map[string]string) appcontext.AppFunction {
+	thresholdValue, ok := parameters[Threshold]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Threshold)
+		return nil
+	}
+
+	threshold, err := strconv.ParseFloat(strings.TrimSpace(thresholdValue), 64)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to a number for '%s' parameter", thresholdValue, Threshold), "error", err)
+		return nil
+	}
+
+	method := transforms.AnomalyMethodZScore
+	if value, ok := parameters[Method]; ok {
+		method = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	action := transforms.AnomalyActionTag
+	if value, ok := parameters[Action]; ok {
+		action = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	counts := map[string]int{Samples: 0, MinSamples: 0}
+	for name := range counts {
+		if value, ok := parameters[name]; ok {
+			counts[name], err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", value, name), "error", err)
+				return nil
+			}
+		}
+	}
+
+	alpha := 0.0
+	if value, ok := parameters[Alpha]; ok {
+		alpha, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to a number for '%s' parameter", value, Alpha), "error", err)
+			return nil
+		}
+	}
+
+	transform, err := transforms.NewAnomalyDetector(method, threshold, action, counts[Samples], counts[MinSamples], alpha)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	if value, ok := parameters[MinStdDev]; ok {
+		minStdDev, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to a number for '%s' parameter", value, MinStdDev), "error", err)
+			return nil
+		}
+
+		if err := transform.SetMinStdDev(minStdDev); err != nil {
+			dynamic.Sdk.LoggingClient.Error(err.Error())
+			return nil
+		}
+	}
+
+	if value, ok := parameters[Cooldown]; ok {
+		if err := transform.SetNotificationCooldown(strings.TrimSpace(value)); err != nil {
+			dynamic.Sdk.LoggingClient.Error(err.Error())
+			return nil
+		}
+	}
+
+	// The statistics are kept when the pipeline is reloaded, and carried over when i.e. only the threshold changes
+	if previous, ok := dynamic.Sdk.keepOnReload(transform, nil).(*transforms.AnomalyDetector); ok {
+		transform.InheritHistory(previous)
+	}
+
+	return transform.DetectAnomalies
+}
+
 // TransformToXML transforms an EdgeX event to XML.
 // It will return an error and stop the pipeline if a non-edgex
 // event is received or if no data is recieved.
 // This function is a configuration function and returns a function pointer.
-func (dynamic AppFunctionsSDKConfigurable) TransformToXML() appcontext.AppFunction {
-	transform := transforms.Conversion{}
-	return transform.TransformToXML
+func (dynamic AppFunctionsSDKConfigurable) TransformToXML() appcontext.AppFunction {
+	transform := transforms.Conversion{}
+	return transform.TransformToXML
+}
+
+// TransformToJSON transforms an EdgeX event to JSON.
+// It will return an error and stop the pipeline if a non-edgex
+// event is received or if no data is recieved.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) TransformToJSON() appcontext.AppFunction {
+	transform := transforms.Conversion{}
+	return transform.TransformToJSON
+}
+
+// TransformToCBOR transforms an EdgeX event to CBOR.
+// It will return an error and stop the pipeline if a non-edgex
+// event is received or if no data is recieved.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) TransformToCBOR() appcontext.AppFunction {
+	transform := transforms.Conversion{}
+	return transform.TransformToCBOR
+}
+
+// TransformToProtobuf transforms an EdgeX event to Protocol Buffers.
+// It will return an error and stop the pipeline if a non-edgex
+// event is received or if no data is recieved.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) TransformToProtobuf() appcontext.AppFunction {
+	transform := transforms.Conversion{}
+	return transform.TransformToProtobuf
+}
+
+// TransformToCSV transforms an EdgeX event, or batched events, to CSV with one row per reading.
+// The optional columns, delimiter and header parameters specify the columns written, the field delimiter
+// and whether a header row is written, which defaults to true.
+// It will return an error and stop the pipeline if a non-edgex
+// event is received or if no data is recieved.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) TransformToCSV(parameters map[string]string) appcontext.AppFunction {
+	var columns []string
+	if value, ok := parameters[Columns]; ok {
+		columns = util.DeleteEmptyAndTrim(strings.FieldsFunc(value, util.SplitComma))
+	}
+
+	includeHeader := true
+	if value, ok := parameters[Header]; ok {
+		var err error
+		includeHeader, err = strconv.ParseBool(value)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error("Could not convert header value to bool " + value)
+			return nil
+		}
+	}
+
+	transform, err := transforms.NewCSVConversion(columns, parameters[Delimiter], includeHeader)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.TransformToCSV
+}
+
+// RenderTemplate reshapes the data from the previous function by rendering it through a Go text/template.
+// The template is specified inline with the template parameter or read from the file specified with the
+// templatefile parameter. The optional mimetype parameter sets the response content type, which defaults to
+// application/json.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) RenderTemplate(parameters map[string]string) appcontext.AppFunction {
+	text, hasText := parameters[Template]
+	file, hasFile := parameters[TemplateFile]
+	if hasText == hasFile {
+		dynamic.Sdk.LoggingClient.Error("RenderTemplate requires either " + Template + " or " + TemplateFile)
+		return nil
+	}
+
+	var transform *transforms.Template
+	var err error
+	if hasText {
+		transform, err = transforms.NewTemplate(text, strings.TrimSpace(parameters[MimeType]))
+	} else {
+		transform, err = transforms.NewTemplateFromFile(strings.TrimSpace(file), strings.TrimSpace(parameters[MimeType]))
+	}
+
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.RenderTemplate
+}
+
+// MapJSON builds a new JSON document from the fields extracted from arbitrary JSON data. The mappings
+// parameter is a comma separated list of target=JSONPath mappings, i.e. "temperature=$.sensor.temp".
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) MapJSON(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newJSONMapper(parameters, "")
+	if transform == nil {
+		return nil
+	}
+	return transform.MapToJSON
+}
+
+// MapJSONToEvent builds an EdgeX Event from the fields extracted from arbitrary JSON data, with a reading
+// for each field. The mappings parameter is a comma separated list of readingname=JSONPath mappings and the
+// devicename parameter is the Event's device name, or a JSONPath to extract it from the data.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) MapJSONToEvent(parameters map[string]string) appcontext.AppFunction {
+	deviceName, ok := parameters[DeviceName]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + DeviceName)
+		return nil
+	}
+
+	transform := dynamic.newJSONMapper(parameters, strings.TrimSpace(deviceName))
+	if transform == nil {
+		return nil
+	}
+	return transform.MapToEvent
+}
+
+func (dynamic AppFunctionsSDKConfigurable) newJSONMapper(parameters map[string]string, deviceName string) *transforms.JSONMapper {
+	mappings, ok := parameters[Mappings]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Mappings)
+		return nil
+	}
+
+	fieldMappings, err := transforms.ParseFieldMappings(mappings)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	transform, err := transforms.NewJSONMapper(fieldMappings, deviceName)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform
+}
+
+// ScaleReadings scales and converts the units of the Event's reading values. The rules parameter is a comma
+// separated list of rules in the form "readingname: scale=<n> offset=<n> from=<units> to=<units>", where all
+// the settings are optional, i.e. "temperature: from=degF to=degC, pressure: from=psi to=kPa".
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) ScaleReadings(parameters map[string]string) appcontext.AppFunction {
+	rules, ok := parameters[Rules]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Rules)
+		return nil
+	}
+
+	scalingRules, err := transforms.ParseScalingRules(rules)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	transform, err := transforms.NewScaling(scalingRules)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.ScaleReadings
+}
+
+// AggregateReadings aggregates the numeric reading values of Events over time windows, keyed by device and
+// reading name, and emits an Event with the statistics for each window when it closes, which is when the window
+// ends or the service shuts down. The windowsize parameter is the window duration, i.e. "1m". The optional slide
+// parameter makes the windows sliding, with a new window starting every slide, otherwise the windows are tumbling.
+// The optional statistics parameter is a comma separated list of min, max, avg, count and sum, which defaults to all
+// of them.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) AggregateReadings(parameters map[string]string) appcontext.AppFunction {
+	windowSize, ok := parameters[WindowSize]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + WindowSize)
+		return nil
+	}
+
+	var statistics []string
+	if value, ok := parameters[Statistics]; ok {
+		statistics = util.DeleteEmptyAndTrim(strings.FieldsFunc(strings.ToLower(value), util.SplitComma))
+	}
+
+	windowSize = strings.TrimSpace(windowSize)
+	slide, ok := parameters[Slide]
+	if !ok {
+		slide = windowSize
+	}
+
+	transform, err := transforms.NewSlidingWindowAggregation(windowSize, strings.TrimSpace(slide), statistics)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.Aggregate
+}
+
+// Deduplicate drops Events whose key has already been seen within the window. The windowsize parameter is required,
+// keytype is one of eventid, checksum, correlationid or hash (the default) and maxentries bounds the keys remembered.
+// When persist is true the keys are persisted to the Store and Forward database, which must be enabled.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) Deduplicate(parameters map[string]string) appcontext.AppFunction {
+	windowSize, ok := parameters[WindowSize]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + WindowSize)
+		return nil
+	}
+
+	keyType := transforms.DeduplicationKeyHash
+	if value, ok := parameters[KeyType]; ok {
+		keyType = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	maxEntries := 0
+	if value, ok := parameters[MaxEntries]; ok {
+		var err error
+		maxEntries, err = strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", value, MaxEntries), "error", err)
+			return nil
+		}
+	}
+
+	storeKey := ""
+	if value, ok := parameters[Persist]; ok {
+		persist, err := strconv.ParseBool(value)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error("Could not convert persist value to bool " + value)
+			return nil
+		}
+
+		if persist {
+			storeKey = dynamic.Sdk.ServiceKey + "-deduplication"
+		}
+	}
+
+	transform, err := transforms.NewPersistentDeduplication(strings.TrimSpace(windowSize), keyType, maxEntries, storeKey)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.Deduplicate
+}
+
+// RateLimit limits the throughput using a token bucket allowing limit values per timeinterval, both of which are
+// required. The key parameter is one of global (the default), device or reading and the mode parameter, one of drop
+// (the default), delay or latest, determines what happens to the values exceeding the limit. The optional maxbacklog
+// parameter is the number of values delayed per key in delay mode, which defaults to the limit.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) RateLimit(parameters map[string]string) appcontext.AppFunction {
+	limitValue, ok := parameters[Limit]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Limit)
+		return nil
+	}
+
+	interval, ok := parameters[TimeInterval]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + TimeInterval)
+		return nil
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(limitValue))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", limitValue, Limit), "error", err)
+		return nil
+	}
+
+	keyType := transforms.RateLimitKeyGlobal
+	if value, ok := parameters[KeyType]; ok {
+		keyType = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	mode := transforms.RateLimitModeDrop
+	if value, ok := parameters[Mode]; ok {
+		mode = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	transform, err := transforms.NewRateLimiter(limit, strings.TrimSpace(interval), keyType, mode)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	if value, ok := parameters[MaxBacklog]; ok {
+		maxBacklog, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", value, MaxBacklog), "error", err)
+			return nil
+		}
+
+		if err := transform.SetMaxBacklog(maxBacklog); err != nil {
+			dynamic.Sdk.LoggingClient.Error(err.Error())
+			return nil
+		}
+	}
+
+	return transform.RateLimit
 }
 
-// TransformToJSON transforms an EdgeX event to JSON.
-// It will return an error and stop the pipeline if a non-edgex
-// event is received or if no data is recieved.
+// SplitByReading splits an Event into an Event for each of its readings, with the rest of the pipeline executed once
+// for each of the Events. Each Event gets its own ID, so Deduplicate keyed by eventid can follow the split,
+// while Deduplicate keyed by checksum or correlationid must come before it.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
 // This function is a configuration function and returns a function pointer.
-func (dynamic AppFunctionsSDKConfigurable) TransformToJSON() appcontext.AppFunction {
-	transform := transforms.Conversion{}
-	return transform.TransformToJSON
+func (dynamic AppFunctionsSDKConfigurable) SplitByReading() appcontext.AppFunction {
+	transform := transforms.NewEventSplitter()
+	return transform.SplitByReading
+}
+
+// JoinEvents merges the Events from the devices specified by the devicenames parameter, whose origins are within the
+// tolerance parameter, i.e. "500ms", of each other, into an Event for the device specified by the devicename parameter.
+// All the parameters are required. Events from other devices are passed on unchanged.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) JoinEvents(parameters map[string]string) appcontext.AppFunction {
+	deviceNames, ok := parameters[DeviceNames]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + DeviceNames)
+		return nil
+	}
+
+	tolerance, ok := parameters[Tolerance]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Tolerance)
+		return nil
+	}
+
+	deviceName, ok := parameters[DeviceName]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + DeviceName)
+		return nil
+	}
+
+	deviceNamesCleaned := util.DeleteEmptyAndTrim(strings.FieldsFunc(deviceNames, util.SplitComma))
+	transform, err := transforms.NewEventJoiner(deviceNamesCleaned, strings.TrimSpace(tolerance), strings.TrimSpace(deviceName))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.JoinEvents
 }
 
 // MarkAsPushed will make a request to CoreData to mark the event that triggered the pipeline as pushed.
@@ -186,17 +857,76 @@ func (dynamic AppFunctionsSDKConfigurable) PushToCore(parameters map[string]stri
 // CompressWithGZIP compresses data received as either a string,[]byte, or json.Marshaler using gzip algorithm and returns a base64 encoded string as a []byte.
 // This function is a configuration function and returns a function pointer.
 func (dynamic AppFunctionsSDKConfigurable) CompressWithGZIP() appcontext.AppFunction {
-	transform := transforms.Compression{}
+	transform := transforms.NewCompression()
+	return transform.CompressWithGZIP
+}
+
+// CompressWithGZIPOptions is CompressWithGZIP with options. The raw binary data is returned instead of the base64
+// encoded string when the optional RawBinary parameter is true.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) CompressWithGZIPOptions(parameters map[string]string) appcontext.AppFunction {
+	transform, ok := dynamic.newCompression(parameters)
+	if !ok {
+		return nil
+	}
 	return transform.CompressWithGZIP
 }
 
 // CompressWithZLIB compresses data received as either a string,[]byte, or json.Marshaler using zlib algorithm and returns a base64 encoded string as a []byte.
 // This function is a configuration function and returns a function pointer.
 func (dynamic AppFunctionsSDKConfigurable) CompressWithZLIB() appcontext.AppFunction {
-	transform := transforms.Compression{}
+	transform := transforms.NewCompression()
+	return transform.CompressWithZLIB
+}
+
+// CompressWithZLIBOptions is CompressWithZLIB with options. The raw binary data is returned instead of the base64
+// encoded string when the optional RawBinary parameter is true.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) CompressWithZLIBOptions(parameters map[string]string) appcontext.AppFunction {
+	transform, ok := dynamic.newCompression(parameters)
+	if !ok {
+		return nil
+	}
 	return transform.CompressWithZLIB
 }
 
+// DecompressWithGZIP decompresses gzip compressed data received as either a string or []byte and returns the decompressed data as a []byte.
+// Base64 encoded data must first be decoded using DecodeBase64.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) DecompressWithGZIP() appcontext.AppFunction {
+	transform := transforms.Compression{}
+	return transform.DecompressWithGZIP
+}
+
+// DecompressWithZLIB decompresses zlib compressed data received as either a string or []byte and returns the decompressed data as a []byte.
+// Base64 encoded data must first be decoded using DecodeBase64.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) DecompressWithZLIB() appcontext.AppFunction {
+	transform := transforms.Compression{}
+	return transform.DecompressWithZLIB
+}
+
+// DecodeBase64 decodes base64 encoded data received as either a string or []byte and returns the decoded data as a []byte.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) DecodeBase64() appcontext.AppFunction {
+	transform := transforms.NewEncoding()
+	return transform.DecodeBase64
+}
+
+func (dynamic AppFunctionsSDKConfigurable) newCompression(parameters map[string]string) (*transforms.Compression, bool) {
+	transform := transforms.NewCompression()
+	value, ok := parameters[RawBinary]
+	if ok {
+		var err error
+		transform.RawBinary, err = strconv.ParseBool(value)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to a bool for '%s' parameter", value, RawBinary), "error", err)
+			return nil, false
+		}
+	}
+	return &transform, true
+}
+
 // EncryptWithAES encrypts either a string, []byte, or json.Marshaller type using AES encryption.
 // It will return a byte[] of the encrypted data.
 // This function is a configuration function and returns a function pointer.
@@ -218,6 +948,96 @@ func (dynamic AppFunctionsSDKConfigurable) EncryptWithAES(parameters map[string]
 	return transforms.EncryptWithAES
 }
 
+// EncryptWithAESGCM encrypts either a string, []byte, or json.Marshaller type using AES-256-GCM encryption with
+// the key retrieved from the SecretProvider. It will return a base64 encoded []byte of the encrypted data.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) EncryptWithAESGCM(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newAESGCM(parameters)
+	if transform == nil {
+		return nil
+	}
+	return transform.EncryptWithAESGCM
+}
+
+// DecryptWithAESGCM decrypts base64 encoded data encrypted by EncryptWithAESGCM using the key retrieved
+// from the SecretProvider. It will return a []byte of the decrypted data.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) DecryptWithAESGCM(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newAESGCM(parameters)
+	if transform == nil {
+		return nil
+	}
+	return transform.DecryptWithAESGCM
+}
+
+func (dynamic AppFunctionsSDKConfigurable) newAESGCM(parameters map[string]string) *transforms.AESGCM {
+	secretPath, ok := parameters[SecretPath]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + SecretPath)
+		return nil
+	}
+	secretName, ok := parameters[SecretName]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + SecretName)
+		return nil
+	}
+
+	return transforms.NewAESGCM(strings.TrimSpace(secretPath), strings.TrimSpace(secretName))
+}
+
+// SignData signs the data from the previous function with the specified algorithm using the key retrieved
+// from the SecretProvider. The signature is attached as a context value, sent as the X-Signature header by
+// HTTPPost, or the data is wrapped along with the signature, depending on the signature mode.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) SignData(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newSigning(parameters)
+	if transform == nil {
+		return nil
+	}
+	return transform.SignData
+}
+
+// VerifySignature verifies the signature of the data from the previous function with the specified algorithm
+// using the key retrieved from the SecretProvider. Unsigned or tampered data stops the pipeline.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) VerifySignature(parameters map[string]string) appcontext.AppFunction {
+	transform := dynamic.newSigning(parameters)
+	if transform == nil {
+		return nil
+	}
+	return transform.VerifySignature
+}
+
+func (dynamic AppFunctionsSDKConfigurable) newSigning(parameters map[string]string) *transforms.Signing {
+	algorithm, ok := parameters[Algorithm]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Algorithm)
+		return nil
+	}
+	secretPath, ok := parameters[SecretPath]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + SecretPath)
+		return nil
+	}
+	secretName, ok := parameters[SecretName]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + SecretName)
+		return nil
+	}
+
+	transform, err := transforms.NewSigning(
+		strings.TrimSpace(algorithm),
+		strings.TrimSpace(parameters[SignatureMode]),
+		strings.TrimSpace(secretPath),
+		strings.TrimSpace(secretName))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform
+}
+
 // HTTPPost will send data from the previous function to the specified Endpoint via http POST. If no previous function exists,
 // then the event that triggered the pipeline will be used. Passing an empty string to the mimetype
 // method will default to application/json.
@@ -446,6 +1266,10 @@ func (dynamic AppFunctionsSDKConfigurable) BatchByCount(parameters map[string]st
 	transform, err := transforms.NewBatchByCount(thresholdValue)
 	if err != nil {
 		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	if !dynamic.setBatchOptions(transform, parameters) {
+		return nil
 	}
 	dynamic.Sdk.LoggingClient.Debug("Batch by count Parameters", BatchThreshold, batchThreshold)
 	return transform.Batch
@@ -461,6 +1285,10 @@ func (dynamic AppFunctionsSDKConfigurable) BatchByTime(parameters map[string]str
 	transform, err := transforms.NewBatchByTime(timeInterval)
 	if err != nil {
 		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	if !dynamic.setBatchOptions(transform, parameters) {
+		return nil
 	}
 	dynamic.Sdk.LoggingClient.Debug("Batch by time Parameters", TimeInterval, timeInterval)
 	return transform.Batch
@@ -485,11 +1313,87 @@ func (dynamic AppFunctionsSDKConfigurable) BatchByTimeAndCount(parameters map[st
 	transform, err := transforms.NewBatchByTimeAndCount(timeInterval, thresholdValue)
 	if err != nil {
 		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	if !dynamic.setBatchOptions(transform, parameters) {
+		return nil
 	}
 	dynamic.Sdk.LoggingClient.Debug("Batch by time and count Parameters", BatchThreshold, batchThreshold, TimeInterval, timeInterval)
 	return transform.Batch
 }
 
+// BatchBySize ...
+func (dynamic AppFunctionsSDKConfigurable) BatchBySize(parameters map[string]string) appcontext.AppFunction {
+	byteThreshold, ok := parameters[ByteThreshold]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + ByteThreshold)
+		return nil
+	}
+
+	thresholdValue, err := strconv.Atoi(byteThreshold)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", byteThreshold, ByteThreshold), "error", err)
+		return nil
+	}
+	transform, err := transforms.NewBatchBySize(thresholdValue)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	if !dynamic.setBatchOptions(transform, parameters) {
+		return nil
+	}
+	dynamic.Sdk.LoggingClient.Debug("Batch by size Parameters", ByteThreshold, byteThreshold)
+	return transform.Batch
+}
+
+// setBatchOptions sets the optional byte threshold and persistence of the batch, returning false if they are
+// invalid. When persist is true the batch is persisted to the Store and Forward database, which must be enabled,
+// otherwise when persistfile is specified to that file.
+func (dynamic AppFunctionsSDKConfigurable) setBatchOptions(transform *transforms.BatchConfig, parameters map[string]string) bool {
+	if byteThreshold, ok := parameters[ByteThreshold]; ok && len(byteThreshold) > 0 {
+		thresholdValue, err := strconv.Atoi(byteThreshold)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not parse '%s' to an int for '%s' parameter", byteThreshold, ByteThreshold), "error", err)
+			return false
+		}
+
+		if err := transform.SetByteThreshold(thresholdValue); err != nil {
+			dynamic.Sdk.LoggingClient.Error(err.Error())
+			return false
+		}
+	}
+
+	persist := false
+	if value, ok := parameters[Persist]; ok {
+		var err error
+		persist, err = strconv.ParseBool(value)
+		if err != nil {
+			dynamic.Sdk.LoggingClient.Error("Could not convert persist value to bool " + value)
+			return false
+		}
+	}
+
+	var err error
+	if persist {
+		var persistentStore appcontext.PersistentStore
+		if dynamic.Sdk.storeClient != nil {
+			persistentStore = store.NewPersistentStore(dynamic.Sdk.storeClient)
+		}
+		err = transform.SetPersistence(persistentStore, dynamic.Sdk.ServiceKey+"-batch")
+	} else if path := strings.TrimSpace(parameters[PersistFile]); len(path) > 0 {
+		err = transform.SetPersistenceFile(path)
+	}
+
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return false
+	}
+
+	dynamic.Sdk.keepOnReload(transform, transform.Close)
+	return true
+}
+
 // JSONLogic ...
 func (dynamic AppFunctionsSDKConfigurable) JSONLogic(parameters map[string]string) appcontext.AppFunction {
 	rule, ok := parameters[Rule]
@@ -567,16 +1471,23 @@ func (dynamic AppFunctionsSDKConfigurable) MQTTSecretSend(parameters map[string]
 			return nil
 		}
 	}
+	protocolVersion := parameters[ProtocolVersion]
+	if _, err := secure.IsProtocolVersion5(protocolVersion); err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
 	mqttConfig := transforms.MQTTSecretConfig{
-		Retain:         retain,
-		SkipCertVerify: skipCertVerify,
-		AutoReconnect:  autoReconnect,
-		QoS:            byte(qos),
-		BrokerAddress:  brokerAddress,
-		ClientId:       clientID,
-		SecretPath:     secretPath,
-		Topic:          topic,
-		AuthMode:       authMode,
+		Retain:          retain,
+		SkipCertVerify:  skipCertVerify,
+		AutoReconnect:   autoReconnect,
+		QoS:             byte(qos),
+		BrokerAddress:   brokerAddress,
+		ClientId:        clientID,
+		SecretPath:      secretPath,
+		Topic:           topic,
+		AuthMode:        authMode,
+		ProtocolVersion: protocolVersion,
+		ResponseTopic:   parameters[ResponseTopic],
 	}
 	// PersistOnError is optional and is false by default.
 	persistOnError := false
@@ -593,6 +1504,10 @@ func (dynamic AppFunctionsSDKConfigurable) MQTTSecretSend(parameters map[string]
 }
 
 // AddTags adds the configured list of tags to Events passed to the transform.
+// Tag values containing "{{" are templates rendered for each Event, i.e. "{{.Device}}", "{{.ReadingCount}}",
+// "{{.ReceivedTopic}}", "{{now \"2006-01-02\"}}", "{{env \"SITE_ID\"}}", "{{setting \"Region\"}}" or
+// "{{secret \"tenant\" \"id\"}}", see transforms.NewTemplatedTags. The value is everything after the first colon of
+// the 'key:value' tag, so templates may contain colons but not commas.
 // This function is a configuration function and returns a function pointer.
 func (dynamic AppFunctionsSDKConfigurable) AddTags(parameters map[string]string) appcontext.AppFunction {
 	tagsSpec, ok := parameters[Tags]
@@ -601,30 +1516,136 @@ func (dynamic AppFunctionsSDKConfigurable) AddTags(parameters map[string]string)
 		return nil
 	}
 
+	tags, ok := dynamic.parseTags(tagsSpec)
+	if !ok {
+		return nil
+	}
+
+	transform, err := transforms.NewTemplatedTags(tags)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	dynamic.Sdk.LoggingClient.Debug("Add Tags", Tags, fmt.Sprintf("%v", tags))
+
+	return transform.AddTags
+}
+
+// EditTags removes and renames the tags of Events passed to the transform. The remove parameter is a comma separated
+// list of the tags to remove and the rename parameter a comma separated list of 'current:new' tag names, at least one
+// of which is required. Tags are removed before they are renamed.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) EditTags(parameters map[string]string) appcontext.AppFunction {
+	var remove []string
+	if value, ok := parameters[Remove]; ok {
+		remove = util.DeleteEmptyAndTrim(strings.FieldsFunc(value, util.SplitComma))
+	}
+
+	var rename map[string]string
+	if value, ok := parameters[Rename]; ok {
+		rename, ok = dynamic.parseTags(value)
+		if !ok {
+			return nil
+		}
+	}
+
+	transform, err := transforms.NewTagEditor(remove, rename)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Could not find '%s' or '%s' parameter: %s", Remove, Rename, err.Error()))
+		return nil
+	}
+	dynamic.Sdk.LoggingClient.Debug("Edit Tags", Remove, strings.Join(remove, ","), Rename, fmt.Sprintf("%v", rename))
+
+	return transform.EditTags
+}
+
+// AddDeviceMetadata adds fields of the Event's device from core-metadata, which requires the Metadata client in the
+// Clients configuration. The fields parameter is an optional comma separated list of description, labels, location,
+// service, profile, manufacturer, model and profilelabels, defaulting to profile, labels and location. The target
+// parameter is tags (the default) to add the fields as Event tags or context to add them as context metadata values.
+// The cachettl parameter, i.e. "10m", is how long the device's metadata is cached, defaulting to 5 minutes.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) AddDeviceMetadata(parameters map[string]string) appcontext.AppFunction {
+	var fields []string
+	if value, ok := parameters[Fields]; ok {
+		fields = util.DeleteEmptyAndTrim(strings.FieldsFunc(strings.ToLower(value), util.SplitComma))
+	}
+
+	target := transforms.DeviceMetadataTargetTags
+	if value, ok := parameters[Target]; ok {
+		target = strings.ToLower(strings.TrimSpace(value))
+	}
+
+	transform, err := transforms.NewDeviceMetadataEnricher(fields, target, strings.TrimSpace(parameters[CacheTTL]))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.AddDeviceMetadata
+}
+
+// AddLookupTags adds the columns of the row matching the Event from a CSV or JSON lookup table file, i.e. asset IDs,
+// locations and cost centres, to the Event's tags. The lookupfile parameter is the path of the file, which is JSON
+// when it has the .json extension and otherwise CSV with a header row. The keytag parameter is the optional tag whose
+// value is looked up, defaulting to the Event's device name. The keycolumn parameter is the column holding the lookup
+// key, defaulting to the first CSV column, and the columns parameter is an optional comma separated list of the
+// columns to add, defaulting to all. The file is checked for changes every reloadinterval, i.e. "30s", defaulting to
+// 10 seconds, and reloaded when changed. Changing the lookupfile in the Writable pipeline configuration reloads the
+// pipeline and so loads the new file.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) AddLookupTags(parameters map[string]string) appcontext.AppFunction {
+	lookupFile, ok := parameters[LookupFile]
+	if !ok {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + LookupFile)
+		return nil
+	}
+
+	var columns []string
+	if value, ok := parameters[Columns]; ok {
+		columns = util.DeleteEmptyAndTrim(strings.FieldsFunc(value, util.SplitComma))
+	}
+
+	transform, err := transforms.NewLookupTable(
+		strings.TrimSpace(lookupFile),
+		strings.TrimSpace(parameters[KeyColumn]),
+		strings.TrimSpace(parameters[KeyTag]),
+		columns,
+		strings.TrimSpace(parameters[ReloadInterval]))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	return transform.AddLookupTags
+}
+
+// parseTags parses a comma separated list of 'key:value' tags, the value being everything after the first colon
+func (dynamic AppFunctionsSDKConfigurable) parseTags(tagsSpec string) (map[string]string, bool) {
 	tagKeyValues := util.DeleteEmptyAndTrim(strings.FieldsFunc(tagsSpec, util.SplitComma))
 
 	tags := make(map[string]string)
 	for _, tag := range tagKeyValues {
-		keyValue := util.DeleteEmptyAndTrim(strings.FieldsFunc(tag, util.SplitColon))
+		keyValue := strings.SplitN(tag, ":", 2)
+		for index := range keyValue {
+			keyValue[index] = strings.TrimSpace(keyValue[index])
+		}
 		if len(keyValue) != 2 {
 			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Bad Tags specification format. Expect comma separated list of 'key:value'. Got `%s`", tagsSpec))
-			return nil
+			return nil, false
 		}
 
 		if len(keyValue[0]) == 0 {
 			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Tag key missing. Got '%s'", tag))
-			return nil
+			return nil, false
 		}
 		if len(keyValue[1]) == 0 {
 			dynamic.Sdk.LoggingClient.Error(fmt.Sprintf("Tag value missing. Got '%s'", tag))
-			return nil
+			return nil, false
 		}
 
 		tags[keyValue[0]] = keyValue[1]
 	}
 
-	transform := transforms.NewTags(tags)
-	dynamic.Sdk.LoggingClient.Debug("Add Tags", Tags, fmt.Sprintf("%v", tags))
-
-	return transform.AddTags
+	return tags, true
 }
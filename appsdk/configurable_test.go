@@ -16,10 +16,15 @@
 package appsdk
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
 )
 
 func TestConfigurableFilterByDeviceName(t *testing.T) {
@@ -96,6 +101,615 @@ func TestConfigurableTransformToJSON(t *testing.T) {
 	assert.NotNil(t, trx, "return result from TransformToJSON should not be nil")
 }
 
+func TestConfigurableTransformToCBOR(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{}
+
+	trx := configurable.TransformToCBOR()
+	assert.NotNil(t, trx, "return result from TransformToCBOR should not be nil")
+}
+
+func TestConfigurableTransformToProtobuf(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{}
+
+	trx := configurable.TransformToProtobuf()
+	assert.NotNil(t, trx, "return result from TransformToProtobuf should not be nil")
+}
+
+func TestConfigurableCompress(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"No Parameters", map[string]string{}, false},
+		{"Raw Binary", map[string]string{RawBinary: "true"}, false},
+		{"Invalid Raw Binary", map[string]string{RawBinary: "bogus"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gzipTrx := configurable.CompressWithGZIPOptions(tt.params)
+			zlibTrx := configurable.CompressWithZLIBOptions(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, gzipTrx, "return result from CompressWithGZIPOptions should be nil")
+				assert.Nil(t, zlibTrx, "return result from CompressWithZLIBOptions should be nil")
+			} else {
+				assert.NotNil(t, gzipTrx, "return result from CompressWithGZIPOptions should not be nil")
+				assert.NotNil(t, zlibTrx, "return result from CompressWithZLIBOptions should not be nil")
+			}
+		})
+	}
+
+	assert.NotNil(t, configurable.CompressWithGZIP(), "return result from CompressWithGZIP should not be nil")
+	assert.NotNil(t, configurable.CompressWithZLIB(), "return result from CompressWithZLIB should not be nil")
+}
+
+func TestConfigurableDecompress(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{}
+
+	assert.NotNil(t, configurable.DecompressWithGZIP(), "return result from DecompressWithGZIP should not be nil")
+	assert.NotNil(t, configurable.DecompressWithZLIB(), "return result from DecompressWithZLIB should not be nil")
+}
+
+func TestConfigurableDecodeBase64(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{}
+
+	trx := configurable.DecodeBase64()
+	assert.NotNil(t, trx, "return result from DecodeBase64 should not be nil")
+}
+
+func TestConfigurableAESGCM(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Valid", map[string]string{SecretPath: "aes", SecretName: "key"}, false},
+		{"Missing SecretPath", map[string]string{SecretName: "key"}, true},
+		{"Missing SecretName", map[string]string{SecretPath: "aes"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptTrx := configurable.EncryptWithAESGCM(tt.params)
+			decryptTrx := configurable.DecryptWithAESGCM(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, encryptTrx, "return result from EncryptWithAESGCM should be nil")
+				assert.Nil(t, decryptTrx, "return result from DecryptWithAESGCM should be nil")
+			} else {
+				assert.NotNil(t, encryptTrx, "return result from EncryptWithAESGCM should not be nil")
+				assert.NotNil(t, decryptTrx, "return result from DecryptWithAESGCM should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableSigning(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Valid", map[string]string{Algorithm: "HMAC-SHA256", SecretPath: "signing", SecretName: "key"}, false},
+		{"Valid wrapper mode", map[string]string{Algorithm: "Ed25519", SignatureMode: "wrapper", SecretPath: "signing", SecretName: "key"}, false},
+		{"Missing Algorithm", map[string]string{SecretPath: "signing", SecretName: "key"}, true},
+		{"Missing SecretPath", map[string]string{Algorithm: "HMAC-SHA256", SecretName: "key"}, true},
+		{"Missing SecretName", map[string]string{Algorithm: "HMAC-SHA256", SecretPath: "signing"}, true},
+		{"Bad Algorithm", map[string]string{Algorithm: "MD5", SecretPath: "signing", SecretName: "key"}, true},
+		{"Bad SignatureMode", map[string]string{Algorithm: "HMAC-SHA256", SignatureMode: "body", SecretPath: "signing", SecretName: "key"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signTrx := configurable.SignData(tt.params)
+			verifyTrx := configurable.VerifySignature(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, signTrx, "return result from SignData should be nil")
+				assert.Nil(t, verifyTrx, "return result from VerifySignature should be nil")
+			} else {
+				assert.NotNil(t, signTrx, "return result from SignData should not be nil")
+				assert.NotNil(t, verifyTrx, "return result from VerifySignature should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableTransformToCSV(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{}, false},
+		{"All parameters", map[string]string{Columns: "device, name, value, units", Delimiter: ";", Header: "false"}, false},
+		{"Bad column", map[string]string{Columns: "device,bogus"}, true},
+		{"Bad delimiter", map[string]string{Delimiter: "::"}, true},
+		{"Bad header", map[string]string{Header: "maybe"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.TransformToCSV(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from TransformToCSV should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from TransformToCSV should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableRenderTemplate(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	templateFile := filepath.Join(t.TempDir(), "template.tmpl")
+	require.NoError(t, ioutil.WriteFile(templateFile, []byte(`{"device":"{{ .Data.Device }}"}`), 0644))
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Inline", map[string]string{Template: `{"device":"{{ .Data.Device }}"}`}, false},
+		{"Inline with mime type", map[string]string{Template: `{{ .Data }}`, MimeType: "text/plain"}, false},
+		{"File", map[string]string{TemplateFile: templateFile}, false},
+		{"Missing template", map[string]string{}, true},
+		{"Both template and file", map[string]string{Template: `{{ .Data }}`, TemplateFile: templateFile}, true},
+		{"Bad template", map[string]string{Template: `{{ .Data `}, true},
+		{"Missing file", map[string]string{TemplateFile: filepath.Join(t.TempDir(), "missing.tmpl")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.RenderTemplate(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from RenderTemplate should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from RenderTemplate should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableMapJSON(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name           string
+		params         map[string]string
+		expectNil      bool
+		expectEventNil bool
+	}{
+		{"Valid", map[string]string{Mappings: "temperature=$.sensor.temp, humidity=$['sensor']['hum']", DeviceName: "sensor01"}, false, false},
+		{"Device name path", map[string]string{Mappings: "temperature=$.temp", DeviceName: "$.id"}, false, false},
+		{"Missing device name", map[string]string{Mappings: "temperature=$.temp"}, false, true},
+		{"Missing mappings", map[string]string{DeviceName: "sensor01"}, true, true},
+		{"Empty mappings", map[string]string{Mappings: " , ", DeviceName: "sensor01"}, true, true},
+		{"Bad mapping", map[string]string{Mappings: "$.temp", DeviceName: "sensor01"}, true, true},
+		{"Bad path", map[string]string{Mappings: "temperature=temp", DeviceName: "sensor01"}, true, true},
+		{"Bad device name path", map[string]string{Mappings: "temperature=$.temp", DeviceName: "$[id"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.MapJSON(tt.params)
+			eventTrx := configurable.MapJSONToEvent(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from MapJSON should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from MapJSON should not be nil")
+			}
+			if tt.expectEventNil {
+				assert.Nil(t, eventTrx, "return result from MapJSONToEvent should be nil")
+			} else {
+				assert.NotNil(t, eventTrx, "return result from MapJSONToEvent should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableScaleReadings(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Valid", map[string]string{Rules: "temperature: from=degF to=degC, level: scale=0.1 offset=-5 to=cm"}, false},
+		{"Missing rules", map[string]string{}, true},
+		{"Empty rules", map[string]string{Rules: ""}, true},
+		{"Bad setting", map[string]string{Rules: "temperature: factor=2"}, true},
+		{"Bad units", map[string]string{Rules: "temperature: from=degF to=kPa"}, true},
+		{"Duplicate rules", map[string]string{Rules: "temperature: scale=2, temperature: offset=1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.ScaleReadings(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from ScaleReadings should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from ScaleReadings should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableAggregateReadings(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Tumbling", map[string]string{WindowSize: "1m"}, false},
+		{"Sliding", map[string]string{WindowSize: "5m", Slide: "1m", Statistics: "Min, max, avg"}, false},
+		{"Missing window size", map[string]string{}, true},
+		{"Bad window size", map[string]string{WindowSize: "one minute"}, true},
+		{"Bad slide", map[string]string{WindowSize: "5m", Slide: "2m"}, true},
+		{"Bad statistic", map[string]string{WindowSize: "1m", Statistics: "median"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.AggregateReadings(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from AggregateReadings should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from AggregateReadings should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableDeduplicate(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+			ServiceKey:    "app-test",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{WindowSize: "10m"}, false},
+		{"All parameters", map[string]string{WindowSize: "10m", KeyType: "EventID", MaxEntries: "100", Persist: "true"}, false},
+		{"Missing window size", map[string]string{}, true},
+		{"Bad window size", map[string]string{WindowSize: "ten minutes"}, true},
+		{"Bad key type", map[string]string{WindowSize: "10m", KeyType: "device"}, true},
+		{"Bad max entries", map[string]string{WindowSize: "10m", MaxEntries: "many"}, true},
+		{"Bad persist", map[string]string{WindowSize: "10m", Persist: "maybe"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.Deduplicate(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from Deduplicate should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from Deduplicate should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableRateLimit(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{Limit: "10", TimeInterval: "1s"}, false},
+		{"All parameters", map[string]string{Limit: "10", TimeInterval: "1m", KeyType: "Device", Mode: "delay", MaxBacklog: "100"}, false},
+		{"Missing limit", map[string]string{TimeInterval: "1s"}, true},
+		{"Missing interval", map[string]string{Limit: "10"}, true},
+		{"Bad limit", map[string]string{Limit: "ten", TimeInterval: "1s"}, true},
+		{"Bad key type", map[string]string{Limit: "10", TimeInterval: "1s", KeyType: "profile"}, true},
+		{"Bad mode", map[string]string{Limit: "10", TimeInterval: "1s", Mode: "queue"}, true},
+		{"Bad backlog", map[string]string{Limit: "10", TimeInterval: "1s", MaxBacklog: "many"}, true},
+		{"Zero backlog", map[string]string{Limit: "10", TimeInterval: "1s", MaxBacklog: "0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.RateLimit(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from RateLimit should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from RateLimit should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableFilterByDeadband(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Absolute", map[string]string{Deadband: "0.5"}, false},
+		{"Percent with heartbeat", map[string]string{Deadband: "5 %", Heartbeat: "15m"}, false},
+		{"Missing deadband", map[string]string{}, true},
+		{"Bad deadband", map[string]string{Deadband: "half"}, true},
+		{"Negative deadband", map[string]string{Deadband: "-1"}, true},
+		{"Bad heartbeat", map[string]string{Deadband: "1", Heartbeat: "often"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.FilterByDeadband(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from FilterByDeadband should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from FilterByDeadband should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableAddDeviceMetadata(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{}, false},
+		{"Context target", map[string]string{Fields: "Profile, Model", Target: "Context", CacheTTL: "10m"}, false},
+		{"Bad field", map[string]string{Fields: "profile, firmware"}, true},
+		{"Bad target", map[string]string{Target: "readings"}, true},
+		{"Bad cache TTL", map[string]string{CacheTTL: "forever"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.AddDeviceMetadata(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from AddDeviceMetadata should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from AddDeviceMetadata should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableEditTags(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Remove", map[string]string{Remove: "GatewayId, Latitude"}, false},
+		{"Rename", map[string]string{Rename: "GatewayId:Gateway, Latitude:Lat"}, false},
+		{"Remove and rename", map[string]string{Remove: "Longitude", Rename: "GatewayId:Gateway"}, false},
+		{"No parameters", map[string]string{}, true},
+		{"Empty remove", map[string]string{Remove: " , "}, true},
+		{"Bad rename", map[string]string{Rename: "GatewayId"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.EditTags(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from EditTags should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from EditTags should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableAddLookupTags(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	lookupFile := filepath.Join(t.TempDir(), "assets.csv")
+	require.NoError(t, ioutil.WriteFile(lookupFile, []byte("device,asset,costcentre\nBoiler-01,A-1001,CC-7\n"), 0644))
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{LookupFile: lookupFile}, false},
+		{"All parameters", map[string]string{LookupFile: lookupFile, KeyColumn: "device", KeyTag: "site", Columns: "asset, costcentre", ReloadInterval: "1m"}, false},
+		{"Missing file parameter", map[string]string{}, true},
+		{"Missing file", map[string]string{LookupFile: lookupFile + ".missing"}, true},
+		{"Bad key column", map[string]string{LookupFile: lookupFile, KeyColumn: "serial"}, true},
+		{"Bad reload interval", map[string]string{LookupFile: lookupFile, ReloadInterval: "often"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.AddLookupTags(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from AddLookupTags should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from AddLookupTags should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableDetectAnomalies(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Defaults", map[string]string{Threshold: "3"}, false},
+		{"EWMA notify", map[string]string{Threshold: "5.5", Method: "EWMA", Action: "notify", Alpha: "0.2"}, false},
+		{"Z-score filter", map[string]string{Threshold: "2", Action: "filter", Samples: "50", MinSamples: "20"}, false},
+		{"Missing threshold", map[string]string{}, true},
+		{"Bad threshold", map[string]string{Threshold: "high"}, true},
+		{"Zero threshold", map[string]string{Threshold: "0"}, true},
+		{"Bad method", map[string]string{Threshold: "3", Method: "iforest"}, true},
+		{"Bad action", map[string]string{Threshold: "3", Action: "alert"}, true},
+		{"Bad samples", map[string]string{Threshold: "3", Samples: "many"}, true},
+		{"Bad alpha", map[string]string{Threshold: "3", Alpha: "2"}, true},
+		{"Min std dev and cooldown", map[string]string{Threshold: "3", Action: "notify", MinStdDev: "0.5", Cooldown: "10m"}, false},
+		{"Bad min std dev", map[string]string{Threshold: "3", MinStdDev: "-1"}, true},
+		{"Bad cooldown", map[string]string{Threshold: "3", Cooldown: "soon"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.DetectAnomalies(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from DetectAnomalies should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from DetectAnomalies should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableAttributeFilters(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		function  func(map[string]string) appcontext.AppFunction
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Device name glob", configurable.FilterByDeviceNamePattern, map[string]string{DeviceNames: "Random-*", FilterOut: "true"}, false},
+		{"Device name regex", configurable.FilterByDeviceNamePattern, map[string]string{DeviceNames: "Random-(Float|Integer)", Regex: "true"}, false},
+		{"Device name missing", configurable.FilterByDeviceNamePattern, map[string]string{}, true},
+		{"Device name bad regex", configurable.FilterByDeviceNamePattern, map[string]string{DeviceNames: "Random-(", Regex: "true"}, true},
+		{"Device name bad regex flag", configurable.FilterByDeviceNamePattern, map[string]string{DeviceNames: "Random-*", Regex: "maybe"}, true},
+		{"Device name bad filter out", configurable.FilterByDeviceNamePattern, map[string]string{DeviceNames: "Random-*", FilterOut: "maybe"}, true},
+		{"Reading name", configurable.FilterByReadingNamePattern, map[string]string{ReadingNames: "temp*"}, false},
+		{"Reading name missing", configurable.FilterByReadingNamePattern, map[string]string{DeviceNames: "temp*"}, true},
+		{"Tags", configurable.FilterByTags, map[string]string{Tags: "site:north, line:*"}, false},
+		{"Tags missing", configurable.FilterByTags, map[string]string{}, true},
+		{"Tags bad format", configurable.FilterByTags, map[string]string{Tags: "site"}, true},
+		{"Value type", configurable.FilterByValueType, map[string]string{ValueTypes: "Int32, Float64", FilterOut: "false"}, false},
+		{"Value type missing", configurable.FilterByValueType, map[string]string{}, true},
+		{"Value", configurable.FilterByValue, map[string]string{Conditions: "temperature > 80"}, false},
+		{"Value missing", configurable.FilterByValue, map[string]string{}, true},
+		{"Value bad condition", configurable.FilterByValue, map[string]string{Conditions: "temperature is hot"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := tt.function(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result should not be nil")
+			}
+		})
+	}
+}
+
+func TestConfigurableSplitByReading(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	assert.NotNil(t, configurable.SplitByReading(), "return result from SplitByReading should not be nil")
+}
+
+func TestConfigurableJoinEvents(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		expectNil bool
+	}{
+		{"Valid", map[string]string{DeviceNames: "device1, device2", Tolerance: "500ms", DeviceName: "joined"}, false},
+		{"Missing device names", map[string]string{Tolerance: "500ms", DeviceName: "joined"}, true},
+		{"Missing tolerance", map[string]string{DeviceNames: "device1, device2", DeviceName: "joined"}, true},
+		{"Missing device name", map[string]string{DeviceNames: "device1, device2", Tolerance: "500ms"}, true},
+		{"Single device", map[string]string{DeviceNames: "device1", Tolerance: "500ms", DeviceName: "joined"}, true},
+		{"Bad tolerance", map[string]string{DeviceNames: "device1, device2", Tolerance: "soon", DeviceName: "joined"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trx := configurable.JoinEvents(tt.params)
+			if tt.expectNil {
+				assert.Nil(t, trx, "return result from JoinEvents should be nil")
+			} else {
+				assert.NotNil(t, trx, "return result from JoinEvents should not be nil")
+			}
+		})
+	}
+}
+
 func TestConfigurableHTTPPost(t *testing.T) {
 	configurable := AppFunctionsSDKConfigurable{
 		Sdk: &AppFunctionsSDK{
@@ -234,7 +848,7 @@ func TestBatchByTime(t *testing.T) {
 	}
 
 	params := make(map[string]string)
-	params[TimeInterval] = "10"
+	params[TimeInterval] = "10s"
 	trx := configurable.BatchByTime(params)
 	assert.NotNil(t, trx, "return result from MQTTSend should not be nil")
 }
@@ -247,12 +861,83 @@ func TestBatchByTimeAndCount(t *testing.T) {
 
 	params := make(map[string]string)
 	params[BatchThreshold] = "30"
-	params[TimeInterval] = "10"
+	params[TimeInterval] = "10s"
 
 	trx := configurable.BatchByTimeAndCount(params)
 	assert.NotNil(t, trx, "return result from MQTTSend should not be nil")
 }
 
+func TestBatchBySize(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		Name          string
+		ByteThreshold string
+		ExpectNil     bool
+	}{
+		{"Valid", "1048576", false},
+		{"Missing", "", true},
+		{"Not a number", "1MB", true},
+		{"Zero", "0", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			params := make(map[string]string)
+			if len(test.ByteThreshold) > 0 {
+				params[ByteThreshold] = test.ByteThreshold
+			}
+
+			trx := configurable.BatchBySize(params)
+			assert.Equal(t, test.ExpectNil, trx == nil)
+		})
+	}
+}
+
+func TestBatchByteThresholdParameter(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	params := map[string]string{BatchThreshold: "30", TimeInterval: "10s", ByteThreshold: "4096"}
+	assert.NotNil(t, configurable.BatchByCount(params))
+	assert.NotNil(t, configurable.BatchByTime(params))
+	assert.NotNil(t, configurable.BatchByTimeAndCount(params))
+
+	params[ByteThreshold] = "-1"
+	assert.Nil(t, configurable.BatchByCount(params))
+	assert.Nil(t, configurable.BatchByTime(params))
+	assert.Nil(t, configurable.BatchByTimeAndCount(params))
+
+	params[TimeInterval] = "10"
+	delete(params, ByteThreshold)
+	assert.Nil(t, configurable.BatchByTime(params), "time interval without unit is invalid")
+}
+
+func TestBatchPersistParameters(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+			ServiceKey:    "test-service",
+		},
+	}
+
+	params := map[string]string{TimeInterval: "10s", PersistFile: filepath.Join(t.TempDir(), "batch.json")}
+	assert.NotNil(t, configurable.BatchByTime(params))
+
+	params[Persist] = "true"
+	assert.Nil(t, configurable.BatchByTime(params), "persist requires StoreAndForward to be enabled")
+
+	params[Persist] = "maybe"
+	assert.Nil(t, configurable.BatchByTime(params))
+}
+
 func TestJSONLogic(t *testing.T) {
 	params := make(map[string]string)
 	params[Rule] = "{}"
@@ -287,6 +972,15 @@ func TestConfigurableMQTTSecretSend(t *testing.T) {
 
 	trx := configurable.MQTTSecretSend(params)
 	assert.NotNil(t, trx, "return result from MQTTSend should not be nil")
+
+	params[ProtocolVersion] = "5"
+	params[ResponseTopic] = "reply"
+	trx = configurable.MQTTSecretSend(params)
+	assert.NotNil(t, trx, "return result from MQTTSend should not be nil for MQTT 5")
+
+	params[ProtocolVersion] = "6"
+	trx = configurable.MQTTSecretSend(params)
+	assert.Nil(t, trx, "return result from MQTTSend should be nil for invalid protocol version")
 }
 
 func TestAppFunctionsSDKConfigurable_AddTags(t *testing.T) {
@@ -309,6 +1003,8 @@ func TestAppFunctionsSDKConfigurable_AddTags(t *testing.T) {
 		{"Bad - Missing key", Tags, "GatewayId:HoustonStore000123,:29.630771,Longitude:-95.377603", true},
 		{"Bad - Missing key & value", Tags, ":,:,:", true},
 		{"Bad - No Tags parameter", "NotTags", ":,:,:", true},
+		{"Good - templated values", Tags, `GatewayId:{{.Device}},Day:{{now "15:04"}}`, false},
+		{"Bad - template", Tags, "GatewayId:{{.Device", true},
 	}
 
 	for _, testCase := range tests {
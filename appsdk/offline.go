@@ -0,0 +1,132 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	bootstrapContainer "github.com/edgexfoundry/go-mod-bootstrap/bootstrap/container"
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap/flags"
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap/startup"
+	"github.com/edgexfoundry/go-mod-bootstrap/di"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/security"
+	"github.com/jcerato/app-functions-sdk-go/internal/trigger/offline"
+)
+
+// offlineStdin is the value of the input flag for reading the envelopes from stdin
+const offlineStdin = "-"
+
+// isOffline returns true when the service runs as a one-shot filter over the envelopes from the input flag
+func (sdk *AppFunctionsSDK) isOffline() bool {
+	return len(sdk.offlineInput) > 0
+}
+
+// prepareOffline sets up the process for running offline. Must be called before any logging client is created.
+func (sdk *AppFunctionsSDK) prepareOffline(sdkFlags flags.Common) error {
+	if sdkFlags.UseRegistry() || len(sdkFlags.ConfigProviderUrl()) > 0 {
+		return errors.New("the Registry and Configuration Provider can not be used with the -i/--input option")
+	}
+
+	// The output data is written to stdout and the logging to stderr, see newStderrLogger
+	sdk.offlineOutput = os.Stdout
+
+	// Secrets come from the InsecureSecrets configuration rather than Vault
+	return os.Setenv(security.EnvSecretStore, "false")
+}
+
+// bootstrapOffline runs the bootstrap with os.Stdout pointing to stderr. go-mod-bootstrap always logs to os.Stdout
+// while loading the configuration, before any bootstrap handler runs, which would mix its log lines in with the
+// output data. os.Stdout is restored as soon as the bootstrap returns, the SDK's logging then goes to stderr using
+// the LoggingClient created by offlineLoggingHandler.
+func (sdk *AppFunctionsSDK) bootstrapOffline(bootstrap func()) {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	bootstrap()
+}
+
+// offlineLoggingHandler replaces the LoggingClient created by the bootstrap with one which logs to stderr, so the
+// logging is kept apart from the output data written to stdout
+func (sdk *AppFunctionsSDK) offlineLoggingHandler(_ context.Context, _ *sync.WaitGroup, _ startup.Timer, dic *di.Container) bool {
+	lc := newStderrLogger(sdk.ServiceKey, sdk.config.Writable.LogLevel)
+	dic.Update(di.ServiceConstructorMap{
+		bootstrapContainer.LoggingClientInterfaceName: func(get di.Get) interface{} {
+			return lc
+		},
+	})
+
+	return true
+}
+
+// newStderrLogger creates the EdgeX LoggingClient logging to stderr at the log level, INFO when the level is
+// invalid. The client has no option for where it logs, it logs to whatever os.Stdout is when it is created, so
+// os.Stdout points to stderr while it is created.
+func newStderrLogger(serviceKey string, logLevel string) logger.LoggingClient {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	defer func() { os.Stdout = stdout }()
+
+	return logger.NewClientStdOut(serviceKey, false, logLevel)
+}
+
+// runOffline runs the pipeline for each envelope from the input and then stops the service
+func (sdk *AppFunctionsSDK) runOffline() error {
+	var input io.Reader = os.Stdin
+	if sdk.offlineInput != offlineStdin {
+		file, err := os.Open(sdk.offlineInput)
+		if err != nil {
+			sdk.LoggingClient.Error(err.Error())
+			return errors.New("Failed to open input")
+		}
+		defer file.Close()
+		input = file
+	}
+
+	output := sdk.offlineOutput
+	if output == nil {
+		output = os.Stdout
+	}
+
+	t := &offline.Trigger{
+		Configuration: sdk.config,
+		Runtime:       sdk.runtime,
+		EdgeXClients:  sdk.EdgexClients,
+		Input:         input,
+		Output:        output,
+	}
+
+	err := t.Run(sdk.appCtx)
+
+	sdk.appCancelCtx() // Cancel all long running go funcs
+	sdk.appWg.Wait()
+
+	// Send any data still held by the pipeline functions, i.e. batched data, before disconnecting
+	sdk.runtime.Shutdown()
+
+	for _, deferredFunc := range sdk.deferredFunctions {
+		deferredFunc()
+	}
+
+	return err
+}
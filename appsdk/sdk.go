@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	nethttp "net/http"
 	"net/url"
 	"os"
@@ -52,6 +53,7 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/internal/bootstrap/container"
 	"github.com/jcerato/app-functions-sdk-go/internal/bootstrap/handlers"
 	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/recorder"
 	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
 	"github.com/jcerato/app-functions-sdk-go/internal/security"
 	"github.com/jcerato/app-functions-sdk-go/internal/store/db/interfaces"
@@ -59,6 +61,7 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/internal/trigger/http"
 	"github.com/jcerato/app-functions-sdk-go/internal/trigger/messagebus"
 	"github.com/jcerato/app-functions-sdk-go/internal/trigger/mqtt"
+	"github.com/jcerato/app-functions-sdk-go/internal/trigger/replay"
 	"github.com/jcerato/app-functions-sdk-go/internal/webserver"
 	"github.com/jcerato/app-functions-sdk-go/pkg/util"
 )
@@ -78,6 +81,7 @@ const (
 	bindingTypeEdgeXMessageBus = "EDGEX-MESSAGEBUS"
 	bindingTypeMQTT            = "EXTERNAL-MQTT"
 	bindingTypeHTTP            = "HTTP"
+	bindingTypeReplay          = "REPLAY"
 
 	OptionalPasswordKey = "Password"
 )
@@ -128,6 +132,27 @@ type AppFunctionsSDK struct {
 	deferredFunctions         []bootstrap.Deferred
 	serviceKeyOverride        string
 	backgroundChannel         <-chan types.MessageEnvelope
+	offlineInput              string
+	offlineOutput             io.Writer
+	// pipelineFunctions are the functions of the configurable pipeline by name, kept for reloading the pipeline
+	pipelineFunctions map[string]*pipelineFunction
+	// loadingFunction is the configurable pipeline function being created
+	loadingFunction *pipelineFunction
+}
+
+// pipelineFunction is a function of the configurable pipeline along with the configuration it was created with.
+// Functions which register their state, i.e. the batched data, with keepOnReload are reused when the pipeline is
+// reloaded without changes to their configuration, so the state isn't lost. All other functions are created again,
+// as they always have been, so they pick up changes made elsewhere, i.e. to the application settings or secrets.
+type pipelineFunction struct {
+	configuration common.PipelineFunction
+	function      appcontext.AppFunction
+	// state is registered by the function with keepOnReload, nil when the function isn't reused
+	state interface{}
+	// close, if set, is called when the function is replaced or removed by reloading the pipeline
+	close func()
+	// replaces is the function with the same name being replaced, only set while this function is created
+	replaces *pipelineFunction
 }
 
 // AddRoute allows you to leverage the existing webserver to add routes.
@@ -165,6 +190,26 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 	sdk.runtime.Initialize(sdk.storeClient, sdk.secretProvider)
 	sdk.runtime.SetTransforms(sdk.transforms)
 
+	if sdk.config.Recorder.Enabled {
+		messageRecorder, err := recorder.NewRecorder(sdk.config.Recorder, sdk.LoggingClient)
+		if err != nil {
+			sdk.LoggingClient.Error(err.Error())
+			return errors.New("Failed to create Recorder")
+		}
+
+		sdk.LoggingClient.Info(fmt.Sprintf("Recording inbound messages to '%s'", messageRecorder.Path()))
+		sdk.runtime.SetRecorder(messageRecorder)
+		sdk.addDeferred(func() {
+			if err := messageRecorder.Close(); err != nil {
+				sdk.LoggingClient.Error("Unable to close the Recorder", "error", err.Error())
+			}
+		})
+	}
+
+	if sdk.isOffline() {
+		return sdk.runOffline()
+	}
+
 	// determine input type and create trigger for it
 	t := sdk.setupTrigger(sdk.config, sdk.runtime)
 	if t == nil {
@@ -211,6 +256,9 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 	sdk.appCancelCtx() // Cancel all long running go funcs
 	sdk.appWg.Wait()
 
+	// Send any data still held by the pipeline functions, i.e. batched data, before disconnecting
+	sdk.runtime.Shutdown()
+
 	// Call all the deferred funcs that need to happen when exiting.
 	// These are things like un-register from the Registry, disconnect from the Message Bus, etc
 	for _, deferredFunc := range sdk.deferredFunctions {
@@ -223,6 +271,7 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 // LoadConfigurablePipeline ...
 func (sdk *AppFunctionsSDK) LoadConfigurablePipeline() ([]appcontext.AppFunction, error) {
 	var pipeline []appcontext.AppFunction
+	loaded := make(map[string]*pipelineFunction)
 
 	sdk.usingConfigurablePipeline = true
 
@@ -264,6 +313,23 @@ func (sdk *AppFunctionsSDK) LoadConfigurablePipeline() ([]appcontext.AppFunction
 		for key := range configuration.Parameters {
 			configuration.Parameters[strings.ToLower(key)] = configuration.Parameters[key]
 		}
+
+		_, duplicate := loaded[functionName]
+		previous := sdk.pipelineFunctions[functionName]
+		if previous != nil && !duplicate {
+			if previous.state != nil && reflect.DeepEqual(previous.configuration, configuration) {
+				loaded[functionName] = previous
+				pipeline = append(pipeline, previous.function)
+				sdk.LoggingClient.Debug(fmt.Sprintf("%s function configuration unchanged, reusing it", functionName))
+				continue
+			}
+
+			// Closed before the new function is created, so the new one doesn't restore data the old one sends
+			if previous.close != nil {
+				previous.close()
+			}
+			delete(sdk.pipelineFunctions, functionName)
+		}
 		for index := range inputParameters {
 			parameter := result.Type().In(index)
 
@@ -283,17 +349,62 @@ func (sdk *AppFunctionsSDK) LoadConfigurablePipeline() ([]appcontext.AppFunction
 			}
 		}
 
+		current := &pipelineFunction{configuration: copyPipelineFunction(configuration)}
+		if !duplicate {
+			current.replaces = previous
+			sdk.loadingFunction = current
+		}
 		function, ok := result.Call(inputParameters)[0].Interface().(appcontext.AppFunction)
+		sdk.loadingFunction = nil
+		current.replaces = nil
 		if !ok {
 			return nil, fmt.Errorf("failed to cast function %s as AppFunction type", functionName)
 		}
+		if !duplicate {
+			current.function = function
+			loaded[functionName] = current
+		}
 		pipeline = append(pipeline, function)
 		configurable.Sdk.LoggingClient.Debug(fmt.Sprintf("%s function added to configurable pipeline", functionName))
 	}
 
+	for functionName, previous := range sdk.pipelineFunctions {
+		if loaded[functionName] != previous && previous.close != nil {
+			previous.close()
+		}
+	}
+	sdk.pipelineFunctions = loaded
+
 	return pipeline, nil
 }
 
+// keepOnReload registers the state of the configurable pipeline function being created, so the function is reused
+// when the pipeline is reloaded without changes to its configuration, along with its close function, if any, which
+// is called when the function is replaced or removed by reloading the pipeline. The state registered by the function
+// being replaced is returned, if any, so the new function can carry it over.
+func (sdk *AppFunctionsSDK) keepOnReload(state interface{}, close func()) interface{} {
+	if sdk.loadingFunction == nil {
+		return nil
+	}
+
+	sdk.loadingFunction.state = state
+	sdk.loadingFunction.close = close
+	if sdk.loadingFunction.replaces == nil {
+		return nil
+	}
+	return sdk.loadingFunction.replaces.state
+}
+
+// copyPipelineFunction returns a copy of the configuration, which doesn't share the parameters map
+func copyPipelineFunction(configuration common.PipelineFunction) common.PipelineFunction {
+	parameters := make(map[string]string, len(configuration.Parameters))
+	for key, value := range configuration.Parameters {
+		parameters[key] = value
+	}
+	configuration.Parameters = parameters
+	return configuration
+}
+
 // SetFunctionsPipeline allows you to define each fgitunction to execute and the order in which each function
 // will be called as each event comes in.
 func (sdk *AppFunctionsSDK) SetFunctionsPipeline(transforms ...appcontext.AppFunction) error {
@@ -341,18 +452,34 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 		"    -s/--skipVersionCheck           Indicates the service should skip the Core Service's version compatibility check.\n" +
 			"    -sk/--serviceKey                Overrides the service service key used with Registry and/or Configuration Providers.\n" +
 			"                                    If the name provided contains the text `<profile>`, this text will be replaced with\n" +
-			"                                    the name of the profile used."
+			"                                    the name of the profile used.\n" +
+			"    -i/--input <file>               Runs the pipeline once for each MessageEnvelope (JSON) read from the file, or\n" +
+			"                                    stdin when '-', writes the resulting output data to stdout and then exits.\n" +
+			"                                    The Registry, Vault, Database and MessageBus are not used in this mode."
 
 	sdkFlags := flags.NewWithUsage(additionalUsage)
 	sdkFlags.FlagSet.BoolVar(&sdk.skipVersionCheck, "skipVersionCheck", false, "")
 	sdkFlags.FlagSet.BoolVar(&sdk.skipVersionCheck, "s", false, "")
 	sdkFlags.FlagSet.StringVar(&sdk.serviceKeyOverride, "serviceKey", "", "")
 	sdkFlags.FlagSet.StringVar(&sdk.serviceKeyOverride, "sk", "", "")
+	sdkFlags.FlagSet.StringVar(&sdk.offlineInput, "input", "", "")
+	sdkFlags.FlagSet.StringVar(&sdk.offlineInput, "i", "", "")
 
 	sdkFlags.Parse(os.Args[1:])
 
-	// Temporarily setup logging to STDOUT so the client can be used before bootstrapping is completed
-	sdk.LoggingClient = logger.NewClientStdOut(sdk.ServiceKey, false, "INFO")
+	if sdk.isOffline() {
+		if err := sdk.prepareOffline(sdkFlags); err != nil {
+			return err
+		}
+	}
+
+	// Temporarily setup logging to STDOUT so the client can be used before bootstrapping is completed.
+	// When offline the output data goes to STDOUT, so the logging goes to STDERR.
+	if sdk.isOffline() {
+		sdk.LoggingClient = newStderrLogger(sdk.ServiceKey, "INFO")
+	} else {
+		sdk.LoggingClient = logger.NewClientStdOut(sdk.ServiceKey, false, "INFO")
+	}
 
 	sdk.setServiceKey(sdkFlags.Profile())
 
@@ -380,24 +507,26 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 	var successful bool
 	var configUpdated config.UpdatedStream = make(chan struct{})
 
-	sdk.appWg, deferred, successful = bootstrap.RunAndReturnWaitGroup(
-		sdk.appCtx,
-		sdk.appCancelCtx,
-		sdkFlags,
-		sdk.ServiceKey,
-		internal.ConfigRegistryStem,
-		sdk.config,
-		configUpdated,
-		startupTimer,
-		dic,
-		[]bootstrapInterfaces.BootstrapHandler{
-			handlers.NewSecrets().BootstrapHandler,
-			handlers.NewDatabase().BootstrapHandler,
-			handlers.NewClients().BootstrapHandler,
-			handlers.NewTelemetry().BootstrapHandler,
-			handlers.NewVersionValidator(sdk.skipVersionCheck, internal.SDKVersion).BootstrapHandler,
-		},
-	)
+	runBootstrap := func() {
+		sdk.appWg, deferred, successful = bootstrap.RunAndReturnWaitGroup(
+			sdk.appCtx,
+			sdk.appCancelCtx,
+			sdkFlags,
+			sdk.ServiceKey,
+			internal.ConfigRegistryStem,
+			sdk.config,
+			configUpdated,
+			startupTimer,
+			dic,
+			sdk.bootstrapHandlers(),
+		)
+	}
+
+	if sdk.isOffline() {
+		sdk.bootstrapOffline(runBootstrap)
+	} else {
+		runBootstrap()
+	}
 
 	// deferred is a a function that needs to be called when services exits.
 	sdk.addDeferred(deferred)
@@ -416,11 +545,13 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 	sdk.EdgexClients.ValueDescriptorClient = container.ValueDescriptorClientFrom(dic.Get)
 	sdk.EdgexClients.NotificationsClient = container.NotificationsClientFrom(dic.Get)
 	sdk.EdgexClients.CommandClient = container.CommandClientFrom(dic.Get)
+	sdk.EdgexClients.DeviceClient = container.DeviceClientFrom(dic.Get)
 
 	// If using the RedisStreams MessageBus implementation then need to make sure the
 	// password for the Redis DB is set in the MessageBus Optional properties.
 	bindingType := strings.ToUpper(sdk.config.Binding.Type)
-	if (bindingType == bindingTypeMessageBus || bindingType == bindingTypeEdgeXMessageBus) &&
+	if !sdk.isOffline() &&
+		(bindingType == bindingTypeMessageBus || bindingType == bindingTypeEdgeXMessageBus) &&
 		sdk.config.MessageBus.Type == messaging.RedisStreams {
 		credentials, err := sdk.secretProvider.GetDatabaseCredentials(sdk.config.Database)
 		if err != nil {
@@ -441,6 +572,27 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 	return nil
 }
 
+// bootstrapHandlers returns the bootstrap handlers to run. The Database and VersionValidator handlers are
+// skipped when running offline since neither the Database nor Core Data are used.
+func (sdk *AppFunctionsSDK) bootstrapHandlers() []bootstrapInterfaces.BootstrapHandler {
+	if sdk.isOffline() {
+		return []bootstrapInterfaces.BootstrapHandler{
+			sdk.offlineLoggingHandler,
+			handlers.NewSecrets().BootstrapHandler,
+			handlers.NewClients().BootstrapHandler,
+			handlers.NewTelemetry().BootstrapHandler,
+		}
+	}
+
+	return []bootstrapInterfaces.BootstrapHandler{
+		handlers.NewSecrets().BootstrapHandler,
+		handlers.NewDatabase().BootstrapHandler,
+		handlers.NewClients().BootstrapHandler,
+		handlers.NewTelemetry().BootstrapHandler,
+		handlers.NewVersionValidator(sdk.skipVersionCheck, internal.SDKVersion).BootstrapHandler,
+	}
+}
+
 // GetSecrets retrieves secrets from a secret store.
 // path specifies the type or location of the secrets to retrieve. If specified it is appended
 // to the base path from the SecretConfig
@@ -478,6 +630,10 @@ func (sdk *AppFunctionsSDK) setupTrigger(configuration *common.ConfigurationStru
 		sdk.LoggingClient.Info("External MQTT trigger selected")
 		t = mqtt.NewTrigger(configuration, runtime, sdk.EdgexClients, sdk.secretProvider)
 
+	case bindingTypeReplay:
+		sdk.LoggingClient.Info("Replay trigger selected")
+		t = &replay.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.EdgexClients}
+
 	default:
 		sdk.LoggingClient.Error(fmt.Sprintf("Invalid Trigger type of '%s' specified", configuration.Binding.Type))
 	}
@@ -17,16 +17,24 @@
 package appsdk
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	bootstrapContainer "github.com/edgexfoundry/go-mod-bootstrap/bootstrap/container"
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap/startup"
+	"github.com/edgexfoundry/go-mod-bootstrap/di"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 
@@ -35,7 +43,9 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
 	triggerHttp "github.com/jcerato/app-functions-sdk-go/internal/trigger/http"
 	"github.com/jcerato/app-functions-sdk-go/internal/trigger/messagebus"
+	"github.com/jcerato/app-functions-sdk-go/internal/trigger/replay"
 	"github.com/jcerato/app-functions-sdk-go/internal/webserver"
+	"github.com/jcerato/app-functions-sdk-go/pkg/transforms"
 )
 
 var lc logger.LoggingClient
@@ -119,6 +129,23 @@ func TestSetupMessageBusTrigger(t *testing.T) {
 	assert.True(t, result, "Expected Instance of Message Bus Trigger")
 }
 
+func TestSetupReplayTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "replay",
+			},
+		},
+	}
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms(sdk.transforms)
+	trigger := sdk.setupTrigger(sdk.config, testRuntime)
+	result := IsInstanceOf(trigger, (*replay.Trigger)(nil))
+	assert.True(t, result, "Expected Instance of Replay Trigger")
+}
+
 func TestSetFunctionsPipelineNoTransforms(t *testing.T) {
 	sdk := AppFunctionsSDK{
 		LoggingClient: lc,
@@ -325,6 +352,124 @@ func TestLoadConfigurablePipelineNumFunctions(t *testing.T) {
 	assert.Equal(t, 3, len(appFunctions))
 }
 
+func TestLoadConfigurablePipelineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.json")
+	batchFunction := func(timeInterval string) common.PipelineFunction {
+		return common.PipelineFunction{
+			Parameters: map[string]string{"TimeInterval": timeInterval, "PersistFile": path},
+		}
+	}
+
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Writable: common.WritableInfo{
+				Pipeline: common.PipelineInfo{
+					ExecutionOrder: "DetectAnomalies, BatchByTime, SetOutputData",
+					Functions: map[string]common.PipelineFunction{
+						"DetectAnomalies": {Parameters: map[string]string{"Threshold": "3"}},
+						"BatchByTime":     batchFunction("1h"),
+						"SetOutputData":   {},
+					},
+				},
+			},
+		},
+	}
+
+	var resumed []string
+	edgexcontext := &appcontext.Context{
+		LoggingClient: lc,
+		ResumePipeline: func(result interface{}) error {
+			resumed = append(resumed, string(result.([]byte)))
+			return nil
+		},
+	}
+
+	appFunctions, err := sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	appFunctions[1](edgexcontext, []byte(`"batched"`))
+	detector := sdk.pipelineFunctions["DetectAnomalies"]
+	batch := sdk.pipelineFunctions["BatchByTime"]
+	output := sdk.pipelineFunctions["SetOutputData"]
+
+	// Unchanged configuration keeps the batch and the anomaly statistics, functions without state are created again
+	sdk.config.Writable.Pipeline.Functions = map[string]common.PipelineFunction{
+		"DetectAnomalies": {Parameters: map[string]string{"Threshold": "3"}},
+		"BatchByTime":     batchFunction("1h"),
+		"SetOutputData":   {},
+	}
+	_, err = sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	assert.Same(t, detector, sdk.pipelineFunctions["DetectAnomalies"])
+	assert.Same(t, batch, sdk.pipelineFunctions["BatchByTime"])
+	assert.NotSame(t, output, sdk.pipelineFunctions["SetOutputData"])
+	assert.Nil(t, resumed)
+
+	// Changed configuration sends the batch before the new batch restores the persisted data
+	sdk.config.Writable.Pipeline.Functions["BatchByTime"] = batchFunction("2h")
+	appFunctions, err = sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	assert.NotSame(t, batch, sdk.pipelineFunctions["BatchByTime"])
+	assert.Equal(t, []string{`["batched"]`}, resumed)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "sent batch should no longer be persisted")
+
+	// Removed function sends its batch
+	appFunctions[1](edgexcontext, []byte(`"removed"`))
+	sdk.config.Writable.Pipeline.ExecutionOrder = "SetOutputData"
+	_, err = sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	assert.Equal(t, []string{`["batched"]`, `["removed"]`}, resumed)
+	assert.NotContains(t, sdk.pipelineFunctions, "BatchByTime")
+}
+
+func TestLoadConfigurablePipelineReloadAnomalyThreshold(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Writable: common.WritableInfo{
+				Pipeline: common.PipelineInfo{
+					ExecutionOrder: "DetectAnomalies",
+					Functions: map[string]common.PipelineFunction{
+						"DetectAnomalies": {Parameters: map[string]string{"Threshold": "3"}},
+					},
+				},
+			},
+		},
+	}
+
+	temperature := func(value string) models.Event {
+		return models.Event{
+			Device:   "thermostat",
+			Readings: []models.Reading{{Name: "temperature", Value: value, ValueType: models.ValueTypeInt32}},
+		}
+	}
+	edgexcontext := &appcontext.Context{LoggingClient: lc}
+
+	appFunctions, err := sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	for i := 0; i < transforms.DefaultAnomalyMinSamples; i++ {
+		appFunctions[0](edgexcontext, temperature([]string{"19", "21"}[i%2]))
+	}
+	detector := sdk.pipelineFunctions["DetectAnomalies"]
+
+	// The new threshold applies without seeing the minimum samples again
+	sdk.config.Writable.Pipeline.Functions = map[string]common.PipelineFunction{
+		"DetectAnomalies": {Parameters: map[string]string{"Threshold": "5"}},
+	}
+	appFunctions, err = sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	assert.NotSame(t, detector, sdk.pipelineFunctions["DetectAnomalies"])
+
+	continuePipeline, result := appFunctions[0](edgexcontext, temperature("23"))
+	require.True(t, continuePipeline)
+	assert.NotContains(t, result.(models.Event).Tags, transforms.AnomalyTag, "within the new threshold")
+
+	continuePipeline, result = appFunctions[0](edgexcontext, temperature("35"))
+	require.True(t, continuePipeline)
+	assert.Equal(t, "temperature", result.(models.Event).Tags[transforms.AnomalyTag])
+}
+
 func TestUseTargetTypeOfByteArrayTrue(t *testing.T) {
 	functions := make(map[string]common.PipelineFunction)
 	functions["CompressWithGZIP"] = common.PipelineFunction{}
@@ -495,3 +640,64 @@ func TestSetServiceKey(t *testing.T) {
 		})
 	}
 }
+
+func TestBootstrapHandlers(t *testing.T) {
+	sdk := AppFunctionsSDK{}
+	assert.Len(t, sdk.bootstrapHandlers(), 5)
+
+	sdk.offlineInput = "-"
+	assert.Len(t, sdk.bootstrapHandlers(), 4, "Database and VersionValidator handlers should be skipped and the logging replaced when offline")
+}
+
+// captureStderr returns what is logged to stderr by the LoggingClient created by create
+func captureStderr(t *testing.T, create func() logger.LoggingClient, log func(client logger.LoggingClient)) string {
+	file, err := ioutil.TempFile(t.TempDir(), "stderr")
+	require.NoError(t, err)
+	defer file.Close()
+
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stderr = file
+	client := create()
+	os.Stderr = stderr
+	assert.Equal(t, stdout, os.Stdout, "os.Stdout should be restored")
+
+	log(client)
+	logged, err := ioutil.ReadFile(file.Name())
+	require.NoError(t, err)
+	return string(logged)
+}
+
+func TestStderrLogger(t *testing.T) {
+	logged := captureStderr(t,
+		func() logger.LoggingClient { return newStderrLogger("app-test", "bogus") },
+		func(client logger.LoggingClient) {
+			client.Debug("not logged")
+			client.Info("started service", "port", 48095)
+			require.NoError(t, client.SetLogLevel(models.ErrorLog))
+			client.Warn("not logged")
+			client.Error("failed")
+		})
+
+	lines := strings.Split(strings.TrimSpace(logged), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^level=INFO ts=\S+ app=app-test source=\S+ port=48095 msg="started service"$`, lines[0])
+	assert.Regexp(t, `^level=ERROR ts=\S+ app=app-test source=\S+ msg=failed$`, lines[1])
+}
+
+func TestOfflineLoggingHandler(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		ServiceKey: "app-test",
+		config:     &common.ConfigurationStruct{Writable: common.WritableInfo{LogLevel: models.DebugLog}},
+	}
+	dic := di.NewContainer(di.ServiceConstructorMap{})
+
+	logged := captureStderr(t,
+		func() logger.LoggingClient {
+			require.True(t, sdk.offlineLoggingHandler(context.Background(), &sync.WaitGroup{}, startup.Timer{}, dic))
+			return bootstrapContainer.LoggingClientFrom(dic.Get)
+		},
+		func(client logger.LoggingClient) { client.Debug("offline") })
+
+	assert.Contains(t, logged, "level=DEBUG", "LoggingClient should log to stderr at the configured level")
+	assert.Contains(t, logged, "msg=offline")
+}
@@ -5,6 +5,7 @@ go 1.15
 require (
 	bitbucket.org/bertimus9/systemstat v0.0.0-20180207000608-0eeff89b0690
 	github.com/diegoholiveira/jsonlogic v1.0.1-0.20200220175622-ab7989be08b9
+	github.com/eclipse/paho.golang v0.10.0
 	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/edgexfoundry/app-functions-sdk-go v1.3.0 // indirect
 	github.com/edgexfoundry/go-mod-bootstrap v0.0.57
@@ -16,6 +17,7 @@ require (
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/mux v1.8.0
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	go.mongodb.org/mongo-driver v1.1.1
+	google.golang.org/protobuf v1.25.0
 )
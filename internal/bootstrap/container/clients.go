@@ -18,6 +18,7 @@ package container
 import (
 	"github.com/edgexfoundry/go-mod-bootstrap/di"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/command"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/metadata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
@@ -70,3 +71,15 @@ func CommandClientFrom(get di.Get) command.CommandClient {
 
 	return get(CommandClientName).(command.CommandClient)
 }
+
+// DeviceClientName contains the name of the DeviceClient's implementation in the DIC.
+var DeviceClientName = di.TypeInstanceToName((*metadata.DeviceClient)(nil))
+
+// DeviceClientFrom helper function queries the DIC and returns the DeviceClient's implementation.
+func DeviceClientFrom(get di.Get) metadata.DeviceClient {
+	if get(DeviceClientName) == nil {
+		return nil
+	}
+
+	return get(DeviceClientName).(metadata.DeviceClient)
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/command"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/metadata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/urlclient/local"
 
@@ -53,6 +54,7 @@ func (_ *Clients) BootstrapHandler(
 	var valueDescriptorClient coredata.ValueDescriptorClient
 	var commandClient command.CommandClient
 	var notificationsClient notifications.NotificationsClient
+	var deviceClient metadata.DeviceClient
 
 	// Use of these client interfaces is optional, so they are not required to be configured. For instance if not
 	// sending commands, then don't need to have the Command client in the configuration.
@@ -74,6 +76,11 @@ func (_ *Clients) BootstrapHandler(
 			local.New(config.Clients[common.NotificationsClientName].Url() + clients.ApiNotificationRoute))
 	}
 
+	if _, ok := config.Clients[common.CoreMetadataClientName]; ok {
+		deviceClient = metadata.NewDeviceClient(
+			local.New(config.Clients[common.CoreMetadataClientName].Url() + clients.ApiDeviceRoute))
+	}
+
 	// Note that all the clients are optional so some or all these clients may be nil
 	// Code that uses them must verify the client was defined and created prior to using it.
 	// This information is provided in the documentation.
@@ -90,6 +97,9 @@ func (_ *Clients) BootstrapHandler(
 		container.NotificationsClientName: func(get di.Get) interface{} {
 			return notificationsClient
 		},
+		container.DeviceClientName: func(get di.Get) interface{} {
+			return deviceClient
+		},
 	})
 
 	return true
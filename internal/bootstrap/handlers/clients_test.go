@@ -69,6 +69,12 @@ func TestClientsBootstrapHandler(t *testing.T) {
 		Protocol: "http",
 	}
 
+	metadataClientInfo := config.ClientInfo{
+		Host:     "localhost",
+		Port:     48081,
+		Protocol: "http",
+	}
+
 	startupTimer := startup.NewStartUpTimer("unit-test")
 
 	tests := []struct {
@@ -76,12 +82,14 @@ func TestClientsBootstrapHandler(t *testing.T) {
 		CoreDataClientInfo      *config.ClientInfo
 		CommandClientInfo       *config.ClientInfo
 		NotificationsClientInfo *config.ClientInfo
+		MetadataClientInfo      *config.ClientInfo
 	}{
 		{
 			Name:                    "All Clients",
 			CoreDataClientInfo:      &coreDataClientInfo,
 			CommandClientInfo:       &commandClientInfo,
 			NotificationsClientInfo: &notificationsClientInfo,
+			MetadataClientInfo:      &metadataClientInfo,
 		},
 		{
 			Name:                    "No Clients",
@@ -113,6 +121,10 @@ func TestClientsBootstrapHandler(t *testing.T) {
 				configuration.Clients[common.NotificationsClientName] = notificationsClientInfo
 			}
 
+			if test.MetadataClientInfo != nil {
+				configuration.Clients[common.CoreMetadataClientName] = metadataClientInfo
+			}
+
 			dic.Update(di.ServiceConstructorMap{
 				container.ConfigurationName: func(get di.Get) interface{} {
 					return configuration
@@ -126,6 +138,7 @@ func TestClientsBootstrapHandler(t *testing.T) {
 			valueDescriptorClient := container.ValueDescriptorClientFrom(dic.Get)
 			commandClient := container.CommandClientFrom(dic.Get)
 			notificationsClient := container.NotificationsClientFrom(dic.Get)
+			deviceClient := container.DeviceClientFrom(dic.Get)
 
 			if test.CoreDataClientInfo != nil {
 				assert.NotNil(t, eventClient)
@@ -146,6 +159,12 @@ func TestClientsBootstrapHandler(t *testing.T) {
 			} else {
 				assert.Nil(t, notificationsClient)
 			}
+
+			if test.MetadataClientInfo != nil {
+				assert.NotNil(t, deviceClient)
+			} else {
+				assert.Nil(t, deviceClient)
+			}
 		})
 	}
 }
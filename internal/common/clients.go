@@ -20,6 +20,7 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/command"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/metadata"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
 )
 
@@ -27,6 +28,7 @@ const (
 	CoreCommandClientName   = "Command"
 	CoreDataClientName      = "CoreData"
 	NotificationsClientName = "Notifications"
+	CoreMetadataClientName  = "Metadata"
 )
 
 type EdgeXClients struct {
@@ -35,4 +37,5 @@ type EdgeXClients struct {
 	CommandClient         command.CommandClient
 	ValueDescriptorClient coredata.ValueDescriptorClient
 	NotificationsClient   notifications.NotificationsClient
+	DeviceClient          metadata.DeviceClient
 }
@@ -67,6 +67,10 @@ type ConfigurationStruct struct {
 	SecretStore bootstrapConfig.SecretStoreInfo
 	// SecretStoreExclusive
 	SecretStoreExclusive bootstrapConfig.SecretStoreInfo
+	// Recorder
+	Recorder RecorderInfo
+	// Replay
+	Replay ReplayInfo
 }
 
 // ServiceInfo is used to hold and configure various settings related to the hosting of this service
@@ -90,10 +94,27 @@ type BindingInfo struct {
 	//
 	// example: messagebus
 	// required: true
-	// enum: messagebus (edgex-messagebus), http, external-mqtt
+	// enum: messagebus (edgex-messagebus), http, external-mqtt, replay
 	Type           string
 	SubscribeTopic string
 	PublishTopic   string
+	// AckMode is the message acknowledgement mode for the MessageBus trigger. Options are "auto" (default), which
+	// treats messages as consumed when received, and "atleastonce", which records received messages in a local
+	// write-ahead log and acknowledges them only once the pipeline has succeeded or the data has been stored for
+	// retry by Store and Forward. The MessageBus implementations don't support acknowledgement, so messages lost
+	// before they are received by the trigger are not redelivered.
+	AckMode string
+	// AckLogFile is the write-ahead log file used for the "atleastonce" AckMode. Defaults to "messagebus-ack.log".
+	AckLogFile string
+	// AckRetryInterval is how often messages which failed are replayed from the write-ahead log, i.e. "30s".
+	// Defaults to 30 seconds.
+	AckRetryInterval string
+	// AckMaxAttempts is the number of times a message is processed before it is moved to the DeadLetterFile.
+	// Defaults to 5.
+	AckMaxAttempts int
+	// DeadLetterFile is the file the messages which failed AckMaxAttempts times are appended to, one JSON
+	// MessageEnvelope per line. Defaults to "messagebus-deadletter.log".
+	DeadLetterFile string
 }
 
 // MqttBrokerConfig contains the MQTT broker configuration for MQTT Trigger
@@ -119,6 +140,17 @@ type MqttBrokerConfig struct {
 	// AuthMode indicates what to use when connecting to the broker. Options are "none", "cacert" , "usernamepassword", "clientcert".
 	// If a CA Cert exists in the SecretPath then it will be used for all modes except "none".
 	AuthMode string
+	// ProtocolVersion is the MQTT protocol version to connect with. Options are "3.1.1" (default) and "5".
+	ProtocolVersion string
+	// SharedSubscriptionGroup is the group name used to create a shared subscription ($share/<group>/<topic>)
+	// so that messages are load balanced across all the service instances subscribed with the same group.
+	SharedSubscriptionGroup string
+	// ContentType is the content type of the payloads received on the SubscribeTopic, i.e. "application/json" or
+	// "application/cbor". When not set the content type is detected from the payload.
+	ContentType string
+	// UseMessageEnvelope indicates the payloads received are EdgeX MessageEnvelopes encoded as JSON, which are
+	// unwrapped to get the actual payload, content type and correlation id.
+	UseMessageEnvelope bool
 }
 
 type PipelineInfo struct {
@@ -223,3 +255,24 @@ func durationToMill(s string) int {
 	v, _ := time.ParseDuration(s)
 	return int(v.Milliseconds())
 }
+
+// RecorderInfo contains the configuration for recording the inbound messages received by the trigger
+type RecorderInfo struct {
+	// Enabled indicates whether inbound messages are recorded
+	Enabled bool
+	// File is the JSONL file the messages are recorded to. Defaults to "recording.jsonl"
+	File string
+	// MaxFileSize is the size in bytes at which the file is rotated. Defaults to 10MB
+	MaxFileSize int64
+	// MaxBackups is the number of rotated files to keep. Defaults to 5
+	MaxBackups int
+}
+
+// ReplayInfo contains the configuration for the replay trigger
+type ReplayInfo struct {
+	// File is the JSONL file, as written by the Recorder, containing the messages to replay
+	File string
+	// Speed is the factor applied to the original timing of the messages, i.e. 1 replays at the original speed
+	// and 10 replays ten times faster. When 0 the messages are replayed as fast as possible.
+	Speed float64
+}
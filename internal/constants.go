@@ -26,6 +26,9 @@ const (
 	DatabaseName         = "application-service"
 	CorrelationHeaderKey = "X-Correlation-ID"
 
+	SignatureHeaderKey          = "X-Signature"
+	SignatureAlgorithmHeaderKey = "X-Signature-Algorithm"
+
 	ApiTriggerRoute   = clients.ApiBase + "/trigger"
 	ApiV2TriggerRoute = v2.ApiBase + "/trigger"
 	ApiSecretsRoute   = clients.ApiBase + "/secrets"
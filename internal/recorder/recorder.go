@@ -0,0 +1,230 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+const (
+	defaultFile        = "recording.jsonl"
+	defaultMaxFileSize = 10 * 1024 * 1024
+	defaultMaxBackups  = 5
+	maxRecordSize      = 64 * 1024 * 1024
+)
+
+// Record is a single inbound message as written to the recording file, one JSON object per line.
+type Record struct {
+	Timestamp   time.Time
+	Topic       string
+	ContentType string
+	Envelope    types.MessageEnvelope
+}
+
+// Recorder writes every inbound MessageEnvelope to a JSONL file, which is rotated once it reaches
+// the configured maximum size. Rotated files are renamed <File>.1, <File>.2, etc., up to MaxBackups.
+type Recorder struct {
+	path        string
+	maxFileSize int64
+	maxBackups  int
+	file        *os.File
+	size        int64
+	lc          logger.LoggingClient
+	mutex       sync.Mutex
+}
+
+// NewRecorder creates a Recorder from the Recorder configuration, appending to the file if it already exists.
+func NewRecorder(config common.RecorderInfo, lc logger.LoggingClient) (*Recorder, error) {
+	recorder := &Recorder{
+		path:        config.File,
+		maxFileSize: config.MaxFileSize,
+		maxBackups:  config.MaxBackups,
+		lc:          lc,
+	}
+
+	if len(recorder.path) == 0 {
+		recorder.path = defaultFile
+	}
+
+	if recorder.maxFileSize <= 0 {
+		recorder.maxFileSize = defaultMaxFileSize
+	}
+
+	if recorder.maxBackups <= 0 {
+		recorder.maxBackups = defaultMaxBackups
+	}
+
+	if err := recorder.open(); err != nil {
+		return nil, err
+	}
+
+	return recorder, nil
+}
+
+// Path returns the path of the file currently being recorded to
+func (recorder *Recorder) Path() string {
+	return recorder.path
+}
+
+// Record writes the envelope received on the topic to the recording file. Failures are logged rather than
+// returned so that recording never interferes with processing the message.
+func (recorder *Recorder) Record(topic string, envelope types.MessageEnvelope) {
+	if recorder == nil {
+		return
+	}
+
+	record := Record{
+		Timestamp:   time.Now().UTC(),
+		Topic:       topic,
+		ContentType: envelope.ContentType,
+		Envelope:    envelope,
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		recorder.lc.Error(fmt.Sprintf("Unable to marshal recording of message: %s", err.Error()))
+		return
+	}
+	data = append(data, '\n')
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.file == nil {
+		return
+	}
+
+	if recorder.size > 0 && recorder.size+int64(len(data)) > recorder.maxFileSize {
+		if err := recorder.rotate(); err != nil {
+			recorder.lc.Error(fmt.Sprintf("Unable to rotate recording file '%s': %s", recorder.path, err.Error()))
+			return
+		}
+	}
+
+	written, err := recorder.file.Write(data)
+	recorder.size += int64(written)
+	if err != nil {
+		recorder.lc.Error(fmt.Sprintf("Unable to write to recording file '%s': %s", recorder.path, err.Error()))
+	}
+}
+
+// Close closes the recording file
+func (recorder *Recorder) Close() error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	if recorder.file == nil {
+		return nil
+	}
+
+	err := recorder.file.Close()
+	recorder.file = nil
+	return err
+}
+
+func (recorder *Recorder) open() error {
+	file, err := os.OpenFile(recorder.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open recording file '%s': %s", recorder.path, err.Error())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to stat recording file '%s': %s", recorder.path, err.Error())
+	}
+
+	recorder.file = file
+	recorder.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts the existing backups, dropping the oldest, and starts a new recording file.
+// Must be called with the mutex locked.
+func (recorder *Recorder) rotate() error {
+	if err := recorder.file.Close(); err != nil {
+		return err
+	}
+	recorder.file = nil
+
+	_ = os.Remove(backupPath(recorder.path, recorder.maxBackups))
+	for index := recorder.maxBackups - 1; index > 0; index-- {
+		err := os.Rename(backupPath(recorder.path, index), backupPath(recorder.path, index+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(recorder.path, backupPath(recorder.path, 1)); err != nil {
+		return err
+	}
+
+	return recorder.open()
+}
+
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
+
+// ReadRecords reads all the records from the recording file. Lines which can't be unmarshaled, such as a
+// partially written last line, are skipped.
+func ReadRecords(path string) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open recording file '%s': %s", path, err.Error())
+	}
+	defer file.Close()
+
+	return readRecords(file)
+}
+
+func readRecords(reader io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read recording: %s", err.Error())
+	}
+
+	return records, nil
+}
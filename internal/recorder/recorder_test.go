@@ -0,0 +1,112 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+var lc = logger.NewMockClient()
+
+func TestRecordAndReadRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "recording.jsonl")
+	recorder, err := NewRecorder(common.RecorderInfo{File: path}, lc)
+	require.NoError(t, err)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123",
+		ContentType:   clients.ContentTypeJSON,
+		Payload:       []byte(`{"device":"test"}`),
+	}
+
+	recorder.Record("edgex/events", envelope)
+	require.NoError(t, recorder.Close())
+
+	records, err := ReadRecords(path)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "edgex/events", records[0].Topic)
+	assert.Equal(t, clients.ContentTypeJSON, records[0].ContentType)
+	assert.Equal(t, envelope, records[0].Envelope)
+	assert.False(t, records[0].Timestamp.IsZero())
+}
+
+func TestRecordRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "recording.jsonl")
+	recorder, err := NewRecorder(common.RecorderInfo{File: path, MaxFileSize: 200, MaxBackups: 2}, lc)
+	require.NoError(t, err)
+
+	for index := 0; index < 10; index++ {
+		recorder.Record("edgex/events", types.MessageEnvelope{
+			CorrelationID: fmt.Sprintf("%d", index),
+			ContentType:   clients.ContentTypeJSON,
+			Payload:       []byte(`{"device":"test"}`),
+		})
+	}
+	require.NoError(t, recorder.Close())
+
+	// Each record exceeds the maximum size, so each file holds a single record
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		records, err := ReadRecords(name)
+		require.NoError(t, err)
+		assert.Len(t, records, 1, name)
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	records, err := ReadRecords(path)
+	require.NoError(t, err)
+	assert.Equal(t, "9", records[0].Envelope.CorrelationID)
+}
+
+func TestReadRecordsSkipsInvalidLines(t *testing.T) {
+	data := `{"Topic":"a","Envelope":{"CorrelationID":"1"}}
+
+{"Topic":"b","Env`
+
+	records, err := readRecords(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "a", records[0].Topic)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var recorder *Recorder
+	assert.NotPanics(t, func() { recorder.Record("topic", types.MessageEnvelope{}) })
+}
@@ -26,13 +26,16 @@ import (
 	"sync"
 
 	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
 	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/recorder"
 	"github.com/jcerato/app-functions-sdk-go/internal/security"
+	"github.com/jcerato/app-functions-sdk-go/internal/store"
 	"github.com/jcerato/app-functions-sdk-go/internal/store/db/interfaces"
 )
 
@@ -46,11 +49,24 @@ type GolangRuntime struct {
 	isBusyCopying  sync.Mutex
 	storeForward   storeForwardInfo
 	secretProvider security.SecretProvider
+	recorder       *recorder.Recorder
+	// shutdownHandlers are registered by pipeline functions through the Context to be called on Shutdown
+	shutdownHandlers []func()
+	shutdownLock     sync.Mutex
+	outputPublisher  OutputPublisher
+	publisherLock    sync.Mutex
 }
 
+// OutputPublisher publishes the OutputData of the Context to the trigger's publish topic. It is set by the triggers
+// able to publish, so that output which isn't the response to a received message, i.e. the output of a pipeline
+// resumed by a batch, can still be sent.
+type OutputPublisher func(edgexcontext *appcontext.Context) error
+
 type MessageError struct {
 	Err       error
 	ErrorCode int
+	// StoredForRetry indicates the data has been persisted by Store and Forward for later retry
+	StoredForRetry bool
 }
 
 // ProcessMessage sends the contents of the message thru the functions pipeline
@@ -141,10 +157,48 @@ func (gr *GolangRuntime) ProcessMessage(edgexcontext *appcontext.Context, envelo
 // Initialize sets the internal reference to the StoreClient for use when Store and Forward is enabled
 func (gr *GolangRuntime) Initialize(storeClient interfaces.StoreClient, secretProvider security.SecretProvider) {
 	gr.storeForward.storeClient = storeClient
+	gr.storeForward.persistentStore = nil
+	if storeClient != nil {
+		gr.storeForward.persistentStore = store.NewPersistentStore(storeClient)
+	}
 	gr.storeForward.runtime = gr
 	gr.secretProvider = secretProvider
 }
 
+// SetRecorder sets the Recorder used to record the inbound messages
+func (gr *GolangRuntime) SetRecorder(recorder *recorder.Recorder) {
+	gr.recorder = recorder
+}
+
+// RecordMessage records the inbound message received on the topic when recording is enabled
+func (gr *GolangRuntime) RecordMessage(topic string, envelope types.MessageEnvelope) {
+	gr.recorder.Record(topic, envelope)
+}
+
+// SetOutputPublisher sets the OutputPublisher used to publish the output of resumed pipelines and split items
+func (gr *GolangRuntime) SetOutputPublisher(publisher OutputPublisher) {
+	gr.publisherLock.Lock()
+	defer gr.publisherLock.Unlock()
+	gr.outputPublisher = publisher
+}
+
+// publishOutput publishes the OutputData of the Context using the OutputPublisher. It returns false when the
+// trigger has no OutputPublisher, i.e. the HTTP trigger which can only return output as the response to a request.
+func (gr *GolangRuntime) publishOutput(edgexcontext *appcontext.Context) (bool, error) {
+	publisher := gr.getOutputPublisher()
+	if publisher == nil {
+		return false, nil
+	}
+
+	return true, publisher(edgexcontext)
+}
+
+func (gr *GolangRuntime) getOutputPublisher() OutputPublisher {
+	gr.publisherLock.Lock()
+	defer gr.publisherLock.Unlock()
+	return gr.outputPublisher
+}
+
 // SetTransforms is thread safe to set transforms
 func (gr *GolangRuntime) SetTransforms(transforms []appcontext.AppFunction) {
 	gr.isBusyCopying.Lock()
@@ -156,10 +210,18 @@ func (gr *GolangRuntime) SetTransforms(transforms []appcontext.AppFunction) {
 func (gr *GolangRuntime) ExecutePipeline(target interface{}, contentType string, edgexcontext *appcontext.Context,
 	transforms []appcontext.AppFunction, startPosition int, isRetry bool) *MessageError {
 
-	var result interface{}
-	var continuePipeline = true
-
 	edgexcontext.SecretProvider = gr.secretProvider
+	edgexcontext.PersistentStore = gr.storeForward.persistentStore
+
+	return gr.executeFunctions(nil, target, contentType, edgexcontext, transforms, startPosition, isRetry)
+}
+
+// executeFunctions executes the pipeline functions from the startPosition. When result is nil the first function is
+// passed the target and contentType, otherwise the result of the previous function.
+func (gr *GolangRuntime) executeFunctions(result interface{}, target interface{}, contentType string,
+	edgexcontext *appcontext.Context, transforms []appcontext.AppFunction, startPosition int, isRetry bool) *MessageError {
+
+	var continuePipeline = true
 
 	for functionIndex, trxFunc := range transforms {
 		if functionIndex < startPosition {
@@ -167,6 +229,9 @@ func (gr *GolangRuntime) ExecutePipeline(target interface{}, contentType string,
 		}
 
 		edgexcontext.RetryData = nil
+		edgexcontext.ResumePipeline = gr.resumePipelineFunc(contentType, edgexcontext, transforms, functionIndex+1)
+		edgexcontext.CanPublishOutput = gr.getOutputPublisher() != nil
+		edgexcontext.OnShutdown = gr.registerShutdownHandler
 
 		if result == nil {
 			continuePipeline, result = trxFunc(edgexcontext, target, contentType)
@@ -180,20 +245,174 @@ func (gr *GolangRuntime) ExecutePipeline(target interface{}, contentType string,
 					edgexcontext.LoggingClient.Error(
 						fmt.Sprintf("Pipeline function #%d resulted in error", functionIndex),
 						"error", err.Error(), clients.CorrelationHeader, edgexcontext.CorrelationID)
+					storedForRetry := false
 					if edgexcontext.RetryData != nil && !isRetry {
-						gr.storeForward.storeForLaterRetry(edgexcontext.RetryData, edgexcontext, functionIndex)
+						storedForRetry = gr.storeForward.storeForLaterRetry(edgexcontext.RetryData, edgexcontext, functionIndex)
 					}
 
-					return &MessageError{Err: err, ErrorCode: http.StatusUnprocessableEntity}
+					return &MessageError{Err: err, ErrorCode: http.StatusUnprocessableEntity, StoredForRetry: storedForRetry}
 				}
 			}
 			break
 		}
+
+		if items, ok := result.(appcontext.SplitResult); ok {
+			return gr.executeSplit(items, contentType, edgexcontext, transforms, functionIndex+1, isRetry)
+		}
 	}
 
 	return nil
 }
 
+// executeSplit executes the rest of the pipeline once for each of the items, each with its own copy of the Context
+// so the items' output, retry data and values don't overwrite each other. The output of each item is published
+// using the OutputPublisher. When the trigger has none, i.e. the HTTP trigger, the output of the last item with
+// output is returned as the response. All the items are processed even when processing one of them fails, in
+// which case the first error is returned.
+func (gr *GolangRuntime) executeSplit(items appcontext.SplitResult, contentType string, edgexcontext *appcontext.Context,
+	transforms []appcontext.AppFunction, startPosition int, isRetry bool) *MessageError {
+
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Executing remainder of pipeline for %d split items", len(items)),
+		clients.CorrelationHeader, edgexcontext.CorrelationID)
+
+	var firstError *MessageError
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+
+		itemContext := newSplitItemContext(edgexcontext)
+		err := gr.executeFunctions(item, nil, contentType, itemContext, transforms, startPosition, isRetry)
+		if err == nil && itemContext.OutputData != nil {
+			err = gr.splitItemOutput(edgexcontext, itemContext)
+		}
+
+		if err != nil && firstError == nil {
+			firstError = err
+		}
+	}
+
+	return firstError
+}
+
+// splitItemOutput publishes the output of the split item or, when the trigger has no OutputPublisher, sets it as
+// the output returned by the trigger
+func (gr *GolangRuntime) splitItemOutput(edgexcontext *appcontext.Context, itemContext *appcontext.Context) *MessageError {
+	published, err := gr.publishOutput(itemContext)
+	if err != nil {
+		edgexcontext.LoggingClient.Error("Failed to publish output data of split item", "error", err.Error(),
+			clients.CorrelationHeader, edgexcontext.CorrelationID)
+		return &MessageError{Err: err, ErrorCode: http.StatusInternalServerError}
+	}
+
+	if !published {
+		edgexcontext.OutputData = itemContext.OutputData
+		edgexcontext.ResponseContentType = itemContext.ResponseContentType
+	}
+
+	return nil
+}
+
+// newSplitItemContext returns a copy of the Context, without its output and retry data, for processing a split item
+func newSplitItemContext(edgexcontext *appcontext.Context) *appcontext.Context {
+	itemContext := &appcontext.Context{
+		EventID:               edgexcontext.EventID,
+		EventChecksum:         edgexcontext.EventChecksum,
+		CorrelationID:         edgexcontext.CorrelationID,
+		Configuration:         edgexcontext.Configuration,
+		LoggingClient:         edgexcontext.LoggingClient,
+		EventClient:           edgexcontext.EventClient,
+		ValueDescriptorClient: edgexcontext.ValueDescriptorClient,
+		CommandClient:         edgexcontext.CommandClient,
+		NotificationsClient:   edgexcontext.NotificationsClient,
+		DeviceClient:          edgexcontext.DeviceClient,
+		SecretProvider:        edgexcontext.SecretProvider,
+		PersistentStore:       edgexcontext.PersistentStore,
+		ReceivedTopic:         edgexcontext.ReceivedTopic,
+		ResponseContentType:   edgexcontext.ResponseContentType,
+	}
+
+	for key, value := range edgexcontext.GetAllValues() {
+		itemContext.AddValue(key, value)
+	}
+
+	return itemContext
+}
+
+// resumePipelineFunc returns the function which executes the pipeline functions from the startPosition with a
+// result produced asynchronously by the function before them, i.e. a batch sent when the batch interval expires.
+func (gr *GolangRuntime) resumePipelineFunc(contentType string, edgexcontext *appcontext.Context,
+	transforms []appcontext.AppFunction, startPosition int) func(result interface{}) error {
+
+	return func(result interface{}) error {
+		if result == nil {
+			return nil
+		}
+
+		resumedContext := &appcontext.Context{
+			CorrelationID:         uuid.New().String(),
+			Configuration:         edgexcontext.Configuration,
+			LoggingClient:         edgexcontext.LoggingClient,
+			EventClient:           edgexcontext.EventClient,
+			ValueDescriptorClient: edgexcontext.ValueDescriptorClient,
+			CommandClient:         edgexcontext.CommandClient,
+			NotificationsClient:   edgexcontext.NotificationsClient,
+			DeviceClient:          edgexcontext.DeviceClient,
+			SecretProvider:        gr.secretProvider,
+			PersistentStore:       gr.storeForward.persistentStore,
+		}
+
+		resumedContext.LoggingClient.Debug(fmt.Sprintf("Resuming pipeline at function #%d", startPosition),
+			clients.CorrelationHeader, resumedContext.CorrelationID)
+
+		// Errors are logged, and stored for retry when enabled, by executeFunctions
+		if err := gr.executeFunctions(result, nil, contentType, resumedContext, transforms, startPosition, false); err != nil {
+			if err.StoredForRetry {
+				return nil
+			}
+			return err.Err
+		}
+
+		if resumedContext.OutputData == nil {
+			return nil
+		}
+
+		published, err := gr.publishOutput(resumedContext)
+		switch {
+		case err != nil:
+			resumedContext.LoggingClient.Error(
+				fmt.Sprintf("Failed to publish output data of resumed pipeline: %s", err.Error()),
+				clients.CorrelationHeader, resumedContext.CorrelationID)
+			return err
+		case !published:
+			resumedContext.LoggingClient.Warn(
+				fmt.Sprintf("Resumed pipeline resulted in %d bytes of output data which the trigger can't publish", len(resumedContext.OutputData)),
+				clients.CorrelationHeader, resumedContext.CorrelationID)
+		}
+
+		return nil
+	}
+}
+
+func (gr *GolangRuntime) registerShutdownHandler(handler func()) {
+	gr.shutdownLock.Lock()
+	defer gr.shutdownLock.Unlock()
+	gr.shutdownHandlers = append(gr.shutdownHandlers, handler)
+}
+
+// Shutdown calls the shutdown handlers registered by the pipeline functions, i.e. to send data which is still
+// batched. Each handler is only called once.
+func (gr *GolangRuntime) Shutdown() {
+	gr.shutdownLock.Lock()
+	handlers := gr.shutdownHandlers
+	gr.shutdownHandlers = nil
+	gr.shutdownLock.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
 func (gr *GolangRuntime) StartStoreAndForward(
 	appWg *sync.WaitGroup,
 	appCtx context.Context,
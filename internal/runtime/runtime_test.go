@@ -17,6 +17,7 @@ package runtime
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -197,6 +198,209 @@ func TestProcessMessageThreeCustomTransformsOneFail(t *testing.T) {
 	assert.False(t, transform3WasCalled, "transform3 should NOT have been called")
 }
 
+func TestProcessMessageSplitResult(t *testing.T) {
+	eventIn := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "20"},
+			{Name: "humidity", Value: "40"},
+			{Name: "pressure", Value: "1000"},
+		},
+	}
+	eventInBytes, _ := json.Marshal(eventIn)
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123-234-345-456",
+		Payload:       eventInBytes,
+		ContentType:   clients.ContentTypeJSON,
+	}
+	context := &appcontext.Context{
+		LoggingClient: lc,
+	}
+
+	var received []string
+	collect := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		event := params[0].(models.Event)
+		require.Len(t, event.Readings, 1)
+		if event.Readings[0].Name == "humidity" {
+			return false, fmt.Errorf("failed %s", event.Readings[0].Name)
+		}
+		return true, event.Readings[0].Name
+	}
+	record := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		received = append(received, params[0].(string))
+		return true, params[0]
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transforms.NewEventSplitter().SplitByReading, collect, record})
+
+	result := runtime.ProcessMessage(context, envelope)
+	require.NotNil(t, result, "error from one of the split items should be returned")
+	assert.EqualError(t, result.Err, "failed humidity")
+	assert.Equal(t, []string{"temperature", "pressure"}, received, "remaining items should still be processed")
+}
+
+func TestProcessMessageSplitResultOutput(t *testing.T) {
+	eventIn := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "20"},
+			{Name: "humidity", Value: "40"},
+		},
+	}
+	eventInBytes, _ := json.Marshal(eventIn)
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123-234-345-456",
+		Payload:       eventInBytes,
+		ContentType:   clients.ContentTypeJSON,
+	}
+
+	output := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		event := params[0].(models.Event)
+		name := event.Readings[0].Name
+		_, found := edgexcontext.GetValue("previous")
+		assert.False(t, found, "values of other items should not be seen")
+		edgexcontext.AddValue("previous", name)
+		assert.Nil(t, edgexcontext.RetryData)
+		edgexcontext.SetRetryData([]byte(name))
+		edgexcontext.Complete([]byte(name))
+		return true, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transforms.NewEventSplitter().SplitByReading, output})
+
+	// Without an OutputPublisher the output of the last item is returned
+	context := &appcontext.Context{LoggingClient: lc}
+	require.Nil(t, runtime.ProcessMessage(context, envelope))
+	assert.Equal(t, []byte("humidity"), context.OutputData)
+	_, found := context.GetValue("previous")
+	assert.False(t, found)
+
+	var published []string
+	runtime.SetOutputPublisher(func(edgexcontext *appcontext.Context) error {
+		assert.Equal(t, envelope.CorrelationID, edgexcontext.CorrelationID)
+		published = append(published, string(edgexcontext.OutputData))
+		return nil
+	})
+
+	context = &appcontext.Context{LoggingClient: lc}
+	require.Nil(t, runtime.ProcessMessage(context, envelope))
+	assert.Nil(t, context.OutputData, "output should be published rather than returned")
+	assert.Equal(t, []string{"temperature", "humidity"}, published)
+
+	runtime.SetOutputPublisher(func(edgexcontext *appcontext.Context) error {
+		return fmt.Errorf("failed %s", edgexcontext.OutputData)
+	})
+
+	result := runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc}, envelope)
+	require.NotNil(t, result)
+	assert.EqualError(t, result.Err, "failed temperature")
+	assert.Equal(t, http.StatusInternalServerError, result.ErrorCode)
+}
+
+func TestProcessMessageBatchResumedOnShutdown(t *testing.T) {
+	eventIn := models.Event{Device: devID1}
+	eventInBytes, _ := json.Marshal(eventIn)
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123-234-345-456",
+		Payload:       eventInBytes,
+		ContentType:   clients.ContentTypeJSON,
+	}
+
+	var resumedContext *appcontext.Context
+	var received []models.Event
+	record := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		resumedContext = edgexcontext
+		require.NoError(t, json.Unmarshal(params[0].([]byte), &received))
+		return false, nil
+	}
+
+	batch, err := transforms.NewBatchByTime("1h")
+	require.NoError(t, err)
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{batch.Batch, record})
+
+	for i := 0; i < 2; i++ {
+		result := runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc}, envelope)
+		require.Nil(t, result)
+	}
+	assert.Nil(t, received, "batch should not be sent before the time interval")
+
+	runtime.Shutdown()
+	require.Len(t, received, 2, "batch should be sent to the remaining functions on shutdown")
+	assert.Equal(t, devID1, received[0].Device)
+	require.NotNil(t, resumedContext)
+	assert.NotEmpty(t, resumedContext.CorrelationID)
+	assert.Equal(t, lc, resumedContext.LoggingClient)
+
+	// Handlers are only called once
+	received = nil
+	runtime.Shutdown()
+	assert.Nil(t, received)
+}
+
+func TestResumedPipelineOutputPublished(t *testing.T) {
+	eventIn := models.Event{Device: devID1}
+	eventInBytes, _ := json.Marshal(eventIn)
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123-234-345-456",
+		Payload:       eventInBytes,
+		ContentType:   clients.ContentTypeJSON,
+	}
+
+	output := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		edgexcontext.Complete(params[0].([]byte))
+		return true, nil
+	}
+
+	batch, err := transforms.NewBatchByTime("1h")
+	require.NoError(t, err)
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{batch.Batch, output})
+
+	var published []byte
+	runtime.SetOutputPublisher(func(edgexcontext *appcontext.Context) error {
+		published = edgexcontext.OutputData
+		return nil
+	})
+
+	context := &appcontext.Context{LoggingClient: lc}
+	require.Nil(t, runtime.ProcessMessage(context, envelope))
+	assert.Nil(t, context.OutputData)
+
+	runtime.Shutdown()
+	var received []models.Event
+	require.NoError(t, json.Unmarshal(published, &received), "resumed output should be published")
+	require.Len(t, received, 1)
+	assert.Equal(t, devID1, received[0].Device)
+}
+
+func TestResumePipelineError(t *testing.T) {
+	fail := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, errors.New("export failed")
+	}
+	succeed := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	context := &appcontext.Context{LoggingClient: lc}
+
+	resume := runtime.resumePipelineFunc(clients.ContentTypeJSON, context, []appcontext.AppFunction{succeed, fail}, 1)
+	assert.EqualError(t, resume([]byte("data")), "export failed")
+
+	resume = runtime.resumePipelineFunc(clients.ContentTypeJSON, context, []appcontext.AppFunction{fail, succeed}, 1)
+	assert.NoError(t, resume([]byte("data")))
+}
+
 func TestProcessMessageTransformError(t *testing.T) {
 	// Error expected from FilterByDeviceName
 	expectedError := "type received is not an Event"
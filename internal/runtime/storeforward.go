@@ -36,9 +36,11 @@ const (
 )
 
 type storeForwardInfo struct {
-	runtime      *GolangRuntime
-	storeClient  interfaces.StoreClient
-	pipelineHash string
+	runtime     *GolangRuntime
+	storeClient interfaces.StoreClient
+	// persistentStore is the PersistentStore set on the Context, nil when Store and Forward isn't enabled
+	persistentStore appcontext.PersistentStore
+	pipelineHash    string
 }
 
 func (sf *storeForwardInfo) startStoreAndForwardRetryLoop(
@@ -103,7 +105,7 @@ func (sf *storeForwardInfo) startStoreAndForwardRetryLoop(
 
 func (sf *storeForwardInfo) storeForLaterRetry(payload []byte,
 	edgexcontext *appcontext.Context,
-	pipelinePosition int) {
+	pipelinePosition int) bool {
 
 	item := contracts.NewStoredObject(sf.runtime.ServiceKey, payload, pipelinePosition, sf.pipelineHash)
 	item.CorrelationID = edgexcontext.CorrelationID
@@ -117,14 +119,17 @@ func (sf *storeForwardInfo) storeForLaterRetry(payload []byte,
 		edgexcontext.LoggingClient.Error(
 			"Failed to store item for later retry", "error", "StoreAndForward not enabled",
 			clients.CorrelationHeader, item.CorrelationID)
-		return
+		return false
 	}
 
 	if _, err := sf.storeClient.Store(item); err != nil {
 		edgexcontext.LoggingClient.Error("Failed to store item for later retry",
 			"error", err,
 			clients.CorrelationHeader, item.CorrelationID)
+		return false
 	}
+
+	return true
 }
 
 func (sf *storeForwardInfo) retryStoredData(serviceKey string,
@@ -232,6 +237,7 @@ func (sf *storeForwardInfo) retryExportFunction(item contracts.StoredObject, con
 		ValueDescriptorClient: edgeXClients.ValueDescriptorClient,
 		CommandClient:         edgeXClients.CommandClient,
 		NotificationsClient:   edgeXClients.NotificationsClient,
+		DeviceClient:          edgeXClients.DeviceClient,
 	}
 
 	edgexContext.LoggingClient.Trace("Retrying stored data", clients.CorrelationHeader, edgexContext.CorrelationID)
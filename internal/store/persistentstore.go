@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package store
+
+import (
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/store/contracts"
+	"github.com/jcerato/app-functions-sdk-go/internal/store/db/interfaces"
+)
+
+// persistentStoreVersion is the Version of the StoredObjects persisted for the pipeline functions, which tells them
+// apart from the data stored for retry
+const persistentStoreVersion = "persistent"
+
+// persistentStore implements appcontext.PersistentStore using the Store and Forward StoreClient, the store key
+// being the StoredObject's AppServiceKey
+type persistentStore struct {
+	client interfaces.StoreClient
+}
+
+// NewPersistentStore returns the PersistentStore which persists data using the Store and Forward StoreClient
+func NewPersistentStore(client interfaces.StoreClient) appcontext.PersistentStore {
+	return &persistentStore{client: client}
+}
+
+func (store *persistentStore) Store(storeKey string, data []byte) (string, error) {
+	return store.client.Store(contracts.NewStoredObject(storeKey, data, 0, persistentStoreVersion))
+}
+
+func (store *persistentStore) Retrieve(storeKey string) (map[string][]byte, error) {
+	objects, err := store.client.RetrieveFromStore(storeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(objects))
+	for _, object := range objects {
+		if object.Version == persistentStoreVersion {
+			data[object.ID] = object.Payload
+		}
+	}
+
+	return data, nil
+}
+
+func (store *persistentStore) Remove(storeKey string, id string) error {
+	return store.client.RemoveFromStore(contracts.StoredObject{ID: id, AppServiceKey: storeKey})
+}
@@ -0,0 +1,53 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/store/contracts"
+	"github.com/jcerato/app-functions-sdk-go/internal/store/db/interfaces/mocks"
+)
+
+func TestPersistentStore(t *testing.T) {
+	client := &mocks.StoreClient{}
+	client.On("Store", mock.MatchedBy(func(object contracts.StoredObject) bool {
+		return object.AppServiceKey == "key" && string(object.Payload) == "data" && object.Version == persistentStoreVersion
+	})).Return(func(contracts.StoredObject) (string, error) { return "id1", nil })
+	client.On("RetrieveFromStore", "key").Return([]contracts.StoredObject{
+		{ID: "id1", AppServiceKey: "key", Payload: []byte("data"), Version: persistentStoreVersion},
+		{ID: "id2", AppServiceKey: "key", Payload: []byte("retry"), Version: "pipeline hash"},
+	}, nil)
+	client.On("RemoveFromStore", contracts.StoredObject{ID: "id1", AppServiceKey: "key"}).Return(nil)
+
+	store := NewPersistentStore(client)
+
+	id, err := store.Store("key", []byte("data"))
+	require.NoError(t, err)
+	assert.Equal(t, "id1", id)
+
+	data, err := store.Retrieve("key")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"id1": []byte("data")}, data, "only the persisted data should be retrieved")
+
+	require.NoError(t, store.Remove("key", "id1"))
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,67 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package telemetry
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a named count, i.e. of the items dropped by a pipeline function, reported by the metrics endpoints
+type Counter struct {
+	count uint64
+}
+
+// Add increments the counter by the delta
+func (counter *Counter) Add(delta uint64) {
+	atomic.AddUint64(&counter.count, delta)
+}
+
+// Count returns the current value of the counter
+func (counter *Counter) Count() uint64 {
+	return atomic.LoadUint64(&counter.count)
+}
+
+var countersLock sync.Mutex
+var counters = make(map[string]*Counter)
+
+// GetCounter returns the counter with the name, registering a new counter the first time the name is used
+func GetCounter(name string) *Counter {
+	countersLock.Lock()
+	defer countersLock.Unlock()
+
+	counter, ok := counters[name]
+	if !ok {
+		counter = &Counter{}
+		counters[name] = counter
+	}
+
+	return counter
+}
+
+// Counters returns the current value of all the registered counters, keyed by name
+func Counters() map[string]uint64 {
+	countersLock.Lock()
+	defer countersLock.Unlock()
+
+	values := make(map[string]uint64, len(counters))
+	for name, counter := range counters {
+		values[name] = counter.Count()
+	}
+
+	return values
+}
@@ -28,6 +28,8 @@ import (
 type SystemUsage struct {
 	Memory     memoryUsage
 	CpuBusyAvg float64
+	// Counters holds the registered counters, i.e. the items dropped by pipeline functions
+	Counters map[string]uint64 `json:",omitempty"`
 }
 
 // swagger:model
@@ -69,6 +71,7 @@ func NewSystemUsage() (s SystemUsage) {
 	s.Memory.LiveObjects = s.Memory.Mallocs - s.Memory.Frees
 
 	s.CpuBusyAvg = usageAvg
+	s.Counters = Counters()
 
 	return s
 }
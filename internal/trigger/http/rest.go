@@ -85,6 +85,14 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
 		CommandClient:         trigger.EdgeXClients.CommandClient,
 		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+		DeviceClient:          trigger.EdgeXClients.DeviceClient,
+	}
+
+	// Signature headers are made available to the pipeline so the signature can be verified
+	for _, key := range []string{internal.SignatureHeaderKey, internal.SignatureAlgorithmHeaderKey} {
+		if value := r.Header.Get(key); len(value) > 0 {
+			edgexContext.AddValue(key, value)
+		}
 	}
 
 	logger.Trace("Received message from http", clients.CorrelationHeader, correlationID)
@@ -96,6 +104,8 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 		Payload:       data,
 	}
 
+	trigger.Runtime.RecordMessage(r.URL.Path, envelope)
+
 	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
 	if messageError != nil {
 		// ProcessMessage logs the error, so no need to log it here.
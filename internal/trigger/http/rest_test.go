@@ -17,9 +17,23 @@
 package http
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/stretchr/testify/assert"
-	"testing"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+	"github.com/jcerato/app-functions-sdk-go/pkg/transforms"
 )
 
 func TestTriggerInitializeWitBackgroundChannel(t *testing.T) {
@@ -32,3 +46,48 @@ func TestTriggerInitializeWitBackgroundChannel(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, "background publishing not supported for services using HTTP trigger", err.Error())
 }
+
+func TestRequestHandlerPersistedBatchResponse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.json")
+	batch, err := transforms.NewBatchByCount(2)
+	require.NoError(t, err)
+	require.NoError(t, batch.SetPersistenceFile(path))
+
+	output := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		edgexcontext.Complete(params[0].([]byte))
+		return false, nil
+	}
+
+	pipelineRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	pipelineRuntime.Initialize(nil, nil)
+	pipelineRuntime.SetTransforms([]appcontext.AppFunction{batch.Batch, output})
+
+	trigger := Trigger{
+		Configuration: &common.ConfigurationStruct{},
+		Runtime:       pipelineRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+	}
+
+	var response *httptest.ResponseRecorder
+	for _, item := range []string{"first", "second"} {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/trigger", strings.NewReader(item))
+		request.Header.Set(clients.ContentType, clients.ContentTypeText)
+		response = httptest.NewRecorder()
+		trigger.requestHandler(response, request)
+		require.Equal(t, http.StatusOK, response.Code)
+	}
+
+	// The HTTP trigger can't publish the output of a resumed pipeline, so the batch is the response to the request
+	var items []string
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &items))
+	assert.Equal(t, []string{"first", "second"}, items)
+
+	// and its persisted items are removed, so a restarted batch doesn't send them again
+	restored, err := transforms.NewBatchByCount(1)
+	require.NoError(t, err)
+	require.NoError(t, restored.SetPersistenceFile(path))
+	continuePipeline, result := restored.Batch(&appcontext.Context{LoggingClient: logger.NewMockClient()}, []byte("third"))
+	require.True(t, continuePipeline)
+	require.NoError(t, json.Unmarshal(result.([]byte), &items))
+	assert.Equal(t, []string{"third"}, items)
+}
@@ -0,0 +1,375 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+const (
+	// AckModeAuto treats messages as consumed as soon as they are received
+	AckModeAuto = "auto"
+	// AckModeAtLeastOnce acknowledges messages only once they have been processed or stored for retry
+	AckModeAtLeastOnce = "atleastonce"
+
+	defaultAckLogFile       = "messagebus-ack.log"
+	defaultDeadLetterFile   = "messagebus-deadletter.log"
+	defaultAckRetryInterval = 30 * time.Second
+	defaultAckMaxAttempts   = 5
+
+	walOperationAdd = "add"
+	walOperationAck = "ack"
+
+	// walCompactThreshold is the number of acknowledged records after which the log file is compacted
+	walCompactThreshold = 1000
+)
+
+// isAtLeastOnce returns true when the AckMode is "atleastonce" or an error if the AckMode is unknown
+func isAtLeastOnce(ackMode string) (bool, error) {
+	switch strings.ToLower(ackMode) {
+	case "", AckModeAuto:
+		return false, nil
+	case AckModeAtLeastOnce:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid AckMode '%s', must be '%s' or '%s'", ackMode, AckModeAuto, AckModeAtLeastOnce)
+	}
+}
+
+// ackRetrySettings returns the interval at which failed messages are replayed and the number of attempts after
+// which they are dead-lettered, using the defaults for those not configured
+func ackRetrySettings(retryInterval string, maxAttempts int) (time.Duration, int, error) {
+	interval := defaultAckRetryInterval
+	if len(retryInterval) > 0 {
+		var err error
+		interval, err = time.ParseDuration(retryInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid AckRetryInterval '%s': %s", retryInterval, err.Error())
+		}
+
+		if interval <= 0 {
+			return 0, 0, fmt.Errorf("invalid AckRetryInterval '%s', must be greater than zero", retryInterval)
+		}
+	}
+
+	if maxAttempts < 0 {
+		return 0, 0, fmt.Errorf("invalid AckMaxAttempts %d, must not be negative", maxAttempts)
+	}
+
+	if maxAttempts == 0 {
+		maxAttempts = defaultAckMaxAttempts
+	}
+
+	return interval, maxAttempts, nil
+}
+
+// shouldAcknowledge returns true when the message has been fully handled. That is the pipeline succeeded, the data
+// was stored for retry by Store and Forward or the message could never be processed, i.e. it can't be unmarshaled.
+func shouldAcknowledge(messageError *runtime.MessageError) bool {
+	return messageError == nil || messageError.StoredForRetry || messageError.ErrorCode == http.StatusBadRequest
+}
+
+type walRecord struct {
+	Operation string
+	ID        string
+	Message   *types.MessageEnvelope `json:",omitempty"`
+}
+
+// writeAheadLog records received messages to a local file before they are processed and records their
+// acknowledgement once processed, so that messages not acknowledged due to a crash can be replayed on startup.
+// Messages which fail while running are replayed by the trigger until they succeed or are dead-lettered.
+type writeAheadLog struct {
+	path     string
+	file     *os.File
+	pending  map[string]types.MessageEnvelope
+	ackCount int
+	// attempts is the number of failed attempts of the pending messages, keyed by id
+	attempts map[string]int
+	// failed are the ids of the pending messages waiting to be replayed
+	failed map[string]bool
+	mutex  sync.Mutex
+}
+
+// openWriteAheadLog opens the write-ahead log file, creating it if needed, and loads the messages which
+// haven't been acknowledged. The file is compacted so that it only contains those messages.
+func openWriteAheadLog(path string) (*writeAheadLog, error) {
+	if len(path) == 0 {
+		path = defaultAckLogFile
+	}
+
+	wal := &writeAheadLog{
+		path:     path,
+		pending:  make(map[string]types.MessageEnvelope),
+		attempts: make(map[string]int),
+		failed:   make(map[string]bool),
+	}
+
+	if err := wal.load(); err != nil {
+		return nil, err
+	}
+
+	if err := wal.compact(); err != nil {
+		return nil, err
+	}
+
+	return wal, nil
+}
+
+func (wal *writeAheadLog) load() error {
+	file, err := os.Open(wal.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to open write-ahead log '%s': %s", wal.path, err.Error())
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		record := walRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// The last record may be partially written if the service crashed while writing it.
+			continue
+		}
+
+		switch record.Operation {
+		case walOperationAdd:
+			if record.Message != nil {
+				wal.pending[record.ID] = *record.Message
+			}
+		case walOperationAck:
+			delete(wal.pending, record.ID)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read write-ahead log '%s': %s", wal.path, err.Error())
+	}
+
+	return nil
+}
+
+// compact rewrites the log file so it only contains the pending messages. Must be called with the mutex locked
+// or before the log is in use.
+func (wal *writeAheadLog) compact() error {
+	tempPath := wal.path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to create write-ahead log '%s': %s", tempPath, err.Error())
+	}
+
+	encoder := json.NewEncoder(file)
+	for id, message := range wal.pending {
+		message := message
+		if err := encoder.Encode(walRecord{Operation: walOperationAdd, ID: id, Message: &message}); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("unable to write write-ahead log '%s': %s", tempPath, err.Error())
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to write write-ahead log '%s': %s", tempPath, err.Error())
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("unable to write write-ahead log '%s': %s", tempPath, err.Error())
+	}
+
+	if wal.file != nil {
+		_ = wal.file.Close()
+		wal.file = nil
+	}
+
+	if err := os.Rename(tempPath, wal.path); err != nil {
+		return fmt.Errorf("unable to replace write-ahead log '%s': %s", wal.path, err.Error())
+	}
+
+	wal.file, err = os.OpenFile(wal.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open write-ahead log '%s': %s", wal.path, err.Error())
+	}
+
+	wal.ackCount = 0
+
+	return nil
+}
+
+// add records the received message and returns the id used to acknowledge it
+func (wal *writeAheadLog) add(message types.MessageEnvelope) (string, error) {
+	id := uuid.New().String()
+
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if err := wal.write(walRecord{Operation: walOperationAdd, ID: id, Message: &message}); err != nil {
+		return "", err
+	}
+
+	// The message must be on disk before it is processed
+	if err := wal.file.Sync(); err != nil {
+		return "", fmt.Errorf("unable to sync write-ahead log '%s': %s", wal.path, err.Error())
+	}
+
+	wal.pending[id] = message
+
+	return id, nil
+}
+
+// ack records the acknowledgement of the message with the specified id
+func (wal *writeAheadLog) ack(id string) error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if _, ok := wal.pending[id]; !ok {
+		return nil
+	}
+
+	if err := wal.write(walRecord{Operation: walOperationAck, ID: id}); err != nil {
+		return err
+	}
+
+	delete(wal.pending, id)
+	delete(wal.attempts, id)
+	delete(wal.failed, id)
+	wal.ackCount++
+
+	if wal.ackCount >= walCompactThreshold {
+		return wal.compact()
+	}
+
+	return nil
+}
+
+// fail records a failed attempt to process the message with the specified id, so it is returned by retries, and
+// returns the number of failed attempts
+func (wal *writeAheadLog) fail(id string) int {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if _, ok := wal.pending[id]; !ok {
+		return 0
+	}
+
+	wal.attempts[id]++
+	wal.failed[id] = true
+
+	return wal.attempts[id]
+}
+
+// retries returns the messages which have failed and are waiting to be replayed, keyed by id. They aren't returned
+// again until they fail again.
+func (wal *writeAheadLog) retries() map[string]types.MessageEnvelope {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	messages := make(map[string]types.MessageEnvelope, len(wal.failed))
+	for id := range wal.failed {
+		messages[id] = wal.pending[id]
+	}
+	wal.failed = make(map[string]bool)
+
+	return messages
+}
+
+// unacknowledged returns the messages which have been received but not acknowledged, keyed by id
+func (wal *writeAheadLog) unacknowledged() map[string]types.MessageEnvelope {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	messages := make(map[string]types.MessageEnvelope, len(wal.pending))
+	for id, message := range wal.pending {
+		messages[id] = message
+	}
+
+	return messages
+}
+
+func (wal *writeAheadLog) close() error {
+	wal.mutex.Lock()
+	defer wal.mutex.Unlock()
+
+	if wal.file == nil {
+		return nil
+	}
+
+	err := wal.file.Close()
+	wal.file = nil
+	return err
+}
+
+func (wal *writeAheadLog) write(record walRecord) error {
+	if wal.file == nil {
+		return fmt.Errorf("write-ahead log '%s' is closed", wal.path)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("unable to marshal write-ahead log record: %s", err.Error())
+	}
+
+	if _, err := wal.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("unable to write to write-ahead log '%s': %s", wal.path, err.Error())
+	}
+
+	return nil
+}
+
+// appendDeadLetter appends the message to the dead-letter file as a line of JSON
+func appendDeadLetter(path string, message types.MessageEnvelope) error {
+	if len(path) == 0 {
+		path = defaultDeadLetterFile
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("unable to marshal dead-letter message: %s", err.Error())
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open dead-letter file '%s': %s", path, err.Error())
+	}
+
+	if _, err = file.Write(append(data, '\n')); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to write dead-letter file '%s': %s", path, err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,216 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+func TestIsAtLeastOnce(t *testing.T) {
+	tests := []struct {
+		Name          string
+		AckMode       string
+		Expected      bool
+		ErrorExpected bool
+	}{
+		{"Default", "", false, false},
+		{"Auto", "auto", false, false},
+		{"At least once", "AtLeastOnce", true, false},
+		{"Invalid", "exactlyonce", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := isAtLeastOnce(test.AckMode)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestAckRetrySettings(t *testing.T) {
+	tests := []struct {
+		Name             string
+		RetryInterval    string
+		MaxAttempts      int
+		ExpectedInterval time.Duration
+		ExpectedAttempts int
+		ErrorExpected    bool
+	}{
+		{"Defaults", "", 0, defaultAckRetryInterval, defaultAckMaxAttempts, false},
+		{"Configured", "1m", 3, time.Minute, 3, false},
+		{"Invalid interval", "soon", 0, 0, 0, true},
+		{"Zero interval", "0s", 0, 0, 0, true},
+		{"Negative attempts", "", -1, 0, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			interval, attempts, err := ackRetrySettings(test.RetryInterval, test.MaxAttempts)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedInterval, interval)
+			assert.Equal(t, test.ExpectedAttempts, attempts)
+		})
+	}
+}
+
+func TestShouldAcknowledge(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Error    *runtime.MessageError
+		Expected bool
+	}{
+		{"Success", nil, true},
+		{"Stored for retry", &runtime.MessageError{Err: errors.New("failed"), ErrorCode: http.StatusUnprocessableEntity, StoredForRetry: true}, true},
+		{"Bad request", &runtime.MessageError{Err: errors.New("failed"), ErrorCode: http.StatusBadRequest}, true},
+		{"Pipeline failed", &runtime.MessageError{Err: errors.New("failed"), ErrorCode: http.StatusUnprocessableEntity}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, shouldAcknowledge(test.Error))
+		})
+	}
+}
+
+func TestWriteAheadLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.log")
+
+	wal, err := openWriteAheadLog(path)
+	require.NoError(t, err)
+	assert.Empty(t, wal.unacknowledged())
+
+	message1 := types.MessageEnvelope{CorrelationID: "1", ContentType: clients.ContentTypeJSON, Payload: []byte(`{"id":"1"}`)}
+	message2 := types.MessageEnvelope{CorrelationID: "2", ContentType: clients.ContentTypeJSON, Payload: []byte(`{"id":"2"}`)}
+
+	id1, err := wal.add(message1)
+	require.NoError(t, err)
+	id2, err := wal.add(message2)
+	require.NoError(t, err)
+	assert.Len(t, wal.unacknowledged(), 2)
+
+	require.NoError(t, wal.ack(id1))
+	require.NoError(t, wal.close())
+
+	// Simulate a partially written record from a crash
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"Operation":"add","ID":"`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	wal, err = openWriteAheadLog(path)
+	require.NoError(t, err)
+	defer wal.close()
+
+	pending := wal.unacknowledged()
+	require.Len(t, pending, 1)
+	assert.Equal(t, message2, pending[id2])
+
+	require.NoError(t, wal.ack(id2))
+	assert.Empty(t, wal.unacknowledged())
+}
+
+func TestWriteAheadLogRetries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	wal, err := openWriteAheadLog(filepath.Join(dir, "test.log"))
+	require.NoError(t, err)
+	defer wal.close()
+
+	message := types.MessageEnvelope{CorrelationID: "1", ContentType: clients.ContentTypeJSON, Payload: []byte(`{"id":"1"}`)}
+	id, err := wal.add(message)
+	require.NoError(t, err)
+	assert.Empty(t, wal.retries(), "message being processed should not be retried")
+
+	assert.Equal(t, 1, wal.fail(id))
+	assert.Equal(t, map[string]types.MessageEnvelope{id: message}, wal.retries())
+	assert.Empty(t, wal.retries(), "message being retried should not be returned again until it fails")
+
+	assert.Equal(t, 2, wal.fail(id))
+	require.NoError(t, wal.ack(id))
+	assert.Empty(t, wal.retries(), "acknowledged message should not be retried")
+	assert.Equal(t, 0, wal.fail(id), "acknowledged message should not be recorded as failed")
+}
+
+func TestRetryLaterDeadLetters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	deadLetterFile := filepath.Join(dir, "deadletter.log")
+	wal, err := openWriteAheadLog(filepath.Join(dir, "test.log"))
+	require.NoError(t, err)
+	defer wal.close()
+
+	trigger := Trigger{
+		Configuration: &common.ConfigurationStruct{Binding: common.BindingInfo{DeadLetterFile: deadLetterFile}},
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logClient},
+		atLeastOnce:   true,
+		wal:           wal,
+		maxAttempts:   2,
+	}
+
+	message := types.MessageEnvelope{CorrelationID: "1", ContentType: clients.ContentTypeJSON, Payload: []byte(`{"id":"1"}`)}
+	id, err := wal.add(message)
+	require.NoError(t, err)
+
+	trigger.retryLater(message, id)
+	assert.Len(t, wal.unacknowledged(), 1, "message should be kept for retry before the last attempt")
+	_, err = os.Stat(deadLetterFile)
+	assert.True(t, os.IsNotExist(err), "message should not be dead-lettered before the last attempt")
+
+	trigger.retryLater(message, id)
+	assert.Empty(t, wal.unacknowledged(), "dead-lettered message should be acknowledged")
+
+	data, err := ioutil.ReadFile(deadLetterFile)
+	require.NoError(t, err)
+	deadLetter := types.MessageEnvelope{}
+	require.NoError(t, json.Unmarshal(data, &deadLetter))
+	assert.Equal(t, message, deadLetter)
+}
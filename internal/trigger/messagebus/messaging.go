@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
@@ -37,6 +38,9 @@ type Trigger struct {
 	client        messaging.MessageClient
 	topics        []types.TopicChannel
 	EdgeXClients  common.EdgeXClients
+	atLeastOnce   bool
+	wal           *writeAheadLog
+	maxAttempts   int
 }
 
 // Initialize ...
@@ -53,6 +57,11 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 	trigger.topics = []types.TopicChannel{{Topic: trigger.Configuration.Binding.SubscribeTopic, Messages: make(chan types.MessageEnvelope)}}
 	messageErrors := make(chan error)
 
+	trigger.atLeastOnce, err = isAtLeastOnce(trigger.Configuration.Binding.AckMode)
+	if err != nil {
+		return nil, err
+	}
+
 	err = trigger.client.Connect()
 	if err != nil {
 		return nil, err
@@ -64,6 +73,30 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 		trigger.Configuration.MessageBus.SubscribeHost.Host,
 		trigger.Configuration.MessageBus.SubscribeHost.Port))
 
+	// The MessageBus implementations don't support acknowledging messages, so "atleastonce" is provided by the
+	// local write-ahead log only. It covers messages from when they are received until they are processed.
+	var retryTicker *time.Ticker
+	var retries <-chan time.Time
+	if trigger.atLeastOnce {
+		var retryInterval time.Duration
+		retryInterval, trigger.maxAttempts, err = ackRetrySettings(trigger.Configuration.Binding.AckRetryInterval, trigger.Configuration.Binding.AckMaxAttempts)
+		if err != nil {
+			return nil, err
+		}
+
+		trigger.wal, err = openWriteAheadLog(trigger.Configuration.Binding.AckLogFile)
+		if err != nil {
+			return nil, err
+		}
+
+		retryTicker = time.NewTicker(retryInterval)
+		retries = retryTicker.C
+
+		logger.Info(fmt.Sprintf("Using write-ahead log '%s' for 'atleastonce' AckMode, retrying failed messages every %s", trigger.wal.path, retryInterval))
+	}
+
+	trigger.Runtime.SetOutputPublisher(trigger.publishOutput)
+
 	trigger.client.Subscribe(trigger.topics, messageErrors)
 	receiveMessage := true
 
@@ -80,49 +113,48 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 	go func() {
 		defer appWg.Done()
 
+		if retryTicker != nil {
+			defer retryTicker.Stop()
+		}
+
+		if trigger.wal != nil {
+			// Replay the messages not acknowledged before the service last stopped
+			for walID, message := range trigger.wal.unacknowledged() {
+				logger.Info("Replaying unacknowledged message from write-ahead log", clients.CorrelationHeader, message.CorrelationID)
+				go trigger.processMessage(message, walID)
+			}
+		}
+
 		for receiveMessage {
 			select {
 			case <-appCtx.Done():
 				return
 
+			case <-retries:
+				// Replay the messages which failed since the last retry
+				for walID, message := range trigger.wal.retries() {
+					logger.Info("Retrying failed message from write-ahead log", clients.CorrelationHeader, message.CorrelationID)
+					go trigger.processMessage(message, walID)
+				}
+
 			case msgErr := <-messageErrors:
 				logger.Error(fmt.Sprintf("Failed to receive message from bus, %v", msgErr))
 
 			case msgs := <-trigger.topics[0].Messages:
-				go func() {
-					logger.Trace("Received message from bus", "topic", trigger.Configuration.Binding.SubscribeTopic, clients.CorrelationHeader, msgs.CorrelationID)
-
-					edgexContext := &appcontext.Context{
-						CorrelationID:         msgs.CorrelationID,
-						Configuration:         trigger.Configuration,
-						LoggingClient:         trigger.EdgeXClients.LoggingClient,
-						EventClient:           trigger.EdgeXClients.EventClient,
-						ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
-						CommandClient:         trigger.EdgeXClients.CommandClient,
-						NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
-					}
+				logger.Trace("Received message from bus", "topic", trigger.Configuration.Binding.SubscribeTopic, clients.CorrelationHeader, msgs.CorrelationID)
 
-					messageError := trigger.Runtime.ProcessMessage(edgexContext, msgs)
-					if messageError != nil {
-						// ProcessMessage logs the error, so no need to log it here.
-						return
-					}
+				trigger.Runtime.RecordMessage(trigger.Configuration.Binding.SubscribeTopic, msgs)
 
-					if edgexContext.OutputData != nil {
-						outputEnvelope := types.MessageEnvelope{
-							CorrelationID: edgexContext.CorrelationID,
-							Payload:       edgexContext.OutputData,
-							ContentType:   clients.ContentTypeJSON,
-						}
-						err := trigger.client.Publish(outputEnvelope, trigger.Configuration.Binding.PublishTopic)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to publish Message to bus, %v", err))
-							return
-						}
-
-						logger.Trace("Published message to bus", "topic", trigger.Configuration.Binding.PublishTopic, clients.CorrelationHeader, msgs.CorrelationID)
+				walID := ""
+				if trigger.wal != nil {
+					id, err := trigger.wal.add(msgs)
+					if err != nil {
+						logger.Error(fmt.Sprintf("Failed to record message in write-ahead log, %v", err), clients.CorrelationHeader, msgs.CorrelationID)
 					}
-				}()
+					walID = id
+				}
+
+				go trigger.processMessage(msgs, walID)
 			case bg := <-background:
 				go func() {
 					err := trigger.client.Publish(bg, trigger.Configuration.Binding.PublishTopic)
@@ -143,6 +175,110 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 		if err != nil {
 			logger.Error("Unable to disconnect from the message bus", "error", err.Error())
 		}
+
+		if trigger.wal != nil {
+			if err := trigger.wal.close(); err != nil {
+				logger.Error("Unable to close the write-ahead log", "error", err.Error())
+			}
+		}
 	}
 	return deferred, nil
 }
+
+func (trigger *Trigger) processMessage(msgs types.MessageEnvelope, walID string) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         msgs.CorrelationID,
+		ReceivedTopic:         trigger.Configuration.Binding.SubscribeTopic,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+		DeviceClient:          trigger.EdgeXClients.DeviceClient,
+	}
+
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, msgs)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		if shouldAcknowledge(messageError) {
+			trigger.acknowledge(msgs, walID)
+		} else {
+			trigger.retryLater(msgs, walID)
+		}
+		return
+	}
+
+	if edgexContext.OutputData != nil {
+		if err := trigger.publishOutput(edgexContext); err != nil {
+			logger.Error(fmt.Sprintf("Failed to publish Message to bus, %v", err))
+			trigger.retryLater(msgs, walID)
+			return
+		}
+	}
+
+	trigger.acknowledge(msgs, walID)
+}
+
+// publishOutput publishes the context's OutputData to the PublishTopic. It is also the runtime's OutputPublisher.
+func (trigger *Trigger) publishOutput(edgexContext *appcontext.Context) error {
+	contentType := edgexContext.ResponseContentType
+	if len(contentType) == 0 {
+		contentType = clients.ContentTypeJSON
+	}
+
+	outputEnvelope := types.MessageEnvelope{
+		CorrelationID: edgexContext.CorrelationID,
+		Payload:       edgexContext.OutputData,
+		ContentType:   contentType,
+	}
+	if err := trigger.client.Publish(outputEnvelope, trigger.Configuration.Binding.PublishTopic); err != nil {
+		return err
+	}
+
+	trigger.EdgeXClients.LoggingClient.Trace("Published message to bus", "topic", trigger.Configuration.Binding.PublishTopic, clients.CorrelationHeader, edgexContext.CorrelationID)
+	return nil
+}
+
+// acknowledge acknowledges the message has been processed when using the "atleastonce" AckMode
+func (trigger *Trigger) acknowledge(msgs types.MessageEnvelope, walID string) {
+	if !trigger.atLeastOnce {
+		return
+	}
+
+	if trigger.wal == nil || len(walID) == 0 {
+		return
+	}
+
+	if err := trigger.wal.ack(walID); err != nil {
+		trigger.EdgeXClients.LoggingClient.Error(fmt.Sprintf("Failed to acknowledge message, %v", err), clients.CorrelationHeader, msgs.CorrelationID)
+	}
+}
+
+// retryLater records the failed attempt to process the message when using the "atleastonce" AckMode, so it is
+// replayed at the next AckRetryInterval. Once it has failed AckMaxAttempts times it is moved to the DeadLetterFile.
+func (trigger *Trigger) retryLater(msgs types.MessageEnvelope, walID string) {
+	if !trigger.atLeastOnce || trigger.wal == nil || len(walID) == 0 {
+		return
+	}
+
+	logger := trigger.EdgeXClients.LoggingClient
+
+	attempts := trigger.wal.fail(walID)
+	if attempts < trigger.maxAttempts {
+		logger.Debug(fmt.Sprintf("Message failed %d of %d attempts, retrying later", attempts, trigger.maxAttempts), clients.CorrelationHeader, msgs.CorrelationID)
+		return
+	}
+
+	deadLetterFile := trigger.Configuration.Binding.DeadLetterFile
+	if err := appendDeadLetter(deadLetterFile, msgs); err != nil {
+		// Left in the write-ahead log so it is retried rather than lost
+		logger.Error(fmt.Sprintf("Failed to dead-letter message, %v", err), clients.CorrelationHeader, msgs.CorrelationID)
+		return
+	}
+
+	logger.Error(fmt.Sprintf("Message failed %d attempts, moved to dead-letter file", attempts), clients.CorrelationHeader, msgs.CorrelationID)
+	trigger.acknowledge(msgs, walID)
+}
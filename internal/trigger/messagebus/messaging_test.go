@@ -208,6 +208,7 @@ func TestInitializeAndProcessEventWithOutput(t *testing.T) {
 	transform1 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 		transformWasCalled.Set(true)
 		assert.Equal(t, expectedEvent, params[0])
+		edgexcontext.ResponseContentType = clients.ContentTypeText
 		edgexcontext.Complete([]byte("Transformed")) //transformed message published to message bus
 		return false, nil
 
@@ -264,6 +265,7 @@ func TestInitializeAndProcessEventWithOutput(t *testing.T) {
 		case msgs := <-testTopics[0].Messages:
 			receiveMessage = false
 			assert.Equal(t, "Transformed", string(msgs.Payload))
+			assert.Equal(t, clients.ContentTypeText, msgs.ContentType)
 
 		}
 	}
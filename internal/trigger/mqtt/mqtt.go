@@ -17,13 +17,17 @@
 package mqtt
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
 	"sync"
 	"time"
 
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	pahoMqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
@@ -37,10 +41,20 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/pkg/secure"
 )
 
+const (
+	// sharedSubscriptionPrefix is the topic prefix for MQTT shared subscriptions, i.e. $share/<group>/<topic>
+	sharedSubscriptionPrefix = "$share/"
+
+	cborMajorTypeArray = 4
+	cborMajorTypeMap   = 5
+	cborMajorTypeTag   = 6
+)
+
 // Trigger implements Trigger to support Triggers
 type Trigger struct {
 	configuration  *common.ConfigurationStruct
 	mqttClient     pahoMqtt.Client
+	connectionV5   *autopaho.ConnectionManager
 	runtime        *runtime.GolangRuntime
 	edgeXClients   common.EdgeXClients
 	secretProvider security.SecretProvider
@@ -60,7 +74,7 @@ func NewTrigger(
 }
 
 // Initialize initializes the Trigger for an external MQTT broker
-func (trigger *Trigger) Initialize(_ *sync.WaitGroup, _ context.Context, background <-chan types.MessageEnvelope) (bootstrap.Deferred, error) {
+func (trigger *Trigger) Initialize(_ *sync.WaitGroup, appCtx context.Context, background <-chan types.MessageEnvelope) (bootstrap.Deferred, error) {
 	// Convenience short cuts
 	logger := trigger.edgeXClients.LoggingClient
 	brokerConfig := trigger.configuration.MqttBroker
@@ -81,6 +95,19 @@ func (trigger *Trigger) Initialize(_ *sync.WaitGroup, _ context.Context, backgro
 		return nil, fmt.Errorf("invalid MQTT Broker Url '%s': %s", trigger.configuration.MqttBroker.Url, err.Error())
 	}
 
+	useV5, err := secure.IsProtocolVersion5(brokerConfig.ProtocolVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(trigger.configuration.Binding.PublishTopic) > 0 {
+		trigger.runtime.SetOutputPublisher(trigger.publishOutput)
+	}
+
+	if useV5 {
+		return trigger.initializeV5(appCtx, brokerUrl)
+	}
+
 	opts := pahoMqtt.NewClientOptions()
 	opts.AutoReconnect = brokerConfig.AutoReconnect
 	opts.OnConnect = trigger.onConnectHandler
@@ -129,7 +156,7 @@ func (trigger *Trigger) Initialize(_ *sync.WaitGroup, _ context.Context, backgro
 func (trigger *Trigger) onConnectHandler(mqttClient pahoMqtt.Client) {
 	// Convenience short cuts
 	logger := trigger.edgeXClients.LoggingClient
-	topic := trigger.configuration.Binding.SubscribeTopic
+	topic := trigger.subscribeTopic()
 	qos := trigger.configuration.MqttBroker.QoS
 
 	if token := mqttClient.Subscribe(topic, qos, trigger.messageHandler); token.Wait() && token.Error() != nil {
@@ -149,32 +176,22 @@ func (trigger *Trigger) messageHandler(client pahoMqtt.Client, message pahoMqtt.
 	topic := trigger.configuration.Binding.PublishTopic
 
 	data := message.Payload()
-	contentType := clients.ContentTypeJSON
-	if data[0] != byte('{') {
-		// If not JSON then assume it is CBOR
-		contentType = clients.ContentTypeCBOR
+
+	envelope, err := trigger.newEnvelope(uuid.New().String(), data, "")
+	if err != nil {
+		logger.Error(fmt.Sprintf("Rejected message received from MQTT Trigger: %s", err.Error()),
+			clients.CorrelationHeader, envelope.CorrelationID)
+		return
 	}
 
-	correlationID := uuid.New().String()
+	trigger.runtime.RecordMessage(message.Topic(), envelope)
 
-	edgexContext := &appcontext.Context{
-		CorrelationID:         correlationID,
-		Configuration:         trigger.configuration,
-		LoggingClient:         trigger.edgeXClients.LoggingClient,
-		EventClient:           trigger.edgeXClients.EventClient,
-		ValueDescriptorClient: trigger.edgeXClients.ValueDescriptorClient,
-		CommandClient:         trigger.edgeXClients.CommandClient,
-		NotificationsClient:   trigger.edgeXClients.NotificationsClient,
-	}
+	correlationID := envelope.CorrelationID
 
-	logger.Trace("Received message from MQTT Trigger", clients.CorrelationHeader, correlationID)
-	logger.Debug(fmt.Sprintf("Received message from MQTT Trigger with %d bytes", len(data)), clients.ContentType, contentType)
+	edgexContext := trigger.newContext(correlationID, message.Topic())
 
-	envelope := types.MessageEnvelope{
-		CorrelationID: correlationID,
-		ContentType:   contentType,
-		Payload:       data,
-	}
+	logger.Trace("Received message from MQTT Trigger", clients.CorrelationHeader, correlationID)
+	logger.Debug(fmt.Sprintf("Received message from MQTT Trigger with %d bytes", len(envelope.Payload)), clients.ContentType, envelope.ContentType)
 
 	messageError := trigger.runtime.ProcessMessage(edgexContext, envelope)
 	if messageError != nil {
@@ -192,3 +209,141 @@ func (trigger *Trigger) messageHandler(client pahoMqtt.Client, message pahoMqtt.
 		}
 	}
 }
+
+// publishOutput publishes the context's OutputData to the PublishTopic. It is the runtime's OutputPublisher, used for
+// output which isn't the response to a received message, i.e. the output of a pipeline resumed by a batch.
+func (trigger *Trigger) publishOutput(edgexContext *appcontext.Context) error {
+	brokerConfig := trigger.configuration.MqttBroker
+	topic := trigger.configuration.Binding.PublishTopic
+
+	if trigger.connectionV5 != nil {
+		publish := &paho.Publish{
+			Topic:   topic,
+			QoS:     brokerConfig.QoS,
+			Retain:  brokerConfig.Retain,
+			Payload: edgexContext.OutputData,
+			Properties: &paho.PublishProperties{
+				ContentType: edgexContext.ResponseContentType,
+				User:        userProperties(edgexContext),
+			},
+		}
+
+		if _, err := trigger.connectionV5.Publish(context.Background(), publish); err != nil {
+			return fmt.Errorf("could not publish to topic '%s' for MQTT 5 trigger: %s", topic, err.Error())
+		}
+	} else {
+		if trigger.mqttClient == nil {
+			return errors.New("MQTT trigger is not connected")
+		}
+
+		if token := trigger.mqttClient.Publish(topic, brokerConfig.QoS, brokerConfig.Retain, edgexContext.OutputData); token.Wait() && token.Error() != nil {
+			return fmt.Errorf("could not publish to topic '%s' for MQTT trigger: %s", topic, token.Error().Error())
+		}
+	}
+
+	trigger.edgeXClients.LoggingClient.Debug(fmt.Sprintf("Published MQTT Trigger output on topic '%s' with %d bytes", topic, len(edgexContext.OutputData)),
+		clients.CorrelationHeader, edgexContext.CorrelationID)
+	return nil
+}
+
+// subscribeTopic returns the topic to subscribe to, which is a shared subscription topic when
+// a SharedSubscriptionGroup has been configured.
+func (trigger *Trigger) subscribeTopic() string {
+	topic := trigger.configuration.Binding.SubscribeTopic
+	group := trigger.configuration.MqttBroker.SharedSubscriptionGroup
+	if len(group) == 0 {
+		return topic
+	}
+
+	return sharedSubscriptionPrefix + group + "/" + topic
+}
+
+func (trigger *Trigger) newContext(correlationID string, receivedTopic string) *appcontext.Context {
+	return &appcontext.Context{
+		CorrelationID:         correlationID,
+		ReceivedTopic:         receivedTopic,
+		Configuration:         trigger.configuration,
+		LoggingClient:         trigger.edgeXClients.LoggingClient,
+		EventClient:           trigger.edgeXClients.EventClient,
+		ValueDescriptorClient: trigger.edgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.edgeXClients.CommandClient,
+		NotificationsClient:   trigger.edgeXClients.NotificationsClient,
+		DeviceClient:          trigger.edgeXClients.DeviceClient,
+	}
+}
+
+// newEnvelope creates the MessageEnvelope for a received payload. The payload is unwrapped from the EdgeX
+// MessageEnvelope JSON when UseMessageEnvelope is enabled. The content type is the one received with the message,
+// i.e. the MQTT 5 content-type property, if any, then the envelope's ContentType or the configured ContentType and
+// finally the one detected from the payload.
+func (trigger *Trigger) newEnvelope(correlationID string, data []byte, contentType string) (types.MessageEnvelope, error) {
+	brokerConfig := trigger.configuration.MqttBroker
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: correlationID,
+	}
+
+	if len(data) == 0 {
+		return envelope, errors.New("payload is empty")
+	}
+
+	if brokerConfig.UseMessageEnvelope {
+		if err := json.Unmarshal(data, &envelope); err != nil {
+			return envelope, fmt.Errorf("unable to unmarshal MessageEnvelope: %s", err.Error())
+		}
+
+		if len(envelope.CorrelationID) == 0 {
+			envelope.CorrelationID = correlationID
+		}
+
+		if len(envelope.Payload) == 0 {
+			return envelope, errors.New("MessageEnvelope payload is empty")
+		}
+
+		if len(contentType) == 0 {
+			contentType = envelope.ContentType
+		}
+	} else {
+		envelope.Payload = data
+
+		if len(contentType) == 0 {
+			contentType = brokerConfig.ContentType
+		}
+	}
+
+	if len(contentType) == 0 {
+		var err error
+		contentType, err = detectContentType(envelope.Payload)
+		if err != nil {
+			return envelope, err
+		}
+	}
+
+	envelope.ContentType = contentType
+
+	return envelope, nil
+}
+
+// detectContentType sniffs the content type of the payload. JSON objects and arrays, optionally preceded by
+// whitespace, are detected as JSON. CBOR maps, arrays and tagged items are detected as CBOR.
+func detectContentType(data []byte) (string, error) {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 {
+		return "", errors.New("payload is empty")
+	}
+
+	switch trimmed[0] {
+	case '{', '[':
+		return clients.ContentTypeJSON, nil
+	}
+
+	// The CBOR major type is in the high-order 3 bits of the initial byte. The untrimmed data is sniffed on purpose
+	// since CBOR is binary, the whitespace bytes are CBOR integers rather than padding. None of the JSON start bytes
+	// or whitespace bytes are CBOR arrays, maps or tags, so the two checks can't both match.
+	switch data[0] >> 5 {
+	case cborMajorTypeArray, cborMajorTypeMap, cborMajorTypeTag:
+		return clients.ContentTypeCBOR, nil
+	}
+
+	return "", errors.New("unable to detect content type of payload, must be JSON or CBOR")
+}
@@ -0,0 +1,208 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/google/uuid"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/secure"
+)
+
+const (
+	defaultConnectTimeout    = 10 * time.Second
+	defaultDisconnectTimeout = 5 * time.Second
+)
+
+// initializeV5 connects to the external MQTT broker using the MQTT 5 protocol. The connection is
+// re-established and the topic re-subscribed automatically should the connection be lost.
+func (trigger *Trigger) initializeV5(appCtx context.Context, brokerUrl *url.URL) (bootstrap.Deferred, error) {
+	// Convenience short cuts
+	logger := trigger.edgeXClients.LoggingClient
+	brokerConfig := trigger.configuration.MqttBroker
+
+	connectTimeout := defaultConnectTimeout
+	if len(brokerConfig.ConnectTimeout) > 0 {
+		duration, err := time.ParseDuration(brokerConfig.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MQTT ConnectTimeout '%s': %s", brokerConfig.ConnectTimeout, err.Error())
+		}
+		connectTimeout = duration
+	}
+
+	config := autopaho.ClientConfig{
+		BrokerUrls:     []*url.URL{brokerUrl},
+		KeepAlive:      uint16(brokerConfig.KeepAlive),
+		ConnectTimeout: connectTimeout,
+		OnConnectionUp: trigger.onConnectionUpV5,
+		OnConnectError: func(err error) {
+			logger.Error(fmt.Sprintf("could not connect to broker for MQTT trigger: %s", err.Error()))
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: brokerConfig.ClientId,
+			Router:   paho.NewSingleHandlerRouter(trigger.messageHandlerV5),
+		},
+	}
+
+	mqttFactory := secure.NewMqttFactory(
+		logger,
+		trigger.secretProvider,
+		brokerConfig.AuthMode,
+		brokerConfig.SecretPath,
+		brokerConfig.SkipCertVerify,
+	)
+
+	if err := mqttFactory.ConfigureV5(&config); err != nil {
+		return nil, fmt.Errorf("unable to create secure MQTT 5 Client: %s", err.Error())
+	}
+
+	logger.Info(fmt.Sprintf("Connecting to mqtt broker for MQTT 5 trigger at: %s", brokerUrl))
+
+	connection, err := autopaho.NewConnection(appCtx, config)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to broker for MQTT 5 trigger: %s", err.Error())
+	}
+
+	trigger.connectionV5 = connection
+
+	ctx, cancel := context.WithTimeout(appCtx, connectTimeout)
+	defer cancel()
+	if err := connection.AwaitConnection(ctx); err != nil {
+		_ = connection.Disconnect(context.Background())
+		return nil, fmt.Errorf("could not connect to broker for MQTT 5 trigger: %s", err.Error())
+	}
+
+	logger.Info("Connected to mqtt server for MQTT 5 trigger")
+
+	deferred := func() {
+		logger.Info("Disconnecting from broker for MQTT 5 trigger")
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDisconnectTimeout)
+		defer cancel()
+		_ = trigger.connectionV5.Disconnect(ctx)
+	}
+
+	return deferred, nil
+}
+
+func (trigger *Trigger) onConnectionUpV5(connection *autopaho.ConnectionManager, _ *paho.Connack) {
+	// Convenience short cuts
+	logger := trigger.edgeXClients.LoggingClient
+	topic := trigger.subscribeTopic()
+	qos := trigger.configuration.MqttBroker.QoS
+
+	subscribe := &paho.Subscribe{
+		Subscriptions: map[string]paho.SubscribeOptions{
+			topic: {QoS: qos},
+		},
+	}
+
+	if _, err := connection.Subscribe(context.Background(), subscribe); err != nil {
+		logger.Error(fmt.Sprintf("could not subscribe to topic '%s' for MQTT 5 trigger: %s", topic, err.Error()))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Subscribed to topic '%s' for MQTT 5 trigger", topic))
+}
+
+// messageHandlerV5 processes messages received via MQTT 5. The user properties are added to the context values,
+// the content-type property overrides the envelope's, configured or detected content type and the response-topic/correlation-data
+// properties are used to send the pipeline output as the reply to the request.
+func (trigger *Trigger) messageHandlerV5(message *paho.Publish) {
+	// Convenience short cuts
+	logger := trigger.edgeXClients.LoggingClient
+	brokerConfig := trigger.configuration.MqttBroker
+	topic := trigger.configuration.Binding.PublishTopic
+
+	var contentType string
+	if message.Properties != nil {
+		contentType = message.Properties.ContentType
+	}
+
+	envelope, err := trigger.newEnvelope(uuid.New().String(), message.Payload, contentType)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Rejected message received from MQTT 5 Trigger: %s", err.Error()),
+			clients.CorrelationHeader, envelope.CorrelationID)
+		return
+	}
+
+	trigger.runtime.RecordMessage(message.Topic, envelope)
+
+	correlationID := envelope.CorrelationID
+
+	edgexContext := trigger.newContext(correlationID, message.Topic)
+
+	var correlationData []byte
+	if properties := message.Properties; properties != nil {
+		for _, property := range properties.User {
+			edgexContext.AddValue(property.Key, property.Value)
+		}
+
+		if len(properties.ResponseTopic) > 0 {
+			topic = properties.ResponseTopic
+			correlationData = properties.CorrelationData
+		}
+	}
+
+	logger.Trace("Received message from MQTT 5 Trigger", clients.CorrelationHeader, correlationID)
+	logger.Debug(fmt.Sprintf("Received message from MQTT 5 Trigger with %d bytes", len(envelope.Payload)), clients.ContentType, envelope.ContentType)
+
+	messageError := trigger.runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		return
+	}
+
+	if len(edgexContext.OutputData) > 0 && len(topic) > 0 {
+		publish := &paho.Publish{
+			Topic:   topic,
+			QoS:     brokerConfig.QoS,
+			Retain:  brokerConfig.Retain,
+			Payload: edgexContext.OutputData,
+			Properties: &paho.PublishProperties{
+				ContentType:     edgexContext.ResponseContentType,
+				CorrelationData: correlationData,
+				User:            userProperties(edgexContext),
+			},
+		}
+
+		if _, err := trigger.connectionV5.Publish(context.Background(), publish); err != nil {
+			logger.Error(fmt.Sprintf("could not publish to topic '%s' for MQTT 5 trigger: %s", topic, err.Error()))
+		} else {
+			logger.Trace("Sent MQTT 5 Trigger response message", clients.CorrelationHeader, correlationID)
+			logger.Debug(fmt.Sprintf("Sent MQTT 5 Trigger response message on topic '%s' with %d bytes", topic, len(edgexContext.OutputData)))
+		}
+	}
+}
+
+// userProperties converts the context values in to MQTT 5 user properties
+func userProperties(edgexContext *appcontext.Context) paho.UserProperties {
+	var properties paho.UserProperties
+	for key, value := range edgexContext.GetAllValues() {
+		properties.Add(key, value)
+	}
+
+	return properties
+}
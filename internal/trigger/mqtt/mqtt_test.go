@@ -0,0 +1,145 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mqtt
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+func TestSubscribeTopic(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Group    string
+		Expected string
+	}{
+		{"No shared subscription", "", "edgex/events/#"},
+		{"Shared subscription", "app-services", "$share/app-services/edgex/events/#"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := &common.ConfigurationStruct{
+				Binding:    common.BindingInfo{SubscribeTopic: "edgex/events/#"},
+				MqttBroker: common.MqttBrokerConfig{SharedSubscriptionGroup: test.Group},
+			}
+			trigger := NewTrigger(config, nil, common.EdgeXClients{}, nil)
+			assert.Equal(t, test.Expected, trigger.subscribeTopic())
+		})
+	}
+}
+
+func TestUserProperties(t *testing.T) {
+	edgexContext := &appcontext.Context{}
+	edgexContext.AddValue("tenant", "acme")
+
+	properties := userProperties(edgexContext)
+	assert.Len(t, properties, 1)
+	assert.Equal(t, "acme", properties.Get("tenant"))
+}
+
+func TestDetectContentType(t *testing.T) {
+	cborData, err := cbor.Marshal(map[string]string{"device": "test"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name          string
+		Data          []byte
+		Expected      string
+		ErrorExpected bool
+	}{
+		{"JSON object", []byte(`{"device":"test"}`), clients.ContentTypeJSON, false},
+		{"JSON array", []byte(`[{"device":"test"}]`), clients.ContentTypeJSON, false},
+		{"Whitespace prefixed JSON", []byte("  \r\n\t{\"device\":\"test\"}"), clients.ContentTypeJSON, false},
+		{"CBOR", cborData, clients.ContentTypeCBOR, false},
+		{"Whitespace prefixed CBOR", append([]byte(" "), cborData...), "", true},
+		{"Empty", []byte{}, "", true},
+		{"Whitespace only", []byte("   "), "", true},
+		{"Unknown", []byte("plain text"), "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := detectContentType(test.Data)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	correlationID := "123-456"
+	eventJSON := []byte(`{"device":"test"}`)
+
+	tests := []struct {
+		Name                  string
+		ConfiguredContentType string
+		UseMessageEnvelope    bool
+		Data                  []byte
+		ContentType           string
+		ExpectedContentType   string
+		ExpectedCorrelationID string
+		ExpectedPayload       []byte
+		ErrorExpected         bool
+	}{
+		{"Detected", "", false, eventJSON, "", clients.ContentTypeJSON, correlationID, eventJSON, false},
+		{"Configured", "text/plain", false, []byte("plain text"), "", "text/plain", correlationID, []byte("plain text"), false},
+		{"Received overrides configured", "text/plain", false, eventJSON, clients.ContentTypeCBOR, clients.ContentTypeCBOR, correlationID, eventJSON, false},
+		{"Envelope", "", true, []byte(`{"CorrelationID":"abc","ContentType":"application/json","Payload":"eyJkZXZpY2UiOiJ0ZXN0In0="}`), "", clients.ContentTypeJSON, "abc", eventJSON, false},
+		{"Envelope received overrides envelope", "", true, []byte(`{"ContentType":"application/cbor","Payload":"eyJkZXZpY2UiOiJ0ZXN0In0="}`), clients.ContentTypeJSON, clients.ContentTypeJSON, correlationID, eventJSON, false},
+		{"Envelope detected", "", true, []byte(`{"Payload":"eyJkZXZpY2UiOiJ0ZXN0In0="}`), "", clients.ContentTypeJSON, correlationID, eventJSON, false},
+		{"Envelope empty payload", "", true, []byte(`{"ContentType":"application/json"}`), "", "", "", nil, true},
+		{"Envelope invalid", "", true, []byte("not an envelope"), "", "", "", nil, true},
+		{"Empty", "", false, []byte{}, "", "", "", nil, true},
+		{"Unknown", "", false, []byte("plain text"), "", "", "", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := &common.ConfigurationStruct{
+				MqttBroker: common.MqttBrokerConfig{
+					ContentType:        test.ConfiguredContentType,
+					UseMessageEnvelope: test.UseMessageEnvelope,
+				},
+			}
+			trigger := NewTrigger(config, nil, common.EdgeXClients{}, nil)
+
+			envelope, err := trigger.newEnvelope(correlationID, test.Data, test.ContentType)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedContentType, envelope.ContentType)
+			assert.Equal(t, test.ExpectedCorrelationID, envelope.CorrelationID)
+			assert.Equal(t, test.ExpectedPayload, envelope.Payload)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+// inputMessage is a MessageEnvelope as read from the input. Records written by the Recorder are also accepted,
+// in which case the recorded Envelope is used.
+type inputMessage struct {
+	types.MessageEnvelope
+	Envelope *types.MessageEnvelope
+}
+
+// Trigger runs the pipeline once for each MessageEnvelope read from the Input and writes the resulting
+// OutputData to the Output, each followed by a newline.
+type Trigger struct {
+	Configuration *common.ConfigurationStruct
+	Runtime       *runtime.GolangRuntime
+	EdgeXClients  common.EdgeXClients
+	Input         io.Reader
+	Output        io.Writer
+}
+
+// Run processes all the envelopes from the Input. The envelopes are JSON objects, typically one per line.
+// An error is returned if the Input can't be read or any of the envelopes fail to process.
+func (trigger *Trigger) Run(appCtx context.Context) error {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	decoder := json.NewDecoder(trigger.Input)
+	total := 0
+	failed := 0
+
+	for decoder.More() {
+		select {
+		case <-appCtx.Done():
+			return fmt.Errorf("processing stopped after %d messages", total)
+		default:
+		}
+
+		message := inputMessage{}
+		if err := decoder.Decode(&message); err != nil {
+			return fmt.Errorf("unable to read message #%d from input: %s", total+1, err.Error())
+		}
+
+		total++
+
+		envelope := message.MessageEnvelope
+		if message.Envelope != nil {
+			envelope = *message.Envelope
+		}
+
+		if len(envelope.ContentType) == 0 {
+			envelope.ContentType = clients.ContentTypeJSON
+		}
+
+		if err := trigger.processMessage(envelope); err != nil {
+			failed++
+			logger.Error(fmt.Sprintf("Failed to process message #%d: %s", total, err.Error()),
+				clients.CorrelationHeader, envelope.CorrelationID)
+		}
+	}
+
+	logger.Info(fmt.Sprintf("Processed %d messages from input", total))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d messages failed to process", failed, total)
+	}
+
+	return nil
+}
+
+func (trigger *Trigger) processMessage(envelope types.MessageEnvelope) error {
+	edgexContext := &appcontext.Context{
+		CorrelationID:         envelope.CorrelationID,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+		DeviceClient:          trigger.EdgeXClients.DeviceClient,
+	}
+
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		return messageError.Err
+	}
+
+	if edgexContext.OutputData == nil {
+		return nil
+	}
+
+	if _, err := trigger.Output.Write(edgexContext.OutputData); err != nil {
+		return fmt.Errorf("unable to write output: %s", err.Error())
+	}
+
+	if _, err := trigger.Output.Write([]byte("\n")); err != nil {
+		return fmt.Errorf("unable to write output: %s", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package offline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+var lc = logger.NewMockClient()
+
+func newTrigger(input string, output *bytes.Buffer) *Trigger {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		data := params[0].([]byte)
+		if string(data) == "fail" {
+			return false, errors.New("failed")
+		}
+
+		edgexcontext.Complete([]byte(strings.ToUpper(string(data))))
+		return false, nil
+	}
+
+	testRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms([]appcontext.AppFunction{transform})
+
+	return &Trigger{
+		Configuration: &common.ConfigurationStruct{},
+		Runtime:       testRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: lc},
+		Input:         strings.NewReader(input),
+		Output:        output,
+	}
+}
+
+func TestRun(t *testing.T) {
+	// Payloads are base64 encoded: "first" and "second". The second message is in the Recorder format.
+	input := `{"CorrelationID":"1","ContentType":"text/plain","Payload":"Zmlyc3Q="}
+{"Timestamp":"2020-10-01T00:00:00Z","Topic":"events","Envelope":{"CorrelationID":"2","Payload":"c2Vjb25k"}}
+`
+
+	output := &bytes.Buffer{}
+	err := newTrigger(input, output).Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "FIRST\nSECOND\n", output.String())
+}
+
+func TestRunWithFailures(t *testing.T) {
+	// Payloads are base64 encoded: "fail" and "ok"
+	input := `{"Payload":"ZmFpbA=="}
+{"Payload":"b2s="}`
+
+	output := &bytes.Buffer{}
+	err := newTrigger(input, output).Run(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, "1 of 2 messages failed to process", err.Error())
+	assert.Equal(t, "OK\n", output.String())
+}
+
+func TestRunInvalidInput(t *testing.T) {
+	output := &bytes.Buffer{}
+	err := newTrigger("not json", output).Run(context.Background())
+	require.Error(t, err)
+	assert.Empty(t, output.String())
+}
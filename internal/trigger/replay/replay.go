@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/recorder"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+// Trigger implements Trigger to replay the messages recorded by the Recorder
+type Trigger struct {
+	Configuration *common.ConfigurationStruct
+	Runtime       *runtime.GolangRuntime
+	EdgeXClients  common.EdgeXClients
+}
+
+// Initialize reads the recorded messages and starts replaying them through the pipeline
+func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context, background <-chan types.MessageEnvelope) (bootstrap.Deferred, error) {
+	logger := trigger.EdgeXClients.LoggingClient
+	replayConfig := trigger.Configuration.Replay
+
+	logger.Info("Initializing Replay Trigger")
+
+	if background != nil {
+		return nil, errors.New("background publishing not supported for services using Replay trigger")
+	}
+
+	if len(replayConfig.File) == 0 {
+		return nil, errors.New("missing File for Replay Trigger. Must be present in [Replay] section.")
+	}
+
+	if replayConfig.Speed < 0 {
+		return nil, fmt.Errorf("invalid Replay Speed '%v', must not be negative", replayConfig.Speed)
+	}
+
+	records, err := recorder.ReadRecords(replayConfig.File)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("Replaying %d messages from '%s'", len(records), replayConfig.File))
+
+	appWg.Add(1)
+
+	go func() {
+		defer appWg.Done()
+
+		for index, record := range records {
+			if index > 0 {
+				delay := replayDelay(records[index-1].Timestamp, record.Timestamp, replayConfig.Speed)
+				if delay > 0 {
+					select {
+					case <-appCtx.Done():
+						logger.Info("Replay stopped")
+						return
+					case <-time.After(delay):
+					}
+				}
+			}
+
+			select {
+			case <-appCtx.Done():
+				logger.Info("Replay stopped")
+				return
+			default:
+			}
+
+			trigger.processRecord(record)
+		}
+
+		logger.Info(fmt.Sprintf("Replay of '%s' complete", replayConfig.File))
+	}()
+
+	return nil, nil
+}
+
+func (trigger *Trigger) processRecord(record recorder.Record) {
+	logger := trigger.EdgeXClients.LoggingClient
+	envelope := record.Envelope
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         envelope.CorrelationID,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+		DeviceClient:          trigger.EdgeXClients.DeviceClient,
+	}
+
+	logger.Trace("Replaying message", "topic", record.Topic, clients.CorrelationHeader, envelope.CorrelationID)
+
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		return
+	}
+
+	if edgexContext.OutputData != nil {
+		logger.Debug(fmt.Sprintf("Replayed message resulted in %d bytes of output data", len(edgexContext.OutputData)),
+			clients.CorrelationHeader, envelope.CorrelationID)
+	}
+}
+
+// replayDelay returns the time to wait between replaying two messages based on when they were originally received
+func replayDelay(previous time.Time, next time.Time, speed float64) time.Duration {
+	if speed == 0 || !next.After(previous) {
+		return 0
+	}
+
+	return time.Duration(float64(next.Sub(previous)) / speed)
+}
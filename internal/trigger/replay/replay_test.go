@@ -0,0 +1,111 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package replay
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/recorder"
+	"github.com/jcerato/app-functions-sdk-go/internal/runtime"
+)
+
+var lc = logger.NewMockClient()
+
+func TestReplayDelay(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		Name     string
+		Previous time.Time
+		Next     time.Time
+		Speed    float64
+		Expected time.Duration
+	}{
+		{"Original speed", start, start.Add(time.Second), 1, time.Second},
+		{"Accelerated", start, start.Add(time.Second), 10, 100 * time.Millisecond},
+		{"As fast as possible", start, start.Add(time.Second), 0, 0},
+		{"Out of order", start.Add(time.Second), start, 1, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, replayDelay(test.Previous, test.Next, test.Speed))
+		})
+	}
+}
+
+func TestInitializeMissingFile(t *testing.T) {
+	trigger := Trigger{
+		Configuration: &common.ConfigurationStruct{},
+		EdgeXClients:  common.EdgeXClients{LoggingClient: lc},
+	}
+
+	_, err := trigger.Initialize(&sync.WaitGroup{}, context.Background(), nil)
+	require.Error(t, err)
+}
+
+func TestInitializeAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "recording.jsonl")
+	messageRecorder, err := recorder.NewRecorder(common.RecorderInfo{File: path}, lc)
+	require.NoError(t, err)
+
+	messageRecorder.Record("topic", types.MessageEnvelope{CorrelationID: "1", ContentType: clients.ContentTypeJSON, Payload: []byte("first")})
+	messageRecorder.Record("topic", types.MessageEnvelope{CorrelationID: "2", ContentType: clients.ContentTypeJSON, Payload: []byte("second")})
+	require.NoError(t, messageRecorder.Close())
+
+	var replayed []string
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		replayed = append(replayed, string(params[0].([]byte)))
+		return false, nil
+	}
+
+	testRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms([]appcontext.AppFunction{transform})
+
+	trigger := Trigger{
+		Configuration: &common.ConfigurationStruct{Replay: common.ReplayInfo{File: path, Speed: 100}},
+		Runtime:       testRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: lc},
+	}
+
+	appWg := &sync.WaitGroup{}
+	deferred, err := trigger.Initialize(appWg, context.Background(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, deferred)
+
+	appWg.Wait()
+	assert.Equal(t, []string{"first", "second"}, replayed)
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/internal/security"
 	"github.com/jcerato/app-functions-sdk-go/internal/telemetry"
 	"github.com/jcerato/app-functions-sdk-go/internal/v2/dtos/requests"
+	"github.com/jcerato/app-functions-sdk-go/internal/v2/dtos/responses"
 )
 
 // V2HttpController controller for V2 REST APIs
@@ -92,7 +93,7 @@ func (v2c *V2HttpController) Config(writer http.ResponseWriter, request *http.Re
 	v2c.sendResponse(writer, request, contractsV2.ApiVersionRoute, response, http.StatusOK)
 }
 
-// Metrics handles the request to the /metrics endpoint, memory and cpu utilization stats
+// Metrics handles the request to the /metrics endpoint, memory and cpu utilization stats and the registered counters
 // It returns a response as specified by the V2 API swagger in openapi/v2
 func (v2c *V2HttpController) Metrics(writer http.ResponseWriter, request *http.Request) {
 	t := telemetry.NewSystemUsage()
@@ -106,7 +107,7 @@ func (v2c *V2HttpController) Metrics(writer http.ResponseWriter, request *http.R
 		CpuBusyAvg:     uint8(t.CpuBusyAvg),
 	}
 
-	response := common.NewMetricsResponse(metrics)
+	response := responses.NewMetricsResponse(metrics, t.Counters)
 	v2c.sendResponse(writer, request, contractsV2.ApiMetricsRoute, response, http.StatusOK)
 }
 
@@ -39,7 +39,9 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/internal"
 	sdkCommon "github.com/jcerato/app-functions-sdk-go/internal/common"
 	"github.com/jcerato/app-functions-sdk-go/internal/security"
+	"github.com/jcerato/app-functions-sdk-go/internal/telemetry"
 	"github.com/jcerato/app-functions-sdk-go/internal/v2/dtos/requests"
+	"github.com/jcerato/app-functions-sdk-go/internal/v2/dtos/responses"
 )
 
 var expectedCorrelationId = uuid.New().String()
@@ -119,10 +121,14 @@ func TestVersionRequest(t *testing.T) {
 
 func TestMetricsRequest(t *testing.T) {
 	target := NewV2HttpController(nil, logger.NewMockClient(), nil, nil)
+	// The counters are process wide, so only the change is asserted
+	dropped := telemetry.GetCounter("TestDropped")
+	expectedDropped := dropped.Count() + 3
+	dropped.Add(3)
 
 	recorder := doRequest(t, http.MethodGet, contractsV2.ApiMetricsRoute, target.Metrics, nil)
 
-	actual := common.MetricsResponse{}
+	actual := responses.MetricsResponse{}
 	err := json.Unmarshal(recorder.Body.Bytes(), &actual)
 	require.NoError(t, err)
 
@@ -134,6 +140,7 @@ func TestMetricsRequest(t *testing.T) {
 	assert.NotZero(t, actual.Metrics.MemSys)
 	assert.NotZero(t, actual.Metrics.MemTotalAlloc)
 	assert.NotNil(t, actual.Metrics.CpuBusyAvg)
+	assert.Equal(t, expectedDropped, actual.Counters["TestDropped"])
 }
 
 func TestConfigRequest(t *testing.T) {
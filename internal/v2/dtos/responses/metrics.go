@@ -0,0 +1,37 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package responses
+
+import (
+	"github.com/edgexfoundry/go-mod-core-contracts/v2/dtos/common"
+)
+
+// MetricsResponse is the response DTO of the metrics endpoint, which extends the common MetricsResponse with the
+// counters registered by the pipeline functions, i.e. the number of items dropped.
+// See detail specified by the V2 API swagger in openapi/v2
+type MetricsResponse struct {
+	common.MetricsResponse `json:",inline"`
+	Counters               map[string]uint64 `json:"counters,omitempty"`
+}
+
+// NewMetricsResponse creates new MetricsResponse with all fields set appropriately
+func NewMetricsResponse(metrics common.Metrics, counters map[string]uint64) MetricsResponse {
+	return MetricsResponse{
+		MetricsResponse: common.NewMetricsResponse(metrics),
+		Counters:        counters,
+	}
+}
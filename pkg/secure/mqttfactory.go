@@ -20,7 +20,10 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/eclipse/paho.golang/autopaho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 
@@ -46,6 +49,9 @@ const (
 	MQTTSecretClientKey  = "clientkey"
 	MQTTSecretClientCert = AuthModeCert
 	MQTTSecretCACert     = AuthModeCA
+	// MQTT protocol versions which can be selected
+	ProtocolVersion311 = "3.1.1"
+	ProtocolVersion5   = "5"
 )
 
 type MqttFactory struct {
@@ -96,6 +102,48 @@ func (factory MqttFactory) Create(opts *mqtt.ClientOptions) (mqtt.Client, error)
 	return mqtt.NewClient(factory.opts), nil
 }
 
+// ConfigureV5 applies the configured AuthMode to the MQTT 5 client configuration. The secrets are retrieved
+// from the SecretPath just like Create does for MQTT 3.1.1 clients.
+func (factory MqttFactory) ConfigureV5(cfg *autopaho.ClientConfig) error {
+	if factory.authMode == "" {
+		factory.authMode = AuthModeNone
+		factory.logger.Warn("AuthMode not set, defaulting to \"" + AuthModeNone + "\"")
+	}
+
+	secrets, err := factory.getSecrets()
+	if err != nil {
+		return err
+	}
+	if secrets == nil {
+		return nil
+	}
+
+	if err = factory.validateSecrets(*secrets); err != nil {
+		return err
+	}
+
+	if factory.authMode == AuthModeUsernamePassword {
+		cfg.SetUsernamePassword(secrets.username, []byte(secrets.password))
+	}
+
+	cfg.TlsCfg, err = factory.tlsConfig(*secrets)
+	return err
+}
+
+// IsProtocolVersion5 determines if the protocol version specified in configuration selects MQTT 5.
+// An empty version defaults to MQTT 3.1.1.
+func IsProtocolVersion5(version string) (bool, error) {
+	switch strings.TrimSpace(version) {
+	case "", ProtocolVersion311, "3", "4":
+		return false, nil
+	case ProtocolVersion5, "5.0":
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported MQTT protocol version '%s'. Must be '%s' or '%s'",
+			version, ProtocolVersion311, ProtocolVersion5)
+	}
+}
+
 func (factory MqttFactory) getSecrets() (*mqttSecrets, error) {
 	// No Auth? No Problem!...No secrets required.
 	if factory.authMode == AuthModeNone {
@@ -148,37 +196,45 @@ func (factory MqttFactory) validateSecrets(secrets mqttSecrets) error {
 }
 
 func (factory MqttFactory) configureMQTTClientForAuth(secrets mqttSecrets) error {
-	var cert tls.Certificate
-	var err error
-	caCertPool := x509.NewCertPool()
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: factory.skipCertVerify,
-	}
 	switch factory.authMode {
 	case AuthModeUsernamePassword:
 		factory.opts.SetUsername(secrets.username)
 		factory.opts.SetPassword(secrets.password)
-	case AuthModeCert:
-		cert, err = tls.X509KeyPair(secrets.certPemBlock, secrets.keyPemBlock)
+	case AuthModeNone:
+		return nil
+	}
+
+	tlsConfig, err := factory.tlsConfig(secrets)
+	if err != nil {
+		return err
+	}
+
+	factory.opts.SetTLSConfig(tlsConfig)
+
+	return nil
+}
+
+func (factory MqttFactory) tlsConfig(secrets mqttSecrets) (*tls.Config, error) {
+	caCertPool := x509.NewCertPool()
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: factory.skipCertVerify,
+	}
+
+	if factory.authMode == AuthModeCert {
+		cert, err := tls.X509KeyPair(secrets.certPemBlock, secrets.keyPemBlock)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		tlsConfig.Certificates = []tls.Certificate{cert}
-	case AuthModeCA:
-		break
-	case AuthModeNone:
-		return nil
 	}
 
 	if len(secrets.caPemBlock) > 0 {
 		ok := caCertPool.AppendCertsFromPEM(secrets.caPemBlock)
 		if !ok {
-			return errors.New("Error parsing CA PEM block")
+			return nil, errors.New("Error parsing CA PEM block")
 		}
 		tlsConfig.ClientCAs = caCertPool
 	}
 
-	factory.opts.SetTLSConfig(tlsConfig)
-
-	return nil
+	return tlsConfig, nil
 }
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/eclipse/paho.golang/autopaho"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/stretchr/testify/assert"
@@ -330,3 +331,56 @@ func (s *mockMQTTSecretClient) GetSecrets(path string, _ ...string) (map[string]
 func (s *mockMQTTSecretClient) StoreSecrets(_ string, _ map[string]string) error {
 	return nil
 }
+
+func TestIsProtocolVersion5(t *testing.T) {
+	tests := []struct {
+		Name          string
+		Version       string
+		Expected      bool
+		ErrorExpected bool
+	}{
+		{"Default", "", false, false},
+		{"3.1.1", ProtocolVersion311, false, false},
+		{"5", ProtocolVersion5, true, false},
+		{"5.0", "5.0", true, false},
+		{"Invalid", "6", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := IsProtocolVersion5(test.Version)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestConfigureV5WithUsernamePassword(t *testing.T) {
+	mockSecretProvider := security.NewSecretProvider(nil, nil)
+	mockSecretProvider.ExclusiveSecretClient = &mockMQTTSecretClient{}
+
+	target := NewMqttFactory(logger.NewMockClient(), mockSecretProvider, AuthModeUsernamePassword, "/path", false)
+	config := autopaho.ClientConfig{}
+
+	err := target.ConfigureV5(&config)
+	require.NoError(t, err)
+	require.NotNil(t, config.TlsCfg)
+	assert.Nil(t, config.TlsCfg.Certificates)
+}
+
+func TestConfigureV5WithNone(t *testing.T) {
+	mockSecretProvider := security.NewSecretProvider(nil, nil)
+	mockSecretProvider.ExclusiveSecretClient = &mockMQTTSecretClient{}
+
+	target := NewMqttFactory(logger.NewMockClient(), mockSecretProvider, AuthModeNone, "", false)
+	config := autopaho.ClientConfig{}
+
+	err := target.ConfigureV5(&config)
+	require.NoError(t, err)
+	assert.Nil(t, config.TlsCfg)
+}
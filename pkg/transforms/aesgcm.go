@@ -0,0 +1,212 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+// aesGCMKeySize is the key size in bytes for AES-256
+const aesGCMKeySize = 32
+
+// AESGCM encrypts and decrypts data using AES-256-GCM with a random nonce per message. The key is retrieved from
+// the SecretProvider and is re-retrieved when the secrets are updated, so key rotation is picked up. The key
+// secret must be the 32 byte key encoded as base64 or hex.
+type AESGCM struct {
+	// SecretPath is the path in the SecretProvider to retrieve the key from
+	SecretPath string
+	// SecretName is the name of the secret holding the key
+	SecretName string
+
+	key              []byte
+	previousKey      []byte
+	keyLastRetrieved time.Time
+	lock             sync.Mutex
+}
+
+// NewAESGCM creates, initializes and returns a new instance of AESGCM
+func NewAESGCM(secretPath string, secretName string) *AESGCM {
+	return &AESGCM{
+		SecretPath: secretPath,
+		SecretName: secretName,
+	}
+}
+
+// EncryptWithAESGCM encrypts a string, []byte, or json.Marshaller type using AES-256-GCM.
+// It will return a Base64 encoded []byte of the nonce followed by the encrypted data.
+func (aesGCM *AESGCM) EncryptWithAESGCM(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("no data received to encrypt")
+	}
+	edgexcontext.LoggingClient.Debug("Encrypting with AES-GCM")
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	key, _, err := aesGCM.getKeys(edgexcontext)
+	if err != nil {
+		return false, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return false, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return false, fmt.Errorf("unable to generate nonce: %s", err.Error())
+	}
+
+	// The nonce is prepended to the encrypted data so that it is available for decryption
+	encrypted := gcm.Seal(nonce, nonce, data, nil)
+
+	encodedData := []byte(base64.StdEncoding.EncodeToString(encrypted))
+
+	// Set response "content-type" header to "text/plain"
+	edgexcontext.ResponseContentType = clients.ContentTypeText
+
+	return true, encodedData
+}
+
+// DecryptWithAESGCM decrypts Base64 encoded data, received as either a string or []byte, which has been encrypted
+// by EncryptWithAESGCM. It will return the decrypted data as a []byte. When the key has been rotated the previous
+// key is also tried, so data encrypted before the rotation can still be decrypted.
+func (aesGCM *AESGCM) DecryptWithAESGCM(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("no data received to decrypt")
+	}
+	edgexcontext.LoggingClient.Debug("Decrypting with AES-GCM")
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false, fmt.Errorf("unable to decode encrypted data: %s", err.Error())
+	}
+
+	key, previousKey, err := aesGCM.getKeys(edgexcontext)
+	if err != nil {
+		return false, err
+	}
+
+	decrypted, err := decryptGCM(key, encrypted)
+	if err != nil && previousKey != nil {
+		decrypted, err = decryptGCM(previousKey, encrypted)
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, decrypted
+}
+
+// getKeys returns the current and previous keys, retrieving the key from the SecretProvider
+// when not yet retrieved or when the secrets have been updated since.
+func (aesGCM *AESGCM) getKeys(edgexcontext *appcontext.Context) ([]byte, []byte, error) {
+	aesGCM.lock.Lock()
+	defer aesGCM.lock.Unlock()
+
+	if aesGCM.key != nil && !aesGCM.keyLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
+		return aesGCM.key, aesGCM.previousKey, nil
+	}
+
+	secrets, err := edgexcontext.GetSecrets(aesGCM.SecretPath, aesGCM.SecretName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to retrieve encryption key from SecretPath '%s': %s", aesGCM.SecretPath, err.Error())
+	}
+
+	value, ok := secrets[aesGCM.SecretName]
+	if !ok {
+		return nil, nil, fmt.Errorf("encryption key secret '%s' not found in SecretPath '%s'", aesGCM.SecretName, aesGCM.SecretPath)
+	}
+
+	key, err := decodeAESGCMKey(value)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if aesGCM.key != nil && string(aesGCM.key) != string(key) {
+		edgexcontext.LoggingClient.Info("AES-GCM encryption key has been rotated")
+		aesGCM.previousKey = aesGCM.key
+	}
+
+	aesGCM.key = key
+	aesGCM.keyLastRetrieved = time.Now()
+
+	return aesGCM.key, aesGCM.previousKey, nil
+}
+
+func decodeAESGCMKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == aesGCMKeySize {
+		return key, nil
+	}
+
+	if key, err := hex.DecodeString(value); err == nil && len(key) == aesGCMKeySize {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("encryption key must be %d bytes encoded as base64 or hex", aesGCMKeySize)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func decryptGCM(key []byte, encrypted []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return nil, errors.New("encrypted data is too short")
+	}
+
+	nonce := encrypted[:gcm.NonceSize()]
+	decrypted, err := gcm.Open(nil, nonce, encrypted[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt data: %s", err.Error())
+	}
+
+	return decrypted, nil
+}
@@ -0,0 +1,151 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	bootstrapConfig "github.com/edgexfoundry/go-mod-bootstrap/config"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/security"
+)
+
+const (
+	aesGCMSecretPath = "aes"
+	aesGCMSecretName = "key"
+)
+
+var (
+	aesGCMKey1 = []byte("0123456789abcdef0123456789abcdef")
+	aesGCMKey2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func newAESGCMContext(t *testing.T, key string) (*appcontext.Context, *security.SecretProviderMock) {
+	secretProvider := security.NewSecretProviderMock(&common.ConfigurationStruct{
+		SecretStoreExclusive: bootstrapConfig.SecretStoreInfo{Path: "/app/"},
+	})
+	require.NoError(t, secretProvider.StoreSecrets(aesGCMSecretPath, map[string]string{aesGCMSecretName: key}))
+
+	return &appcontext.Context{
+		LoggingClient:  logClient,
+		SecretProvider: secretProvider,
+	}, secretProvider
+}
+
+func TestAESGCMEncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		Name string
+		Key  string
+	}{
+		{"Base64 key", base64.StdEncoding.EncodeToString(aesGCMKey1)},
+		{"Hex key", hex.EncodeToString(aesGCMKey1)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			edgexContext, _ := newAESGCMContext(t, test.Key)
+			aesGCM := NewAESGCM(aesGCMSecretPath, aesGCMSecretName)
+
+			continuePipeline, encrypted := aesGCM.EncryptWithAESGCM(edgexContext, []byte(plainString))
+			require.True(t, continuePipeline, encrypted)
+			assert.Equal(t, clients.ContentTypeText, edgexContext.ResponseContentType)
+
+			// A random nonce is used for each message
+			_, encrypted2 := aesGCM.EncryptWithAESGCM(edgexContext, []byte(plainString))
+			assert.NotEqual(t, encrypted, encrypted2)
+
+			continuePipeline, decrypted := aesGCM.DecryptWithAESGCM(edgexContext, encrypted)
+			require.True(t, continuePipeline, decrypted)
+			assert.Equal(t, plainString, string(decrypted.([]byte)))
+		})
+	}
+}
+
+func TestAESGCMKeyRotation(t *testing.T) {
+	edgexContext, secretProvider := newAESGCMContext(t, base64.StdEncoding.EncodeToString(aesGCMKey1))
+	aesGCM := NewAESGCM(aesGCMSecretPath, aesGCMSecretName)
+
+	continuePipeline, encryptedWithKey1 := aesGCM.EncryptWithAESGCM(edgexContext, plainString)
+	require.True(t, continuePipeline, encryptedWithKey1)
+
+	require.NoError(t, secretProvider.StoreSecrets(aesGCMSecretPath, map[string]string{aesGCMSecretName: base64.StdEncoding.EncodeToString(aesGCMKey2)}))
+	secretProvider.InsecureSecretsUpdated()
+
+	continuePipeline, encryptedWithKey2 := aesGCM.EncryptWithAESGCM(edgexContext, plainString)
+	require.True(t, continuePipeline, encryptedWithKey2)
+	assert.Equal(t, aesGCMKey2, aesGCM.key, "rotated key should be used")
+
+	// Data encrypted with the previous key can still be decrypted
+	for _, encrypted := range []interface{}{encryptedWithKey1, encryptedWithKey2} {
+		continuePipeline, decrypted := aesGCM.DecryptWithAESGCM(edgexContext, encrypted)
+		require.True(t, continuePipeline, decrypted)
+		assert.Equal(t, plainString, string(decrypted.([]byte)))
+	}
+
+	// Data encrypted with another key can't be decrypted
+	other := NewAESGCM(aesGCMSecretPath, aesGCMSecretName)
+	other.key = []byte("00000000000000000000000000000000")
+	other.keyLastRetrieved = secretProvider.SecretsLastUpdated()
+	_, encryptedWithOther := other.EncryptWithAESGCM(edgexContext, plainString)
+	continuePipeline, result := aesGCM.DecryptWithAESGCM(edgexContext, encryptedWithOther)
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error))
+}
+
+func TestAESGCMErrors(t *testing.T) {
+	validKey := base64.StdEncoding.EncodeToString(aesGCMKey1)
+
+	tests := []struct {
+		Name       string
+		Key        string
+		SecretName string
+		Decrypt    bool
+		Data       []interface{}
+	}{
+		{"No data to encrypt", validKey, aesGCMSecretName, false, nil},
+		{"No data to decrypt", validKey, aesGCMSecretName, true, nil},
+		{"Invalid key", "too short", aesGCMSecretName, false, []interface{}{plainString}},
+		{"Missing secret", validKey, "bogus", false, []interface{}{plainString}},
+		{"Not base64", validKey, aesGCMSecretName, true, []interface{}{"not base64!"}},
+		{"Too short", validKey, aesGCMSecretName, true, []interface{}{"AAAA"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			edgexContext, _ := newAESGCMContext(t, test.Key)
+			aesGCM := NewAESGCM(aesGCMSecretPath, test.SecretName)
+
+			var continuePipeline bool
+			var result interface{}
+			if test.Decrypt {
+				continuePipeline, result = aesGCM.DecryptWithAESGCM(edgexContext, test.Data...)
+			} else {
+				continuePipeline, result = aesGCM.EncryptWithAESGCM(edgexContext, test.Data...)
+			}
+
+			assert.False(t, continuePipeline)
+			assert.Error(t, result.(error))
+		})
+	}
+}
@@ -0,0 +1,364 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// Statistics which can be calculated by the Aggregation transform
+const (
+	StatisticMin   = "min"
+	StatisticMax   = "max"
+	StatisticAvg   = "avg"
+	StatisticCount = "count"
+	StatisticSum   = "sum"
+)
+
+// DefaultStatistics are the statistics calculated when none are specified
+var DefaultStatistics = []string{StatisticMin, StatisticMax, StatisticAvg, StatisticCount, StatisticSum}
+
+type readingStatistics struct {
+	count int64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func (stats *readingStatistics) add(value float64) {
+	if stats.count == 0 || value < stats.min {
+		stats.min = value
+	}
+	if stats.count == 0 || value > stats.max {
+		stats.max = value
+	}
+	stats.count++
+	stats.sum += value
+}
+
+func (stats *readingStatistics) value(statistic string) float64 {
+	switch statistic {
+	case StatisticMin:
+		return stats.min
+	case StatisticMax:
+		return stats.max
+	case StatisticSum:
+		return stats.sum
+	case StatisticAvg:
+		return stats.sum / float64(stats.count)
+	default:
+		return math.NaN()
+	}
+}
+
+// window holds the statistics of each reading name for a single window of a single device
+type window map[string]*readingStatistics
+
+// Aggregation aggregates the numeric reading values of Events over time windows, keyed by device and reading
+// name, and emits an Event containing the configured statistics for each window when it closes. Windows are aligned
+// to multiples of the slide, which is the window size for tumbling windows, so windows close every slide. A timer
+// closes the windows when they end and sends their Events to the remaining pipeline functions in the background,
+// which also happens for the windows still open when the service shuts down.
+type Aggregation struct {
+	WindowSize time.Duration
+	Slide      time.Duration
+	Statistics []string
+
+	// windows is keyed by device name and then window start in Unix nanoseconds
+	windows map[string]map[int64]window
+	lock    sync.Mutex
+	// timer is running while there are open windows, it closes the windows which have ended when it expires
+	timer *time.Timer
+	// resume executes the rest of the pipeline for the Events of windows closed by the timer or on shutdown
+	resume       func(result interface{}) error
+	shutdownOnce sync.Once
+	afterFunc    func(duration time.Duration, function func()) *time.Timer
+	now          func() time.Time
+}
+
+// NewTumblingWindowAggregation creates, initializes and returns a new instance of Aggregation using
+// non-overlapping windows of the specified size, i.e. "1m".
+func NewTumblingWindowAggregation(windowSize string, statistics []string) (*Aggregation, error) {
+	return NewSlidingWindowAggregation(windowSize, windowSize, statistics)
+}
+
+// NewSlidingWindowAggregation creates, initializes and returns a new instance of Aggregation using windows
+// of the specified size, a new one starting every slide, i.e. a "5m" window every "1m". The window size
+// must be a multiple of the slide.
+func NewSlidingWindowAggregation(windowSize string, slide string, statistics []string) (*Aggregation, error) {
+	parsedSize, err := time.ParseDuration(windowSize)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window size '%s': %s", windowSize, err.Error())
+	}
+
+	parsedSlide, err := time.ParseDuration(slide)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window slide '%s': %s", slide, err.Error())
+	}
+
+	if parsedSize <= 0 || parsedSlide <= 0 {
+		return nil, errors.New("window size and slide must be greater than zero")
+	}
+
+	if parsedSlide > parsedSize || parsedSize%parsedSlide != 0 {
+		return nil, fmt.Errorf("window size '%s' must be a multiple of the slide '%s'", windowSize, slide)
+	}
+
+	if len(statistics) == 0 {
+		statistics = DefaultStatistics
+	}
+
+	for _, statistic := range statistics {
+		switch statistic {
+		case StatisticMin, StatisticMax, StatisticAvg, StatisticCount, StatisticSum:
+		default:
+			return nil, fmt.Errorf("unknown statistic '%s'", statistic)
+		}
+	}
+
+	return &Aggregation{
+		WindowSize: parsedSize,
+		Slide:      parsedSlide,
+		Statistics: statistics,
+		windows:    make(map[string]map[int64]window),
+		afterFunc:  time.AfterFunc,
+		now:        time.Now,
+	}, nil
+}
+
+// Aggregate adds the Event's numeric readings to the device's open windows. The pipeline is stopped since the
+// Events of the closed windows, with a reading named "<readingname>_<statistic>" for each statistic of each reading
+// name in the window and the Origin set to the window's end, are sent to the remaining pipeline functions when the
+// windows close. Windows which have already ended when the Event is received, i.e. when the pipeline can't be
+// resumed, are closed and their Events returned, as a SplitResult when there is more than one, instead.
+// Readings with non-numeric values are ignored.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (aggregation *Aggregation) Aggregate(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	if edgexcontext.OnShutdown != nil {
+		aggregation.shutdownOnce.Do(func() {
+			edgexcontext.OnShutdown(aggregation.Flush)
+		})
+	}
+
+	aggregation.lock.Lock()
+	defer aggregation.lock.Unlock()
+
+	// Keep the latest, so the windows are sent using the current pipeline
+	aggregation.resume = edgexcontext.ResumePipeline
+
+	now := aggregation.now().UnixNano()
+
+	closed := aggregation.closeWindows(func(end int64) bool { return end <= now })
+
+	deviceWindows, ok := aggregation.windows[event.Device]
+	if !ok {
+		deviceWindows = make(map[int64]window)
+		aggregation.windows[event.Device] = deviceWindows
+	}
+
+	size := int64(aggregation.WindowSize)
+	slide := int64(aggregation.Slide)
+	for _, reading := range event.Readings {
+		value, err := parseReadingValue(reading)
+		if err != nil {
+			continue
+		}
+
+		// Add the value to every window containing the current time
+		for start := now - now%slide; start+size > now; start -= slide {
+			readingWindow, ok := deviceWindows[start]
+			if !ok {
+				readingWindow = make(window)
+				deviceWindows[start] = readingWindow
+			}
+
+			stats, ok := readingWindow[reading.Name]
+			if !ok {
+				stats = &readingStatistics{}
+				readingWindow[reading.Name] = stats
+			}
+
+			stats.add(value)
+		}
+	}
+
+	if len(deviceWindows) == 0 {
+		delete(aggregation.windows, event.Device)
+	}
+
+	aggregation.startTimer(now)
+
+	switch len(closed) {
+	case 0:
+		edgexcontext.LoggingClient.Debug("Aggregating readings, no windows closed")
+		return false, nil
+	case 1:
+		edgexcontext.LoggingClient.Debug("Aggregation window closed")
+		return true, closed[0]
+	default:
+		edgexcontext.LoggingClient.Debug(fmt.Sprintf("%d aggregation windows closed", len(closed)))
+		result := make(appcontext.SplitResult, len(closed))
+		for index, closedEvent := range closed {
+			result[index] = closedEvent
+		}
+		return true, result
+	}
+}
+
+// startTimer starts the timer closing the windows at the next multiple of the slide, when there are open windows
+// and the pipeline can be resumed. The caller must hold the lock.
+func (aggregation *Aggregation) startTimer(now int64) {
+	if aggregation.timer != nil || aggregation.resume == nil || len(aggregation.windows) == 0 {
+		return
+	}
+
+	slide := int64(aggregation.Slide)
+	next := now - now%slide + slide
+	aggregation.timer = aggregation.afterFunc(time.Duration(next-now), aggregation.timerExpired)
+}
+
+// timerExpired closes the windows which have ended and sends their Events to the remaining pipeline functions
+func (aggregation *Aggregation) timerExpired() {
+	aggregation.lock.Lock()
+	aggregation.timer = nil
+	if aggregation.resume == nil {
+		aggregation.lock.Unlock()
+		return
+	}
+
+	now := aggregation.now().UnixNano()
+	closed := aggregation.closeWindows(func(end int64) bool { return end <= now })
+	resume := aggregation.resume
+	aggregation.startTimer(now)
+	aggregation.lock.Unlock()
+
+	for _, event := range closed {
+		resume(event)
+	}
+}
+
+// Flush closes all the open windows, even though they haven't ended, and sends their Events to the remaining
+// pipeline functions now
+func (aggregation *Aggregation) Flush() {
+	aggregation.lock.Lock()
+	if aggregation.timer != nil {
+		aggregation.timer.Stop()
+		aggregation.timer = nil
+	}
+
+	resume := aggregation.resume
+	if resume == nil {
+		aggregation.lock.Unlock()
+		return
+	}
+
+	closed := aggregation.closeWindows(func(int64) bool { return true })
+	aggregation.lock.Unlock()
+
+	for _, event := range closed {
+		resume(event)
+	}
+}
+
+// closeWindows removes the windows of all the devices for which isClosed returns true when passed the window's
+// end and returns an Event for each of them, ordered by the window's end and then device name. The caller must hold
+// the lock.
+func (aggregation *Aggregation) closeWindows(isClosed func(end int64) bool) []models.Event {
+	size := int64(aggregation.WindowSize)
+
+	var events []models.Event
+	for device, deviceWindows := range aggregation.windows {
+		for start, closedWindow := range deviceWindows {
+			end := start + size
+			if !isClosed(end) {
+				continue
+			}
+
+			delete(deviceWindows, start)
+			events = append(events, models.Event{
+				Device:   device,
+				Origin:   end,
+				Readings: aggregation.windowReadings(device, closedWindow, end),
+			})
+		}
+
+		if len(deviceWindows) == 0 {
+			delete(aggregation.windows, device)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Origin != events[j].Origin {
+			return events[i].Origin < events[j].Origin
+		}
+		return events[i].Device < events[j].Device
+	})
+
+	return events
+}
+
+// windowReadings returns a reading for each statistic of each reading name in the window, ordered by reading name
+func (aggregation *Aggregation) windowReadings(device string, closedWindow window, end int64) []models.Reading {
+	names := make([]string, 0, len(closedWindow))
+	for name := range closedWindow {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	readings := make([]models.Reading, 0, len(names)*len(aggregation.Statistics))
+	for _, name := range names {
+		stats := closedWindow[name]
+		for _, statistic := range aggregation.Statistics {
+			reading := models.Reading{
+				Device: device,
+				Name:   name + "_" + statistic,
+				Origin: end,
+			}
+
+			if statistic == StatisticCount {
+				reading.ValueType = models.ValueTypeInt64
+				reading.Value = strconv.FormatInt(stats.count, 10)
+			} else {
+				reading.ValueType = models.ValueTypeFloat64
+				reading.FloatEncoding = models.ENotation
+				reading.Value = strconv.FormatFloat(stats.value(statistic), 'e', -1, 64)
+			}
+
+			readings = append(readings, reading)
+		}
+	}
+
+	return readings
+}
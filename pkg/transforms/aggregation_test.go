@@ -0,0 +1,244 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	current time.Time
+}
+
+func (clock *fakeClock) now() time.Time {
+	return clock.current
+}
+
+// fakeTimers records the functions passed to afterFunc rather than running them, so tests can expire the timers
+type fakeTimers struct {
+	durations []time.Duration
+	functions []func()
+}
+
+func (timers *fakeTimers) afterFunc(duration time.Duration, function func()) *time.Timer {
+	timers.durations = append(timers.durations, duration)
+	timers.functions = append(timers.functions, function)
+	return time.AfterFunc(time.Hour, func() {})
+}
+
+func newAggregationEvent(device string, values ...string) models.Event {
+	event := models.Event{Device: device}
+	for _, value := range values {
+		event.Readings = append(event.Readings, models.Reading{Name: "temperature", Value: value, ValueType: models.ValueTypeInt32})
+	}
+	return event
+}
+
+func readingValues(event models.Event) map[string]string {
+	values := make(map[string]string)
+	for _, reading := range event.Readings {
+		values[reading.Name] = reading.Value
+	}
+	return values
+}
+
+func TestNewAggregationErrors(t *testing.T) {
+	tests := []struct {
+		Name       string
+		WindowSize string
+		Slide      string
+		Statistics []string
+	}{
+		{"Bad window size", "abc", "1m", nil},
+		{"Bad slide", "1m", "abc", nil},
+		{"Zero window size", "0s", "0s", nil},
+		{"Slide larger than window", "1m", "2m", nil},
+		{"Window not multiple of slide", "5m", "2m", nil},
+		{"Unknown statistic", "1m", "1m", []string{"median"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewSlidingWindowAggregation(test.WindowSize, test.Slide, test.Statistics)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAggregateTumblingWindow(t *testing.T) {
+	aggregation, err := NewTumblingWindowAggregation("1m", nil)
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(600, 0)}
+	aggregation.now = clock.now
+
+	continuePipeline, result := aggregation.Aggregate(context, newAggregationEvent(devID1, "10", "20"))
+	assert.False(t, continuePipeline)
+	assert.Nil(t, result)
+
+	clock.current = clock.current.Add(30 * time.Second)
+	continuePipeline, result = aggregation.Aggregate(context, newAggregationEvent(devID1, "30"))
+	assert.False(t, continuePipeline)
+
+	// Non-numeric readings are ignored
+	event := newAggregationEvent(devID1)
+	event.Readings = append(event.Readings, models.Reading{Name: "status", Value: "ok", ValueType: models.ValueTypeString})
+	continuePipeline, _ = aggregation.Aggregate(context, event)
+	assert.False(t, continuePipeline)
+
+	// Other devices have their own windows
+	continuePipeline, _ = aggregation.Aggregate(context, newAggregationEvent(devID2, "1000"))
+	assert.False(t, continuePipeline)
+
+	// Without a timer the next event after the windows' end closes them, one Event per window, and starts the
+	// next window
+	clock.current = time.Unix(660, 0)
+	continuePipeline, result = aggregation.Aggregate(context, newAggregationEvent(devID1, "100"))
+	require.True(t, continuePipeline, result)
+	require.IsType(t, appcontext.SplitResult{}, result)
+	closed := result.(appcontext.SplitResult)
+	require.Len(t, closed, 2)
+	assert.Equal(t, devID2, closed[1].(models.Event).Device)
+	assert.Equal(t, "1", readingValues(closed[1].(models.Event))["temperature_count"])
+
+	aggregated := closed[0].(models.Event)
+	assert.Equal(t, devID1, aggregated.Device)
+	assert.Equal(t, clock.current.UnixNano(), aggregated.Origin)
+	assert.Equal(t, map[string]string{
+		"temperature_min":   "1e+01",
+		"temperature_max":   "3e+01",
+		"temperature_avg":   "2e+01",
+		"temperature_count": "3",
+		"temperature_sum":   "6e+01",
+	}, readingValues(aggregated))
+
+	for _, reading := range aggregated.Readings {
+		assert.Equal(t, devID1, reading.Device)
+		assert.Equal(t, time.Unix(660, 0).UnixNano(), reading.Origin)
+	}
+
+	clock.current = time.Unix(720, 0)
+	continuePipeline, result = aggregation.Aggregate(context, newAggregationEvent(devID1))
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, "1", readingValues(result.(models.Event))["temperature_count"])
+}
+
+func TestAggregateSlidingWindow(t *testing.T) {
+	aggregation, err := NewSlidingWindowAggregation("2m", "1m", []string{StatisticCount, StatisticSum})
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(630, 0)}
+	aggregation.now = clock.now
+
+	// Belongs to the windows [540, 660) and [600, 720)
+	continuePipeline, _ := aggregation.Aggregate(context, newAggregationEvent(devID1, "1"))
+	assert.False(t, continuePipeline)
+
+	// Belongs to the windows [600, 720) and [660, 780), and closes [540, 660)
+	clock.current = time.Unix(690, 0)
+	continuePipeline, result := aggregation.Aggregate(context, newAggregationEvent(devID1, "2"))
+	require.True(t, continuePipeline, result)
+	aggregated := result.(models.Event)
+	require.Len(t, aggregated.Readings, 2)
+	assert.Equal(t, "temperature_count", aggregated.Readings[0].Name)
+	assert.Equal(t, map[string]string{"temperature_count": "1", "temperature_sum": "1e+00"}, readingValues(aggregated))
+
+	// Closes [600, 720) and [660, 780), which are emitted in order as an event each
+	clock.current = time.Unix(800, 0)
+	continuePipeline, result = aggregation.Aggregate(context, newAggregationEvent(devID1))
+	require.True(t, continuePipeline, result)
+	require.IsType(t, appcontext.SplitResult{}, result)
+	closed := result.(appcontext.SplitResult)
+	require.Len(t, closed, 2)
+
+	aggregated = closed[0].(models.Event)
+	assert.Equal(t, time.Unix(720, 0).UnixNano(), aggregated.Origin)
+	assert.Equal(t, map[string]string{"temperature_count": "2", "temperature_sum": "3e+00"}, readingValues(aggregated))
+
+	aggregated = closed[1].(models.Event)
+	assert.Equal(t, time.Unix(780, 0).UnixNano(), aggregated.Origin)
+	assert.Equal(t, map[string]string{"temperature_count": "1", "temperature_sum": "2e+00"}, readingValues(aggregated))
+}
+
+func TestAggregateTimer(t *testing.T) {
+	aggregation, err := NewTumblingWindowAggregation("1m", []string{StatisticCount})
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(630, 0)}
+	aggregation.now = clock.now
+	timers := &fakeTimers{}
+	aggregation.afterFunc = timers.afterFunc
+
+	edgexcontext, resumed := newResumableContext()
+	var shutdownHandlers []func()
+	edgexcontext.OnShutdown = func(handler func()) {
+		shutdownHandlers = append(shutdownHandlers, handler)
+	}
+
+	continuePipeline, _ := aggregation.Aggregate(edgexcontext, newAggregationEvent(devID1, "1"))
+	assert.False(t, continuePipeline)
+	continuePipeline, _ = aggregation.Aggregate(edgexcontext, newAggregationEvent(devID2, "2", "3"))
+	assert.False(t, continuePipeline)
+	assert.Len(t, shutdownHandlers, 1, "shutdown handler should be registered once")
+	require.Equal(t, []time.Duration{30 * time.Second}, timers.durations, "timer should expire at the window's end")
+
+	// The timer closes the windows of all the devices, one Event per window
+	clock.current = time.Unix(660, 0)
+	timers.functions[0]()
+	require.Len(t, resumed, 2)
+	aggregated := (<-resumed).(models.Event)
+	assert.Equal(t, devID1, aggregated.Device)
+	assert.Equal(t, time.Unix(660, 0).UnixNano(), aggregated.Origin)
+	assert.Equal(t, "1", readingValues(aggregated)["temperature_count"])
+	aggregated = (<-resumed).(models.Event)
+	assert.Equal(t, devID2, aggregated.Device)
+	assert.Equal(t, "2", readingValues(aggregated)["temperature_count"])
+	assert.Len(t, timers.durations, 1, "timer should not be restarted without open windows")
+
+	clock.current = time.Unix(670, 0)
+	continuePipeline, _ = aggregation.Aggregate(edgexcontext, newAggregationEvent(devID1, "4"))
+	assert.False(t, continuePipeline)
+	require.Len(t, timers.durations, 2)
+	assert.Equal(t, 50*time.Second, timers.durations[1])
+
+	// The open windows are sent on shutdown
+	shutdownHandlers[0]()
+	require.Len(t, resumed, 1)
+	aggregated = (<-resumed).(models.Event)
+	assert.Equal(t, devID1, aggregated.Device)
+	assert.Equal(t, time.Unix(720, 0).UnixNano(), aggregated.Origin)
+	assert.Nil(t, aggregation.timer, "timer should be stopped")
+
+	shutdownHandlers[0]()
+	assert.Len(t, resumed, 0)
+}
+
+func TestAggregateErrors(t *testing.T) {
+	aggregation, err := NewTumblingWindowAggregation("1m", nil)
+	require.NoError(t, err)
+
+	continuePipeline, result := aggregation.Aggregate(context)
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "No Event Received", result.(error).Error())
+
+	continuePipeline, result = aggregation.Aggregate(context, "data")
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "Unexpected type received", result.(error).Error())
+}
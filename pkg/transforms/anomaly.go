@@ -0,0 +1,395 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+// Methods used by AnomalyDetector to decide whether a reading value is an anomaly
+const (
+	// AnomalyMethodZScore flags values more than Threshold standard deviations from the mean of the last Samples values
+	AnomalyMethodZScore = "zscore"
+	// AnomalyMethodEWMA flags values deviating more than Threshold from the exponentially weighted moving average
+	AnomalyMethodEWMA = "ewma"
+)
+
+// Actions taken by AnomalyDetector when anomalies are detected
+const (
+	// AnomalyActionTag adds the AnomalyTag to the Event, listing the names of the anomalous readings
+	AnomalyActionTag = "tag"
+	// AnomalyActionFilter removes the readings which are not anomalies, dropping Events without anomalies
+	AnomalyActionFilter = "filter"
+	// AnomalyActionNotify sends a notification to the support notifications service
+	AnomalyActionNotify = "notify"
+)
+
+// AnomalyTag is the tag added to Events with anomalies, its value is the comma separated names of the anomalous readings
+const AnomalyTag = "anomaly"
+
+// Defaults used by NewAnomalyDetector when the value is zero
+const (
+	DefaultAnomalySamples    = 100
+	DefaultAnomalyMinSamples = 10
+	DefaultAnomalyAlpha      = 0.3
+	// DefaultAnomalyNotificationCooldown is the default minimum time between the notifications sent for a device
+	DefaultAnomalyNotificationCooldown = time.Minute
+)
+
+// anomalyMinStdDevRatio is the minimum standard deviation, relative to the magnitude of the mean or one when it
+// is smaller, used by the z-score method when MinStdDev is zero
+const anomalyMinStdDevRatio = 0.01
+
+// anomalyNotificationSender is the Sender of the notifications sent for anomalies
+const anomalyNotificationSender = "app-functions-sdk anomaly detection"
+
+// readingHistory holds the rolling statistics of a device's reading
+type readingHistory struct {
+	// values is a ring buffer of the last values, next is where the next value goes once it is full
+	values []float64
+	next   int
+	count  int64
+	ewma   float64
+}
+
+func (history *readingHistory) add(value float64, samples int, alpha float64) {
+	if history.count == 0 {
+		history.ewma = value
+	} else {
+		history.ewma = alpha*value + (1-alpha)*history.ewma
+	}
+	history.count++
+
+	if len(history.values) < samples {
+		history.values = append(history.values, value)
+		return
+	}
+
+	history.values[history.next] = value
+	history.next = (history.next + 1) % samples
+}
+
+// meanAndStdDev returns the mean and population standard deviation of the values
+func (history *readingHistory) meanAndStdDev() (float64, float64) {
+	sum := 0.0
+	for _, value := range history.values {
+		sum += value
+	}
+	mean := sum / float64(len(history.values))
+
+	squares := 0.0
+	for _, value := range history.values {
+		squares += (value - mean) * (value - mean)
+	}
+
+	return mean, math.Sqrt(squares / float64(len(history.values)))
+}
+
+// AnomalyDetector keeps rolling statistics of the numeric reading values, keyed by device and reading name, and
+// detects values which are anomalies using the Method. Values aren't flagged until MinSamples values have been seen
+// for the device's reading. All values, including anomalies, are added to the statistics after being checked.
+type AnomalyDetector struct {
+	Method    string
+	Threshold float64
+	Action    string
+	// Samples is the number of recent values the mean and standard deviation are calculated from
+	Samples int
+	// MinSamples is the number of values to be seen before values are checked
+	MinSamples int
+	// Alpha is the weight of the newest value in the EWMA, between 0 and 1
+	Alpha float64
+	// MinStdDev is the minimum standard deviation used by the z-score method, so a small change of values which
+	// have been almost constant isn't an anomaly. When zero it is 1% of the magnitude of the mean.
+	MinStdDev float64
+	// NotificationCooldown is the minimum time between the notifications sent for a device by the notify action
+	NotificationCooldown time.Duration
+
+	// history is keyed by device name and reading name
+	history map[string]*readingHistory
+	// notified is when a notification was last sent for each device
+	notified map[string]time.Time
+	lock     sync.Mutex
+	now      func() time.Time
+}
+
+// NewAnomalyDetector creates, initializes and returns a new instance of AnomalyDetector. The method is one of zscore
+// or ewma and the action one of tag, filter or notify. Zero samples, minSamples and alpha use the defaults.
+func NewAnomalyDetector(method string, threshold float64, action string, samples int, minSamples int, alpha float64) (*AnomalyDetector, error) {
+	switch method {
+	case AnomalyMethodZScore, AnomalyMethodEWMA:
+	default:
+		return nil, fmt.Errorf("unknown anomaly detection method '%s'", method)
+	}
+
+	switch action {
+	case AnomalyActionTag, AnomalyActionFilter, AnomalyActionNotify:
+	default:
+		return nil, fmt.Errorf("unknown anomaly action '%s'", action)
+	}
+
+	if threshold <= 0 || math.IsNaN(threshold) || math.IsInf(threshold, 0) {
+		return nil, fmt.Errorf("invalid anomaly threshold %v, must be a finite number greater than zero", threshold)
+	}
+
+	if samples < 0 || minSamples < 0 {
+		return nil, errors.New("anomaly samples must not be negative")
+	}
+
+	if alpha < 0 || alpha > 1 || math.IsNaN(alpha) {
+		return nil, fmt.Errorf("invalid anomaly alpha %v, must be between 0 and 1", alpha)
+	}
+
+	if samples == 0 {
+		samples = DefaultAnomalySamples
+	}
+
+	if minSamples == 0 {
+		minSamples = DefaultAnomalyMinSamples
+	}
+
+	if alpha == 0 {
+		alpha = DefaultAnomalyAlpha
+	}
+
+	return &AnomalyDetector{
+		Method:     method,
+		Threshold:  threshold,
+		Action:     action,
+		Samples:    samples,
+		MinSamples: minSamples,
+		Alpha:      alpha,
+
+		NotificationCooldown: DefaultAnomalyNotificationCooldown,
+		history:              make(map[string]*readingHistory),
+		notified:             make(map[string]time.Time),
+		now:                  time.Now,
+	}, nil
+}
+
+// SetMinStdDev sets the minimum standard deviation used by the z-score method, zero uses 1% of the mean's magnitude
+func (detector *AnomalyDetector) SetMinStdDev(minStdDev float64) error {
+	if minStdDev < 0 || math.IsNaN(minStdDev) || math.IsInf(minStdDev, 0) {
+		return fmt.Errorf("invalid anomaly minimum standard deviation %v, must be a finite number not less than zero", minStdDev)
+	}
+
+	detector.MinStdDev = minStdDev
+	return nil
+}
+
+// SetNotificationCooldown sets the minimum time between the notifications sent for a device, i.e. "5m". Zero sends
+// a notification for every Event with anomalies.
+func (detector *AnomalyDetector) SetNotificationCooldown(cooldown string) error {
+	parsedCooldown, err := time.ParseDuration(cooldown)
+	if err != nil {
+		return fmt.Errorf("invalid anomaly notification cooldown '%s': %s", cooldown, err.Error())
+	}
+
+	if parsedCooldown < 0 {
+		return errors.New("anomaly notification cooldown must not be negative")
+	}
+
+	detector.NotificationCooldown = parsedCooldown
+	return nil
+}
+
+// InheritHistory carries the rolling statistics and notification cooldowns over from the previous detector, i.e.
+// the one it replaces with a new Threshold, so detection continues without seeing MinSamples values again. Nothing
+// is carried over when the previous detector calculates its statistics with other Samples or Alpha.
+func (detector *AnomalyDetector) InheritHistory(previous *AnomalyDetector) {
+	if previous == nil || previous == detector || previous.Samples != detector.Samples || previous.Alpha != detector.Alpha {
+		return
+	}
+
+	// Copied, so the previous detector still processing Events doesn't share the statistics
+	previous.lock.Lock()
+	history := make(map[string]*readingHistory, len(previous.history))
+	for key, readings := range previous.history {
+		copied := *readings
+		copied.values = append([]float64(nil), readings.values...)
+		history[key] = &copied
+	}
+	notified := make(map[string]time.Time, len(previous.notified))
+	for device, sent := range previous.notified {
+		notified[device] = sent
+	}
+	previous.lock.Unlock()
+
+	detector.lock.Lock()
+	detector.history = history
+	detector.notified = notified
+	detector.lock.Unlock()
+}
+
+// DetectAnomalies checks each numeric reading value of the Event against the rolling statistics of the device's
+// reading and takes the Action for the anomalies found. Events without anomalies are passed on unchanged, except
+// with the filter action when they are dropped. With the notify action at most one notification is sent per device
+// every NotificationCooldown. A notification that can't be sent is logged and doesn't stop the pipeline.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (detector *AnomalyDetector) DetectAnomalies(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	edgexcontext.LoggingClient.Debug("Detecting Anomalies")
+
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	var anomalies []models.Reading
+	var descriptions []string
+
+	detector.lock.Lock()
+	for _, reading := range event.Readings {
+		value, err := parseReadingValue(reading)
+		if err != nil {
+			continue
+		}
+
+		key := event.Device + "/" + reading.Name
+		history, ok := detector.history[key]
+		if !ok {
+			history = &readingHistory{}
+			detector.history[key] = history
+		}
+
+		if description, anomaly := detector.check(history, value); anomaly {
+			anomalies = append(anomalies, reading)
+			descriptions = append(descriptions, fmt.Sprintf("%s value %s %s", reading.Name, reading.Value, description))
+		}
+
+		history.add(value, detector.Samples, detector.Alpha)
+	}
+	detector.lock.Unlock()
+
+	if len(anomalies) == 0 {
+		if detector.Action == AnomalyActionFilter {
+			return false, nil
+		}
+		return true, event
+	}
+
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Detected %d anomalies for device %s", len(anomalies), event.Device))
+
+	switch detector.Action {
+	case AnomalyActionFilter:
+		event.Readings = anomalies
+
+	case AnomalyActionNotify:
+		if !detector.notificationDue(event.Device) {
+			edgexcontext.LoggingClient.Debug(fmt.Sprintf("Anomaly notification for device %s skipped during cooldown", event.Device))
+			break
+		}
+
+		if err := detector.notify(edgexcontext, event, descriptions); err != nil {
+			edgexcontext.LoggingClient.Error(err.Error())
+			// The next anomalies are notified rather than waiting for the cooldown of a notification never sent
+			detector.lock.Lock()
+			delete(detector.notified, event.Device)
+			detector.lock.Unlock()
+		}
+
+	default:
+		names := make([]string, len(anomalies))
+		for index, reading := range anomalies {
+			names[index] = reading.Name
+		}
+
+		event.Tags = copyTags(event.Tags)
+		if event.Tags == nil {
+			event.Tags = make(map[string]string)
+		}
+		event.Tags[AnomalyTag] = strings.Join(names, ",")
+	}
+
+	return true, event
+}
+
+// check returns whether the value is an anomaly for the history, along with a description of why
+func (detector *AnomalyDetector) check(history *readingHistory, value float64) (string, bool) {
+	if history.count < int64(detector.MinSamples) {
+		return "", false
+	}
+
+	if detector.Method == AnomalyMethodEWMA {
+		deviation := math.Abs(value - history.ewma)
+		return fmt.Sprintf("deviates %.4g from the moving average %.4g", deviation, history.ewma),
+			deviation > detector.Threshold
+	}
+
+	mean, stdDev := history.meanAndStdDev()
+	minStdDev := detector.MinStdDev
+	if minStdDev == 0 {
+		minStdDev = anomalyMinStdDevRatio * math.Max(math.Abs(mean), 1)
+	}
+	stdDev = math.Max(stdDev, minStdDev)
+
+	zScore := math.Abs(value-mean) / stdDev
+	return fmt.Sprintf("has a z-score of %.4g from the mean %.4g", zScore, mean),
+		zScore > detector.Threshold
+}
+
+// notificationDue returns whether the NotificationCooldown has passed since the last notification for the device,
+// in which case the time of the notification about to be sent is recorded
+func (detector *AnomalyDetector) notificationDue(device string) bool {
+	detector.lock.Lock()
+	defer detector.lock.Unlock()
+
+	now := detector.now()
+	if last, ok := detector.notified[device]; ok && now.Sub(last) < detector.NotificationCooldown {
+		return false
+	}
+
+	detector.notified[device] = now
+	return true
+}
+
+func (detector *AnomalyDetector) notify(edgexcontext *appcontext.Context, event models.Event, descriptions []string) error {
+	if edgexcontext.NotificationsClient == nil {
+		return fmt.Errorf("unable to send anomaly notification: '%s' is missing from Clients configuration", common.NotificationsClientName)
+	}
+
+	notification := notifications.Notification{
+		Slug:        fmt.Sprintf("anomaly-%s-%d", event.Device, time.Now().UnixNano()),
+		Sender:      anomalyNotificationSender,
+		Category:    notifications.HW_HEALTH,
+		Severity:    notifications.CRITICAL,
+		Content:     fmt.Sprintf("Anomalies detected for device %s: %s", event.Device, strings.Join(descriptions, "; ")),
+		Description: "Reading values detected as anomalies",
+		Labels:      []string{AnomalyTag, event.Device},
+	}
+
+	ctx := syscontext.WithValue(syscontext.Background(), clients.CorrelationHeader, edgexcontext.CorrelationID)
+	if err := edgexcontext.NotificationsClient.SendNotification(ctx, notification); err != nil {
+		return fmt.Errorf("unable to send anomaly notification: %s", err.Error())
+	}
+
+	return nil
+}
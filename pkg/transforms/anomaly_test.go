@@ -0,0 +1,287 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotificationsClient struct {
+	sent []notifications.Notification
+	err  error
+}
+
+func (client *fakeNotificationsClient) SendNotification(_ syscontext.Context, n notifications.Notification) error {
+	client.sent = append(client.sent, n)
+	return client.err
+}
+
+func newAnomalyEvent(device string, temperature string, humidity string) models.Event {
+	return models.Event{
+		Device: device,
+		Readings: []models.Reading{
+			{Device: device, Name: "temperature", Value: temperature, ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation},
+			{Device: device, Name: "humidity", Value: humidity, ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation},
+			{Device: device, Name: "status", Value: "on", ValueType: models.ValueTypeString},
+		},
+	}
+}
+
+// warmUp sends events with temperatures alternating between 19 and 21 and a steady humidity of 40
+func warmUp(t *testing.T, detector *AnomalyDetector, count int) {
+	warmUpDevice(t, detector, devID1, count)
+}
+
+// warmUpDevice sends the device's events with temperatures alternating between 19 and 21 and a steady humidity of 40
+func warmUpDevice(t *testing.T, detector *AnomalyDetector, device string, count int) {
+	values := []string{"19", "21"}
+	for i := 0; i < count; i++ {
+		continuePipeline, result := detector.DetectAnomalies(context, newAnomalyEvent(device, values[i%2], "40"))
+		if detector.Action != AnomalyActionFilter {
+			require.True(t, continuePipeline)
+			assert.NotContains(t, result.(models.Event).Tags, AnomalyTag, "value %d should not be an anomaly", i)
+		}
+	}
+}
+
+func TestNewAnomalyDetectorErrors(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Method     string
+		Threshold  float64
+		Action     string
+		Samples    int
+		MinSamples int
+		Alpha      float64
+	}{
+		{"Bad method", "mad", 3, AnomalyActionTag, 0, 0, 0},
+		{"Bad action", AnomalyMethodZScore, 3, "drop", 0, 0, 0},
+		{"Zero threshold", AnomalyMethodZScore, 0, AnomalyActionTag, 0, 0, 0},
+		{"Negative samples", AnomalyMethodZScore, 3, AnomalyActionTag, -1, 0, 0},
+		{"Negative min samples", AnomalyMethodZScore, 3, AnomalyActionTag, 0, -1, 0},
+		{"Alpha over one", AnomalyMethodEWMA, 3, AnomalyActionTag, 0, 0, 1.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewAnomalyDetector(test.Method, test.Threshold, test.Action, test.Samples, test.MinSamples, test.Alpha)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewAnomalyDetectorDefaults(t *testing.T) {
+	detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionTag, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAnomalySamples, detector.Samples)
+	assert.Equal(t, DefaultAnomalyMinSamples, detector.MinSamples)
+	assert.Equal(t, DefaultAnomalyAlpha, detector.Alpha)
+}
+
+func TestAnomalyDetectorSetterErrors(t *testing.T) {
+	detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionNotify, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAnomalyNotificationCooldown, detector.NotificationCooldown)
+
+	assert.Error(t, detector.SetMinStdDev(-1))
+	assert.Error(t, detector.SetMinStdDev(math.Inf(1)))
+	assert.Error(t, detector.SetNotificationCooldown("soon"))
+	assert.Error(t, detector.SetNotificationCooldown("-1m"))
+	require.NoError(t, detector.SetNotificationCooldown("0s"))
+	assert.Equal(t, time.Duration(0), detector.NotificationCooldown)
+}
+
+func TestDetectAnomaliesTag(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Method    string
+		Threshold float64
+	}{
+		{"Z-score", AnomalyMethodZScore, 3},
+		{"EWMA", AnomalyMethodEWMA, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			detector, err := NewAnomalyDetector(test.Method, test.Threshold, AnomalyActionTag, 20, 10, 0)
+			require.NoError(t, err)
+			warmUp(t, detector, 10)
+
+			// Within the normal range
+			continuePipeline, result := detector.DetectAnomalies(context, newAnomalyEvent(devID1, "22", "40"))
+			require.True(t, continuePipeline)
+			assert.NotContains(t, result.(models.Event).Tags, AnomalyTag)
+
+			continuePipeline, result = detector.DetectAnomalies(context, newAnomalyEvent(devID1, "35", "40"))
+			require.True(t, continuePipeline)
+			event := result.(models.Event)
+			assert.Equal(t, "temperature", event.Tags[AnomalyTag])
+			assert.Len(t, event.Readings, 3, "readings should not be removed when tagging")
+
+			// Statistics are kept per device
+			continuePipeline, result = detector.DetectAnomalies(context, newAnomalyEvent(devID2, "35", "40"))
+			require.True(t, continuePipeline)
+			assert.NotContains(t, result.(models.Event).Tags, AnomalyTag, "first values of another device should not be checked")
+		})
+	}
+}
+
+func TestDetectAnomaliesConstantValues(t *testing.T) {
+	tests := []struct {
+		Name      string
+		MinStdDev float64
+		Humidity  string
+		Anomaly   bool
+	}{
+		{"Small change within default minimum", 0, "41", false},
+		{"Large change beyond default minimum", 0, "45", true},
+		{"Small change beyond configured minimum", 0.1, "41", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionTag, 0, 5, 0)
+			require.NoError(t, err)
+			require.NoError(t, detector.SetMinStdDev(test.MinStdDev))
+			warmUp(t, detector, 5)
+
+			continuePipeline, result := detector.DetectAnomalies(context, newAnomalyEvent(devID1, "20", test.Humidity))
+			require.True(t, continuePipeline)
+			if test.Anomaly {
+				assert.Equal(t, "humidity", result.(models.Event).Tags[AnomalyTag])
+			} else {
+				assert.NotContains(t, result.(models.Event).Tags, AnomalyTag)
+			}
+		})
+	}
+}
+
+func TestInheritHistory(t *testing.T) {
+	previous, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionTag, 0, 10, 0)
+	require.NoError(t, err)
+	warmUp(t, previous, 10)
+
+	tests := []struct {
+		Name    string
+		Samples int
+		Anomaly bool
+	}{
+		{"Same statistics", 0, true},
+		{"Other samples", 50, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			detector, err := NewAnomalyDetector(AnomalyMethodZScore, 5, AnomalyActionTag, test.Samples, 10, 0)
+			require.NoError(t, err)
+			detector.InheritHistory(previous)
+
+			continuePipeline, result := detector.DetectAnomalies(context, newAnomalyEvent(devID1, "35", "40"))
+			require.True(t, continuePipeline)
+			if test.Anomaly {
+				assert.Equal(t, "temperature", result.(models.Event).Tags[AnomalyTag])
+			} else {
+				assert.NotContains(t, result.(models.Event).Tags, AnomalyTag)
+			}
+		})
+	}
+
+	// The previous detector's statistics aren't changed by the detector inheriting them
+	assert.Equal(t, int64(10), previous.history[devID1+"/temperature"].count)
+}
+
+func TestDetectAnomaliesFilter(t *testing.T) {
+	detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionFilter, 0, 10, 0)
+	require.NoError(t, err)
+	warmUp(t, detector, 10)
+
+	continuePipeline, result := detector.DetectAnomalies(context, newAnomalyEvent(devID1, "20", "40"))
+	assert.False(t, continuePipeline, "event without anomalies should be dropped")
+	assert.Nil(t, result)
+
+	continuePipeline, result = detector.DetectAnomalies(context, newAnomalyEvent(devID1, "-10", "40"))
+	require.True(t, continuePipeline)
+	assert.Equal(t, []string{"temperature"}, readingNames(result.(models.Event)))
+}
+
+func TestDetectAnomaliesNotify(t *testing.T) {
+	detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionNotify, 0, 10, 0)
+	require.NoError(t, err)
+	warmUp(t, detector, 10)
+
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	detector.now = clock.now
+
+	client := &fakeNotificationsClient{}
+	edgexcontext := &appcontext.Context{LoggingClient: logClient, NotificationsClient: client, CorrelationID: "123"}
+	event := newAnomalyEvent(devID1, "50", "40")
+
+	continuePipeline, result := detector.DetectAnomalies(edgexcontext, event)
+	require.True(t, continuePipeline)
+	assert.Equal(t, event, result, "event should be passed on unchanged")
+	require.Len(t, client.sent, 1)
+	assert.Equal(t, notifications.CRITICAL, client.sent[0].Severity)
+	assert.Contains(t, client.sent[0].Content, devID1)
+	assert.Contains(t, client.sent[0].Content, "temperature value 50")
+
+	// Further anomalies of the device aren't notified during the cooldown, unlike those of other devices
+	warmUpDevice(t, detector, devID2, 10)
+	clock.current = clock.current.Add(DefaultAnomalyNotificationCooldown - time.Second)
+	detector.DetectAnomalies(edgexcontext, newAnomalyEvent(devID1, "-50", "40"))
+	detector.DetectAnomalies(edgexcontext, newAnomalyEvent(devID2, "50", "40"))
+	require.Len(t, client.sent, 2)
+	assert.Contains(t, client.sent[1].Content, devID2)
+
+	clock.current = clock.current.Add(time.Second)
+	detector.DetectAnomalies(edgexcontext, newAnomalyEvent(devID1, "-50", "40"))
+	require.Len(t, client.sent, 3, "anomalies should be notified once the cooldown has passed")
+
+	// Notification failures don't stop the pipeline, nor start a cooldown
+	clock.current = clock.current.Add(DefaultAnomalyNotificationCooldown)
+	client.err = errors.New("unavailable")
+	continuePipeline, _ = detector.DetectAnomalies(edgexcontext, newAnomalyEvent(devID1, "500", "40"))
+	assert.True(t, continuePipeline)
+	client.err = nil
+	detector.DetectAnomalies(edgexcontext, newAnomalyEvent(devID1, "-5000", "40"))
+	assert.Len(t, client.sent, 5)
+
+	continuePipeline, _ = detector.DetectAnomalies(context, newAnomalyEvent(devID1, "-80", "40"))
+	assert.True(t, continuePipeline, "missing notifications client should not stop the pipeline")
+}
+
+func TestDetectAnomaliesErrors(t *testing.T) {
+	detector, err := NewAnomalyDetector(AnomalyMethodZScore, 3, AnomalyActionTag, 0, 0, 0)
+	require.NoError(t, err)
+
+	continuePipeline, result := detector.DetectAnomalies(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = detector.DetectAnomalies(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
@@ -17,12 +17,15 @@
 package transforms
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
-	"github.com/jcerato/app-functions-sdk-go/internal/common"
 	"github.com/jcerato/app-functions-sdk-go/pkg/util"
 )
 
@@ -33,17 +36,20 @@ const (
 	BatchByCountOnly = iota
 	BatchByTimeOnly
 	BatchByTimeAndCount
+	BatchBySizeOnly
 )
 
 type atomicBatchData struct {
 	mutex sync.Mutex
 	data  [][]byte
+	size  int
 }
 
 func (d *atomicBatchData) append(toBeAdded []byte) [][]byte {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	d.data = append(d.data, toBeAdded)
+	d.size += len(toBeAdded)
 	result := d.data
 	return result
 }
@@ -59,6 +65,7 @@ func (d *atomicBatchData) removeAll() {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	d.data = nil
+	d.size = 0
 }
 
 func (d *atomicBatchData) length() int {
@@ -68,16 +75,46 @@ func (d *atomicBatchData) length() int {
 	return result
 }
 
+func (d *atomicBatchData) byteSize() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	result := d.size
+	return result
+}
+
 // BatchConfig ...
 type BatchConfig struct {
-	timeInterval                string
-	parsedDuration              time.Duration
-	batchThreshold              int
-	batchMode                   BatchMode
-	batchData                   atomicBatchData
-	continuedPipelineTransforms []appcontext.AppFunction
-	timerActive                 common.AtomicBool
-	done                        chan bool
+	timeInterval   string
+	parsedDuration time.Duration
+	batchThreshold int
+	// byteThreshold is the total size in bytes of the batched data at which the batch is sent, zero disables it
+	byteThreshold int
+	batchMode     BatchMode
+	batchData     atomicBatchData
+	// lock serializes adding to the batch with sending it, so each item is sent exactly once
+	lock sync.Mutex
+	// timer is running while a time based batch is being collected, it sends the batch when it expires
+	timer *time.Timer
+	// sent counts the batches sent, so an expiring timer doesn't send the next batch when its own was already sent
+	sent uint64
+	// started is when the first item of the current batch was added
+	started int64
+	// overdue is set when the timer expires before the pipeline can be resumed, i.e. for a batch restored on
+	// startup, so the batch is sent with the next data received
+	overdue bool
+	// closed is set once the batch is closed, after which data is sent on as it is received
+	closed bool
+	// store persists the batched items when persistence is enabled
+	store    batchStore
+	sequence uint64
+	// persisted are the sequences of the persisted items of the current batch
+	persisted []uint64
+	// resume executes the rest of the pipeline for a batch sent when the timer expires or on shutdown
+	resume        func(result interface{}) error
+	loggingClient logger.LoggingClient
+	shutdownOnce  sync.Once
+	afterFunc     func(duration time.Duration, function func()) *time.Timer
+	now           func() time.Time
 }
 
 // NewBatchByTime create, initializes  and returns a new instance for BatchConfig
@@ -85,13 +122,14 @@ func NewBatchByTime(timeInterval string) (*BatchConfig, error) {
 	config := BatchConfig{
 		timeInterval: timeInterval,
 		batchMode:    BatchByTimeOnly, //Default to CountAndTime
+		afterFunc:    time.AfterFunc,
+		now:          time.Now,
 	}
 	var err error
 	config.parsedDuration, err = time.ParseDuration(config.timeInterval)
 	if err != nil {
 		return nil, err
 	}
-	config.done = make(chan bool)
 
 	return &config, nil
 }
@@ -101,6 +139,8 @@ func NewBatchByCount(batchThreshold int) (*BatchConfig, error) {
 	config := BatchConfig{
 		batchThreshold: batchThreshold,
 		batchMode:      BatchByCountOnly, //Default to CountAndTime
+		afterFunc:      time.AfterFunc,
+		now:            time.Now,
 	}
 
 	return &config, nil
@@ -112,18 +152,117 @@ func NewBatchByTimeAndCount(timeInterval string, batchThreshold int) (*BatchConf
 		timeInterval:   timeInterval,
 		batchThreshold: batchThreshold,
 		batchMode:      BatchByTimeAndCount, //Default to CountAndTime
+		afterFunc:      time.AfterFunc,
+		now:            time.Now,
 	}
 	var err error
 	config.parsedDuration, err = time.ParseDuration(config.timeInterval)
 	if err != nil {
 		return nil, err
 	}
-	config.done = make(chan bool)
 
 	return &config, nil
 }
 
-// Batch ...
+// NewBatchBySize create, initializes  and returns a new instance for BatchConfig which sends the batch once the
+// total size of the batched data reaches the byteThreshold
+func NewBatchBySize(byteThreshold int) (*BatchConfig, error) {
+	config := BatchConfig{
+		batchMode: BatchBySizeOnly,
+		afterFunc: time.AfterFunc,
+		now:       time.Now,
+	}
+
+	if err := config.SetByteThreshold(byteThreshold); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// SetByteThreshold sets the total size in bytes of the batched data at which the batch is sent, in addition to the
+// count and time thresholds of the batch mode
+func (batch *BatchConfig) SetByteThreshold(byteThreshold int) error {
+	if byteThreshold <= 0 {
+		return fmt.Errorf("invalid batch byte threshold %d, must be greater than zero", byteThreshold)
+	}
+
+	batch.byteThreshold = byteThreshold
+	return nil
+}
+
+// SetPersistence persists the batched data to the PersistentStore, i.e. the Context's PersistentStore, under the
+// storeKey as it is added, so it survives restarts, and restores the batch persisted before the service restarted.
+// The storeKey must not be the service key, which is used by Store and Forward for the data to retry.
+func (batch *BatchConfig) SetPersistence(store appcontext.PersistentStore, storeKey string) error {
+	if store == nil {
+		return errors.New("batch persistence requires StoreAndForward to be enabled")
+	}
+
+	if len(storeKey) == 0 {
+		return errors.New("batch persistence requires a store key")
+	}
+
+	return batch.restore(&persistentBatchStore{store: store, storeKey: storeKey, ids: make(map[uint64]string)})
+}
+
+// SetPersistenceFile persists the batched data to the local file at the path as it is added, so it survives
+// restarts, and restores the batch persisted before the service restarted
+func (batch *BatchConfig) SetPersistenceFile(path string) error {
+	if len(path) == 0 {
+		return errors.New("batch persistence requires a file path")
+	}
+
+	return batch.restore(&fileBatchStore{path: path})
+}
+
+// restore loads the persisted batch and resumes its timer, so the batch is sent when its interval expires as
+// if the service hadn't restarted
+func (batch *BatchConfig) restore(store batchStore) error {
+	items, err := store.load()
+	if err != nil {
+		return fmt.Errorf("unable to restore persisted batch: %s", err.Error())
+	}
+
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+
+	batch.store = store
+	if len(items) == 0 {
+		return nil
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Sequence < items[j].Sequence })
+
+	batch.started = items[0].Started
+	for _, item := range items {
+		batch.batchData.append(item.Data)
+		batch.persisted = append(batch.persisted, item.Sequence)
+	}
+	batch.sequence = items[len(items)-1].Sequence + 1
+
+	if batch.batchMode == BatchByTimeOnly || batch.batchMode == BatchByTimeAndCount {
+		remaining := time.Duration(batch.started + int64(batch.parsedDuration) - batch.now().UnixNano())
+		if remaining < 0 {
+			remaining = 0
+		}
+		batch.startTimer(remaining)
+	}
+
+	return nil
+}
+
+// Batch adds the data to the batch and sends the batch on thru the pipeline, as a JSON array of the batched items,
+// once the count or byte threshold is reached. In the time based modes the first item of a batch starts a timer
+// and when the interval expires the batch is sent to the remaining pipeline functions in the background, so the
+// calling goroutine is never blocked waiting for the batch. Batched data is also sent when the service shuts down.
+// Items which are not valid JSON are added to the array as JSON strings. When persistence is enabled each item is
+// also persisted as it is added, and only removed once the remaining pipeline functions have processed the batch
+// successfully or it has been stored for retry. To know that, a batch reaching its threshold is then sent to the
+// remaining functions using the Context's ResumePipeline rather than returned, so the trigger doesn't respond with it.
+// A batch which fails remains persisted and is sent again once the service restarts. When the trigger can't publish
+// the output of a resumed pipeline, i.e. the HTTP trigger, a batch reaching its threshold is returned as without
+// persistence, so its output is the response to the request, and its persisted items are removed.
 func (batch *BatchConfig) Batch(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
@@ -135,51 +274,195 @@ func (batch *BatchConfig) Batch(edgexcontext *appcontext.Context, params ...inte
 	if err != nil {
 		return false, err
 	}
+
+	if edgexcontext.OnShutdown != nil {
+		batch.shutdownOnce.Do(func() {
+			edgexcontext.OnShutdown(batch.Flush)
+		})
+	}
+
+	batch.lock.Lock()
+
+	// Keep the latest, so the batch is sent using the current pipeline
+	batch.resume = edgexcontext.ResumePipeline
+	batch.loggingClient = edgexcontext.LoggingClient
+
 	// always append data
+	if batch.batchData.length() == 0 {
+		batch.started = batch.now().UnixNano()
+	}
 	batch.batchData.append(data)
 
-	// If its time only or time and count
-	if batch.batchMode != BatchByCountOnly {
-		if !batch.timerActive.Value() {
-			batch.timerActive.Set(true)
-			for {
-				select {
-				case <-batch.done:
-					edgexcontext.LoggingClient.Debug("Batch count has been reached")
-				case <-time.After(batch.parsedDuration):
-					edgexcontext.LoggingClient.Debug("Timer has elapsed")
-				}
-				break
-			}
-			batch.timerActive.Set(false)
+	if batch.store != nil && !batch.closed {
+		item := batchItem{Sequence: batch.sequence, Started: batch.started, Data: data}
+		batch.sequence++
+		if err := batch.store.add(item); err != nil {
+			edgexcontext.LoggingClient.Error("Unable to persist batched data", "error", err)
 		} else {
-			if batch.batchMode == BatchByTimeOnly {
-				return false, nil
-			}
+			batch.persisted = append(batch.persisted, item.Sequence)
 		}
 	}
 
-	if batch.batchMode != BatchByTimeOnly {
-		//Only want to check the threshold if the timer is running and in TimeAndCount mode OR if we are
-		// in CountOnly mode
-		if batch.batchMode == BatchByCountOnly || (batch.timerActive.Value() && batch.batchMode == BatchByTimeAndCount) {
-			// if we have not reached the threshold, then stop pipeline and continue batching
-			if batch.batchData.length() < batch.batchThreshold {
-				return false, nil
-			}
-			// if in BatchByCountOnly mode, there are no listeners so this would hang indefinitely
-			if batch.done != nil {
-				batch.done <- true
-			}
+	switch {
+	case batch.closed:
+		edgexcontext.LoggingClient.Debug("Batch has been closed, forwarding Batched Data...")
+	case batch.overdue:
+		edgexcontext.LoggingClient.Debug("Batch time interval has elapsed, forwarding Batched Data...")
+	case batch.thresholdReached():
+		edgexcontext.LoggingClient.Debug("Batch threshold has been reached, forwarding Batched Data...")
+	default:
+		if batch.batchMode != BatchByCountOnly && batch.batchMode != BatchBySizeOnly && batch.timer == nil {
+			batch.startTimer(batch.parsedDuration)
 		}
+		batch.lock.Unlock()
+		return false, nil
 	}
 
-	edgexcontext.LoggingClient.Debug("Forwarding Batched Data...")
-	// we've met the threshold, lets clear out the buffer and send it forward in the pipeline
-	if batch.batchData.length() > 0 {
-		copy := batch.batchData.all()
-		batch.batchData.removeAll()
-		return true, copy
+	result, sequences := batch.take()
+	store := batch.store
+	batch.lock.Unlock()
+
+	if store == nil || edgexcontext.ResumePipeline == nil || !edgexcontext.CanPublishOutput {
+		batch.removePersisted(sequences, edgexcontext.LoggingClient)
+		return true, result
 	}
+
+	batch.forward(edgexcontext.ResumePipeline, result, sequences, edgexcontext.LoggingClient)
 	return false, nil
 }
+
+// startTimer starts the timer sending the current batch when it expires, the caller must hold the lock
+func (batch *BatchConfig) startTimer(duration time.Duration) {
+	batchNumber := batch.sent
+	batch.timer = batch.afterFunc(duration, func() {
+		batch.send(func() bool { return batch.sent == batchNumber })
+	})
+}
+
+// Flush sends the batched data, if any, to the remaining pipeline functions now
+func (batch *BatchConfig) Flush() {
+	batch.send(func() bool { return true })
+}
+
+// Close sends the batched data, if any, to the remaining pipeline functions and stops batching, so any data
+// received afterwards is sent on by itself. It is called when the batch is replaced by one with a new configuration,
+// i.e. when the configurable pipeline is reloaded, so its persisted data is either sent or left for the new batch
+// to restore rather than being sent by both.
+func (batch *BatchConfig) Close() {
+	batch.lock.Lock()
+	batch.closed = true
+	if batch.resume == nil && batch.store != nil {
+		// The pipeline can't be resumed to send the data, which remains persisted for the new batch to restore
+		if batch.timer != nil {
+			batch.timer.Stop()
+			batch.timer = nil
+		}
+		batch.batchData.removeAll()
+		batch.persisted = nil
+		batch.lock.Unlock()
+		return
+	}
+	batch.lock.Unlock()
+
+	batch.Flush()
+}
+
+// send sends the batched data to the remaining pipeline functions when there is some and shouldSend,
+// which is called with the lock held, returns true
+func (batch *BatchConfig) send(shouldSend func() bool) {
+	batch.lock.Lock()
+	if !shouldSend() || batch.batchData.length() == 0 {
+		batch.lock.Unlock()
+		return
+	}
+
+	if batch.resume == nil {
+		// Batch hasn't been called by the pipeline runtime yet, i.e. the batch was restored on startup, so the
+		// batch is sent with the next data received
+		batch.overdue = true
+		batch.timer = nil
+		batch.lock.Unlock()
+		return
+	}
+
+	result, sequences := batch.take()
+	resume := batch.resume
+	loggingClient := batch.loggingClient
+	batch.lock.Unlock()
+
+	loggingClient.Debug("Forwarding Batched Data...")
+	batch.forward(resume, result, sequences, loggingClient)
+}
+
+// forward executes the remaining pipeline functions with the batch and removes its persisted items once they
+// succeeded, otherwise the items remain persisted so the batch is sent again after a restart
+func (batch *BatchConfig) forward(resume func(result interface{}) error, result []byte, sequences []uint64,
+	loggingClient logger.LoggingClient) {
+
+	if err := resume(result); err != nil {
+		if len(sequences) > 0 {
+			loggingClient.Error("Batched data was not sent, it remains persisted to be sent after a restart", "error", err)
+		}
+		return
+	}
+
+	batch.removePersisted(sequences, loggingClient)
+}
+
+// removePersisted removes the persisted items with the sequences
+func (batch *BatchConfig) removePersisted(sequences []uint64, loggingClient logger.LoggingClient) {
+	if len(sequences) == 0 {
+		return
+	}
+
+	batch.lock.Lock()
+	defer batch.lock.Unlock()
+
+	if err := batch.store.remove(sequences); err != nil {
+		loggingClient.Error("Unable to remove persisted batched data", "error", err)
+	}
+}
+
+func (batch *BatchConfig) thresholdReached() bool {
+	if batch.byteThreshold > 0 && batch.batchData.byteSize() >= batch.byteThreshold {
+		return true
+	}
+
+	switch batch.batchMode {
+	case BatchByCountOnly, BatchByTimeAndCount:
+		return batch.batchData.length() >= batch.batchThreshold
+	default:
+		return false
+	}
+}
+
+// take removes the batched data and returns it as a JSON array along with the sequences of its persisted items,
+// the caller must hold the lock
+func (batch *BatchConfig) take() ([]byte, []uint64) {
+	if batch.timer != nil {
+		batch.timer.Stop()
+		batch.timer = nil
+	}
+
+	items := batch.batchData.all()
+	batch.batchData.removeAll()
+	sequences := batch.persisted
+	batch.persisted = nil
+	batch.sent++
+	batch.overdue = false
+
+	array := make([]json.RawMessage, len(items))
+	for index, item := range items {
+		if json.Valid(item) {
+			array[index] = item
+			continue
+		}
+
+		// Marshalling a string can't fail
+		array[index], _ = json.Marshal(string(item))
+	}
+
+	// Marshalling valid JSON can't fail
+	result, _ := json.Marshal(array)
+	return result, sequences
+}
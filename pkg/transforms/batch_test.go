@@ -1,15 +1,37 @@
 package transforms
 
 import (
-	"sync"
+	"encoding/json"
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var dataToBatch = [3]string{"Test1", "Test2", "Test3"}
 
+// newResumableContext returns a copy of the test context whose resumed pipeline sends its result to the channel
+func newResumableContext() (*appcontext.Context, chan interface{}) {
+	resumed := make(chan interface{}, 10)
+	resumable := *context
+	resumable.CanPublishOutput = true
+	resumable.ResumePipeline = func(result interface{}) error {
+		resumed <- result
+		return nil
+	}
+	return &resumable, resumed
+}
+
+func batchedItems(t *testing.T, result interface{}) []string {
+	var items []string
+	require.NoError(t, json.Unmarshal(result.([]byte), &items))
+	return items
+}
+
 func TestBatchNoData(t *testing.T) {
 
 	bs, _ := NewBatchByCount(1)
@@ -32,7 +54,7 @@ func TestBatchInCountMode(t *testing.T) {
 
 	continuePipeline3, result3 := bs.Batch(context, []byte(dataToBatch[0]))
 	assert.True(t, continuePipeline3)
-	assert.Len(t, result3, 3, "Should have 3 records")
+	assert.Len(t, batchedItems(t, result3), 3, "Should have 3 records")
 	assert.Len(t, bs.batchData.all(), 0, "Records should have been cleared")
 
 	continuePipeline4, _ := bs.Batch(context, []byte(dataToBatch[0]))
@@ -45,121 +67,308 @@ func TestBatchInCountMode(t *testing.T) {
 
 	continuePipeline6, result4 := bs.Batch(context, []byte(dataToBatch[0]))
 	assert.True(t, continuePipeline6)
-	assert.Len(t, result4, 3, "Should have 3 records")
+	assert.Len(t, batchedItems(t, result4), 3, "Should have 3 records")
 	assert.Len(t, bs.batchData.all(), 0, "Records should have been cleared")
 }
+
 func TestBatchInTimeAndCountMode_TimeElapsed(t *testing.T) {
 
-	bs, _ := NewBatchByTimeAndCount("2s", 10)
-	var wgAll sync.WaitGroup
-	var wgFirst sync.WaitGroup
-	wgAll.Add(3)
-	wgFirst.Add(1)
-
-	go func() {
-		go func() {
-			time.Sleep(time.Second * 1)
-			wgFirst.Done()
-		}()
-
-		// Key to this test is this call occurs first and will be blocked until
-		// batch time interval has elapsed. In the mean time the other go func have to execute
-		// before the batch time interval has elapsed, so the sleep above has to be less than the
-		// batch time interval.
-		continuePipeline1, result := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.True(t, continuePipeline1)
-		if continuePipeline1 {
-			assert.Equal(t, 3, len(result.([][]byte)))
-			assert.Len(t, bs.batchData.all(), 0, "Should have 0 records")
-		}
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		continuePipeline2, _ := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline2)
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		continuePipeline3, _ := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline3)
-		wgAll.Done()
-	}()
-	wgAll.Wait()
+	bs, _ := NewBatchByTimeAndCount("100ms", 10)
+	edgexcontext, resumed := newResumableContext()
+
+	for _, data := range dataToBatch {
+		continuePipeline, result := bs.Batch(edgexcontext, []byte(data))
+		assert.False(t, continuePipeline, "Batch should not block until the time interval has elapsed")
+		assert.Nil(t, result)
+	}
+
+	select {
+	case result := <-resumed:
+		assert.Equal(t, dataToBatch[:], batchedItems(t, result))
+		assert.Len(t, bs.batchData.all(), 0, "Should have 0 records")
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Batch was not sent when the time interval elapsed")
+	}
 }
 
 func TestBatchInTimeAndCountMode_CountMet(t *testing.T) {
 
 	bs, _ := NewBatchByTimeAndCount("90s", 3)
-	var wgAll sync.WaitGroup
-	var wgFirst sync.WaitGroup
-	var wgSecond sync.WaitGroup
-	wgAll.Add(3)
-	wgFirst.Add(1)
-	wgSecond.Add(1)
-
-	go func() {
-		go func() {
-			time.Sleep(time.Second * 10)
-			wgFirst.Done()
-		}()
-		continuePipeline1, _ := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline1)
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		go func() {
-			time.Sleep(time.Second * 10)
-			wgSecond.Done()
-		}()
-		continuePipeline2, _ := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline2)
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		wgSecond.Wait()
-		continuePipeline3, result := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.True(t, continuePipeline3)
-		assert.Equal(t, 3, len(result.([][]byte)))
-		assert.Nil(t, bs.batchData.all(), "Should have 0 records")
-		wgAll.Done()
-	}()
-	wgAll.Wait()
+	edgexcontext, resumed := newResumableContext()
+
+	continuePipeline1, _ := bs.Batch(edgexcontext, []byte(dataToBatch[0]))
+	assert.False(t, continuePipeline1)
+	assert.NotNil(t, bs.timer, "Timer should have been started")
+
+	continuePipeline2, _ := bs.Batch(edgexcontext, []byte(dataToBatch[1]))
+	assert.False(t, continuePipeline2)
+
+	continuePipeline3, result := bs.Batch(edgexcontext, []byte(dataToBatch[2]))
+	assert.True(t, continuePipeline3)
+	assert.Equal(t, dataToBatch[:], batchedItems(t, result))
+	assert.Nil(t, bs.batchData.all(), "Should have 0 records")
+	assert.Nil(t, bs.timer, "Timer should have been stopped")
+	assert.Len(t, resumed, 0)
 }
+
 func TestBatchInTimeMode(t *testing.T) {
 
-	bs, _ := NewBatchByTime("3s")
-	var wgAll sync.WaitGroup
-	var wgFirst sync.WaitGroup
-	wgAll.Add(3)
-	wgFirst.Add(1)
-
-	go func() {
-		go func() {
-			time.Sleep(100)
-			wgFirst.Done()
-		}()
-
-		continuePipeline1, result := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.True(t, continuePipeline1)
-		assert.Equal(t, 3, len(result.([][]byte)))
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		continuePipeline2, result := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline2)
+	bs, _ := NewBatchByTime("100ms")
+	edgexcontext, resumed := newResumableContext()
+
+	for _, data := range dataToBatch {
+		continuePipeline, result := bs.Batch(edgexcontext, []byte(data))
+		assert.False(t, continuePipeline)
 		assert.Nil(t, result)
-		wgAll.Done()
-	}()
-	go func() {
-		wgFirst.Wait()
-		continuePipeline3, _ := bs.Batch(context, []byte(dataToBatch[0]))
-		assert.False(t, continuePipeline3)
-		wgAll.Done()
-	}()
-	wgAll.Wait()
+	}
+
+	select {
+	case result := <-resumed:
+		assert.Equal(t, dataToBatch[:], batchedItems(t, result))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Batch was not sent when the time interval elapsed")
+	}
+
+	// Next batch starts a new timer
+	continuePipeline, _ := bs.Batch(edgexcontext, []byte(dataToBatch[0]))
+	assert.False(t, continuePipeline)
+
+	select {
+	case result := <-resumed:
+		assert.Equal(t, dataToBatch[:1], batchedItems(t, result))
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "Second batch was not sent when the time interval elapsed")
+	}
+}
+
+func TestBatchExpiredTimerOfSentBatch(t *testing.T) {
+
+	bs, _ := NewBatchByTimeAndCount("1m", 2)
+	edgexcontext, resumed := newResumableContext()
+
+	var expire []func()
+	bs.afterFunc = func(duration time.Duration, function func()) *time.Timer {
+		expire = append(expire, function)
+		return time.NewTimer(time.Hour)
+	}
+
+	bs.Batch(edgexcontext, []byte(dataToBatch[0]))
+	continuePipeline, _ := bs.Batch(edgexcontext, []byte(dataToBatch[1]))
+	require.True(t, continuePipeline)
+	bs.Batch(edgexcontext, []byte(dataToBatch[2]))
+	require.Len(t, expire, 2)
+
+	// Timer of the batch already sent by count expires late
+	expire[0]()
+	assert.Len(t, resumed, 0, "Expired timer should not send the next batch")
+
+	expire[1]()
+	require.Len(t, resumed, 1)
+	assert.Equal(t, dataToBatch[2:], batchedItems(t, <-resumed))
+}
+
+func TestBatchBySize(t *testing.T) {
+
+	_, err := NewBatchBySize(0)
+	assert.Error(t, err)
+
+	bs, err := NewBatchBySize(10)
+	require.NoError(t, err)
+
+	continuePipeline, _ := bs.Batch(context, []byte(dataToBatch[0]))
+	assert.False(t, continuePipeline)
+	assert.Nil(t, bs.timer, "Size only mode should not start a timer")
+
+	continuePipeline, result := bs.Batch(context, []byte(dataToBatch[1]))
+	assert.True(t, continuePipeline, "Batch should be sent when it reaches 10 bytes")
+	assert.Equal(t, dataToBatch[:2], batchedItems(t, result))
+}
+
+func TestBatchByCountWithByteThreshold(t *testing.T) {
+
+	bs, _ := NewBatchByCount(10)
+	assert.Error(t, bs.SetByteThreshold(-1))
+	require.NoError(t, bs.SetByteThreshold(8))
+
+	continuePipeline, _ := bs.Batch(context, []byte(dataToBatch[0]))
+	assert.False(t, continuePipeline)
+
+	continuePipeline, result := bs.Batch(context, []byte(dataToBatch[1]))
+	assert.True(t, continuePipeline, "Byte threshold should send the batch before the count is reached")
+	assert.Len(t, batchedItems(t, result), 2)
+}
+
+func TestBatchJSONItems(t *testing.T) {
+
+	bs, _ := NewBatchByCount(2)
+
+	bs.Batch(context, []byte(`{"device":"id1"}`))
+	continuePipeline, result := bs.Batch(context, []byte(dataToBatch[0]))
+	require.True(t, continuePipeline)
+	assert.Equal(t, `[{"device":"id1"},"Test1"]`, string(result.([]byte)))
+}
+
+func TestBatchFlushOnShutdown(t *testing.T) {
+
+	bs, _ := NewBatchByTime("1h")
+	edgexcontext, resumed := newResumableContext()
+
+	var handlers []func()
+	edgexcontext.OnShutdown = func(handler func()) {
+		handlers = append(handlers, handler)
+	}
+
+	bs.Batch(edgexcontext, []byte(dataToBatch[0]))
+	bs.Batch(edgexcontext, []byte(dataToBatch[1]))
+	require.Len(t, handlers, 1, "Shutdown handler should only be registered once")
+
+	handlers[0]()
+	require.Len(t, resumed, 1)
+	assert.Equal(t, dataToBatch[:2], batchedItems(t, <-resumed))
+	assert.Nil(t, bs.timer, "Timer should have been stopped")
+
+	// Nothing left to send
+	handlers[0]()
+	assert.Len(t, resumed, 0)
+}
+
+func TestBatchPersistence(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Persist func(batch *BatchConfig) error
+	}{
+		{"PersistentStore", func() func(batch *BatchConfig) error {
+			store := newFakePersistentStore()
+			return func(batch *BatchConfig) error { return batch.SetPersistence(store, "test-batch") }
+		}()},
+		{"File", func() func(batch *BatchConfig) error {
+			path := filepath.Join(t.TempDir(), "batch.json")
+			return func(batch *BatchConfig) error { return batch.SetPersistenceFile(path) }
+		}()},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			clock := &fakeClock{current: time.Unix(1000, 0)}
+			var timers []time.Duration
+			newBatch := func() *BatchConfig {
+				batch, err := NewBatchByTimeAndCount("1m", 3)
+				require.NoError(t, err)
+				batch.now = clock.now
+				batch.afterFunc = func(duration time.Duration, function func()) *time.Timer {
+					timers = append(timers, duration)
+					return time.NewTimer(time.Hour)
+				}
+				require.NoError(t, test.Persist(batch))
+				return batch
+			}
+
+			batch := newBatch()
+			edgexcontext, resumed := newResumableContext()
+			batch.Batch(edgexcontext, []byte(dataToBatch[0]))
+			clock.current = clock.current.Add(20 * time.Second)
+			batch.Batch(edgexcontext, []byte(dataToBatch[1]))
+
+			// Service restarts 10 seconds later
+			clock.current = clock.current.Add(10 * time.Second)
+			restored := newBatch()
+			assert.Equal(t, [][]byte{[]byte(dataToBatch[0]), []byte(dataToBatch[1])}, restored.batchData.all())
+			require.Len(t, timers, 2)
+			assert.Equal(t, 30*time.Second, timers[1], "timer should resume with the remaining time of the batch")
+
+			// Persisted batch is sent using ResumePipeline, so it is only removed once the pipeline succeeded
+			continuePipeline, result := restored.Batch(edgexcontext, []byte(dataToBatch[2]))
+			require.False(t, continuePipeline)
+			assert.Nil(t, result)
+			require.Len(t, resumed, 1)
+			assert.Equal(t, dataToBatch[:], batchedItems(t, <-resumed))
+
+			// Sent batch is no longer persisted
+			restored = newBatch()
+			assert.Len(t, restored.batchData.all(), 0)
+
+			// Batch the pipeline fails to process remains persisted
+			failing := *edgexcontext
+			failing.ResumePipeline = func(interface{}) error { return errors.New("export failed") }
+			for _, data := range dataToBatch {
+				restored.Batch(&failing, []byte(data))
+			}
+			assert.Len(t, restored.batchData.all(), 0)
+			restored = newBatch()
+			assert.Len(t, restored.batchData.all(), len(dataToBatch))
+		})
+	}
+}
+
+func TestBatchRestoredAfterInterval(t *testing.T) {
+	store := newFakePersistentStore()
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+
+	batch, _ := NewBatchByTime("1m")
+	batch.now = clock.now
+	require.NoError(t, batch.SetPersistence(store, "test-batch"))
+	edgexcontext, resumed := newResumableContext()
+	batch.Batch(edgexcontext, []byte(dataToBatch[0]))
+
+	// Restarted after the interval, so the timer expires immediately but the pipeline can't be resumed yet
+	clock.current = clock.current.Add(2 * time.Minute)
+	restored, _ := NewBatchByTime("1m")
+	restored.now = clock.now
+	var expire func()
+	restored.afterFunc = func(duration time.Duration, function func()) *time.Timer {
+		assert.Equal(t, time.Duration(0), duration)
+		expire = function
+		return time.NewTimer(time.Hour)
+	}
+	require.NoError(t, restored.SetPersistence(store, "test-batch"))
+	require.NotNil(t, expire)
+	expire()
+	assert.Len(t, restored.batchData.all(), 1, "batch should be kept until it can be sent")
+
+	continuePipeline, result := restored.Batch(edgexcontext, []byte(dataToBatch[1]))
+	require.False(t, continuePipeline)
+	assert.Nil(t, result)
+	require.Len(t, resumed, 1, "overdue batch should be sent with the next data")
+	assert.Equal(t, dataToBatch[:2], batchedItems(t, <-resumed))
+	assert.Len(t, store.persisted("test-batch"), 0)
+}
+
+func TestBatchPersistenceErrors(t *testing.T) {
+	batch, _ := NewBatchByTime("1m")
+	assert.Error(t, batch.SetPersistence(nil, "test-batch"))
+	assert.Error(t, batch.SetPersistence(newFakePersistentStore(), ""))
+	assert.Error(t, batch.SetPersistenceFile(""))
+	assert.Error(t, batch.SetPersistenceFile(t.TempDir()), "directory can not be read as a batch")
+}
+
+func TestBatchClose(t *testing.T) {
+	batch, _ := NewBatchByTime("1h")
+	edgexcontext, resumed := newResumableContext()
+	batch.Batch(edgexcontext, []byte(dataToBatch[0]))
+	batch.Batch(edgexcontext, []byte(dataToBatch[1]))
+
+	batch.Close()
+	require.Len(t, resumed, 1, "batched data should be sent when closed")
+	assert.Equal(t, dataToBatch[:2], batchedItems(t, <-resumed))
+
+	continuePipeline, result := batch.Batch(edgexcontext, []byte(dataToBatch[2]))
+	require.True(t, continuePipeline, "data should be sent on once closed")
+	assert.Equal(t, dataToBatch[2:], batchedItems(t, result))
+}
+
+func TestBatchCloseRestored(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.json")
+	batch, _ := NewBatchByTime("1h")
+	require.NoError(t, batch.SetPersistenceFile(path))
+	batch.Batch(context, []byte(dataToBatch[0]))
+
+	restored, _ := NewBatchByTime("1h")
+	require.NoError(t, restored.SetPersistenceFile(path))
+	restored.Close()
+	assert.Len(t, restored.batchData.all(), 0)
+
+	// Restored data which couldn't be sent remains persisted for the batch replacing the closed one
+	replacement, _ := NewBatchByTime("1h")
+	require.NoError(t, replacement.SetPersistenceFile(path))
+	assert.Equal(t, [][]byte{[]byte(dataToBatch[0])}, replacement.batchData.all())
 }
@@ -0,0 +1,215 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strconv"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// batchItem is a batched item as persisted. Started is when the batch the item belongs to was started, so the
+// batch timer can be resumed after a restart, and Sequence orders the items within the batch.
+type batchItem struct {
+	Sequence uint64
+	Started  int64
+	Data     []byte
+}
+
+// batchStore persists the items of the current batch as they are added, so they survive restarts
+type batchStore interface {
+	// add persists the item
+	add(item batchItem) error
+	// remove removes the persisted items with the sequences, once the batch they belong to has been sent
+	remove(sequences []uint64) error
+	// load returns the persisted items
+	load() ([]batchItem, error)
+}
+
+// persistentBatchStore persists the batched items to the PersistentStore under the storeKey
+type persistentBatchStore struct {
+	store    appcontext.PersistentStore
+	storeKey string
+	// ids are the ids of the persisted items by their sequence
+	ids map[uint64]string
+}
+
+func (store *persistentBatchStore) add(item batchItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	id, err := store.store.Store(store.storeKey, data)
+	if err != nil {
+		return err
+	}
+
+	store.ids[item.Sequence] = id
+	return nil
+}
+
+func (store *persistentBatchStore) remove(sequences []uint64) error {
+	var firstError error
+	for _, sequence := range sequences {
+		id, ok := store.ids[sequence]
+		if !ok {
+			continue
+		}
+
+		if err := store.store.Remove(store.storeKey, id); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			continue
+		}
+		delete(store.ids, sequence)
+	}
+
+	return firstError
+}
+
+func (store *persistentBatchStore) load() ([]batchItem, error) {
+	persisted, err := store.store.Retrieve(store.storeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []batchItem
+	for id, data := range persisted {
+		var item batchItem
+		if json.Unmarshal(data, &item) != nil {
+			continue
+		}
+
+		store.ids[item.Sequence] = id
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// fileBatchStore persists the batched items to a local file with one JSON item per line
+type fileBatchStore struct {
+	path string
+}
+
+func (store *fileBatchStore) add(item batchItem) error {
+	line, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(store.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(append(line, '\n')); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// remove rewrites the file without the items with the sequences, replacing it so a restart never sees a partially
+// written file, and removes it once no items remain
+func (store *fileBatchStore) remove(sequences []uint64) error {
+	items, err := store.load()
+	if err != nil {
+		return err
+	}
+
+	removed := make(map[uint64]bool, len(sequences))
+	for _, sequence := range sequences {
+		removed[sequence] = true
+	}
+
+	var lines []byte
+	for _, item := range items {
+		if removed[item.Sequence] {
+			continue
+		}
+
+		// Marshalling an item which was unmarshalled can't fail
+		line, _ := json.Marshal(item)
+		lines = append(append(lines, line...), '\n')
+	}
+
+	if len(lines) == 0 {
+		if err := os.Remove(store.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	tempPath := store.path + "." + strconv.Itoa(os.Getpid())
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(lines); err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tempPath, store.path)
+	}
+
+	if err != nil {
+		os.Remove(tempPath)
+	}
+
+	return err
+}
+
+func (store *fileBatchStore) load() ([]batchItem, error) {
+	file, err := os.Open(store.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var items []batchItem
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var item batchItem
+		// A line only partially written when the service stopped is skipped
+		if err := json.Unmarshal(scanner.Bytes(), &item); err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, scanner.Err()
+}
@@ -23,13 +23,30 @@ import (
 	"compress/zlib"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
 	"github.com/jcerato/app-functions-sdk-go/pkg/util"
 )
 
+const (
+	// ContentEncodingKey is the context value key for the encoding of raw binary compressed data
+	ContentEncodingKey = "Content-Encoding"
+	// ContentEncodingGZIP is the Content-Encoding of data compressed with GZIP
+	ContentEncodingGZIP = "gzip"
+	// ContentEncodingZLIB is the Content-Encoding of data compressed with ZLIB
+	ContentEncodingZLIB = "deflate"
+
+	contentTypeBinary = "application/octet-stream"
+)
+
 type Compression struct {
+	// RawBinary indicates the compressed data is returned as raw binary rather than as a base64 encoded string.
+	// In this case the Content-Encoding context value is set so exports can pass on how the data is encoded.
+	RawBinary  bool
 	gzipWriter *gzip.Writer
 	zlibWriter *zlib.Writer
 }
@@ -39,8 +56,14 @@ func NewCompression() Compression {
 	return Compression{}
 }
 
+// NewCompressionWithRawBinary creates, initializes and returns a new instance of Compression
+// which returns the compressed data as raw binary
+func NewCompressionWithRawBinary() Compression {
+	return Compression{RawBinary: true}
+}
+
 // CompressWithGZIP compresses data received as either a string,[]byte, or json.Marshaler using gzip algorithm
-// and returns a base64 encoded string as a []byte.
+// and returns a base64 encoded string as a []byte, or the raw binary data when RawBinary is set.
 func (compression *Compression) CompressWithGZIP(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
@@ -62,15 +85,12 @@ func (compression *Compression) CompressWithGZIP(edgexcontext *appcontext.Contex
 	compression.gzipWriter.Write([]byte(data))
 	compression.gzipWriter.Close()
 
-	// Set response "content-type" header to "text/plain"
-	edgexcontext.ResponseContentType = clients.ContentTypeText
-
-	return true, bytesBufferToBase64(buf)
+	return true, compression.result(edgexcontext, buf, ContentEncodingGZIP)
 
 }
 
 // CompressWithZLIB compresses data received as either a string,[]byte, or json.Marshaler using zlib algorithm
-// and returns a base64 encoded string as a []byte.
+// and returns a base64 encoded string as a []byte, or the raw binary data when RawBinary is set.
 func (compression *Compression) CompressWithZLIB(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
@@ -92,11 +112,77 @@ func (compression *Compression) CompressWithZLIB(edgexcontext *appcontext.Contex
 	compression.zlibWriter.Write([]byte(data))
 	compression.zlibWriter.Close()
 
+	return true, compression.result(edgexcontext, buf, ContentEncodingZLIB)
+
+}
+
+// DecompressWithGZIP decompresses gzip compressed data received as either a string or []byte
+// and returns the decompressed data as a []byte.
+func (compression *Compression) DecompressWithGZIP(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		// We didn't receive a result
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Decompression with GZIP")
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("unable to decompress GZIP data: %s", err.Error())
+	}
+	defer reader.Close()
+
+	return decompressed(edgexcontext, reader, "GZIP")
+}
+
+// DecompressWithZLIB decompresses zlib compressed data received as either a string or []byte
+// and returns the decompressed data as a []byte.
+func (compression *Compression) DecompressWithZLIB(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		// We didn't receive a result
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Decompression with ZLIB")
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("unable to decompress ZLIB data: %s", err.Error())
+	}
+	defer reader.Close()
+
+	return decompressed(edgexcontext, reader, "ZLIB")
+}
+
+func (compression *Compression) result(edgexcontext *appcontext.Context, buf bytes.Buffer, encoding string) []byte {
+	if compression.RawBinary {
+		edgexcontext.ResponseContentType = contentTypeBinary
+		edgexcontext.AddValue(ContentEncodingKey, encoding)
+		return buf.Bytes()
+	}
+
 	// Set response "content-type" header to "text/plain"
 	edgexcontext.ResponseContentType = clients.ContentTypeText
 
-	return true, bytesBufferToBase64(buf)
+	return bytesBufferToBase64(buf)
+}
+
+func decompressed(edgexcontext *appcontext.Context, reader io.Reader, algorithm string) (bool, interface{}) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return false, fmt.Errorf("unable to decompress %s data: %s", algorithm, err.Error())
+	}
+
+	// The data is no longer encoded
+	edgexcontext.RemoveValue(ContentEncodingKey)
 
+	return true, data
 }
 
 func bytesBufferToBase64(buf bytes.Buffer) []byte {
@@ -88,6 +88,88 @@ func TestZlib(t *testing.T) {
 	assert.Equal(t, context.ResponseContentType, clients.ContentTypeText)
 }
 
+func TestCompressRawBinaryAndDecompress(t *testing.T) {
+	tests := []struct {
+		Name             string
+		Compress         func(comp *Compression) (bool, interface{})
+		Decompress       func(comp *Compression, data interface{}) (bool, interface{})
+		ExpectedEncoding string
+	}{
+		{
+			"GZIP",
+			func(comp *Compression) (bool, interface{}) {
+				return comp.CompressWithGZIP(context, []byte(clearString))
+			},
+			func(comp *Compression, data interface{}) (bool, interface{}) {
+				return comp.DecompressWithGZIP(context, data)
+			},
+			ContentEncodingGZIP,
+		},
+		{
+			"ZLIB",
+			func(comp *Compression) (bool, interface{}) {
+				return comp.CompressWithZLIB(context, []byte(clearString))
+			},
+			func(comp *Compression, data interface{}) (bool, interface{}) {
+				return comp.DecompressWithZLIB(context, data)
+			},
+			ContentEncodingZLIB,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			comp := NewCompressionWithRawBinary()
+
+			continuePipeline, compressed := test.Compress(&comp)
+			require.True(t, continuePipeline)
+			assert.Equal(t, "application/octet-stream", context.ResponseContentType)
+			encoding, ok := context.GetValue(ContentEncodingKey)
+			require.True(t, ok)
+			assert.Equal(t, test.ExpectedEncoding, encoding)
+
+			continuePipeline, decompressed := test.Decompress(&comp, compressed)
+			require.True(t, continuePipeline)
+			assert.Equal(t, clearString, string(decompressed.([]byte)))
+			_, ok = context.GetValue(ContentEncodingKey)
+			assert.False(t, ok, "Content-Encoding should be removed once decompressed")
+		})
+	}
+}
+
+func TestDecompressBase64Encoded(t *testing.T) {
+	comp := NewCompression()
+	encoding := NewEncoding()
+
+	continuePipeline, decoded := encoding.DecodeBase64(context, gzipString)
+	require.True(t, continuePipeline)
+	continuePipeline, result := comp.DecompressWithGZIP(context, decoded)
+	require.True(t, continuePipeline)
+	assert.Equal(t, clearString, string(result.([]byte)))
+
+	continuePipeline, decoded = encoding.DecodeBase64(context, []byte(zlibString))
+	require.True(t, continuePipeline)
+	continuePipeline, result = comp.DecompressWithZLIB(context, decoded)
+	require.True(t, continuePipeline)
+	assert.Equal(t, clearString, string(result.([]byte)))
+}
+
+func TestDecompressInvalidData(t *testing.T) {
+	comp := NewCompression()
+
+	continuePipeline, result := comp.DecompressWithGZIP(context, []byte(clearString))
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error))
+
+	continuePipeline, result = comp.DecompressWithZLIB(context, []byte(clearString))
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error))
+
+	continuePipeline, result = comp.DecompressWithGZIP(context)
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error))
+}
+
 var result []byte
 
 func BenchmarkGzip(b *testing.B) {
@@ -23,10 +23,11 @@ import (
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/fxamacker/cbor/v2"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
 )
 
-// Conversion houses various built in conversion transforms (XML, JSON, CSV)
+// Conversion houses various built in conversion transforms (XML, JSON, CBOR, Protobuf)
 type Conversion struct {
 }
 
@@ -73,3 +74,36 @@ func (f Conversion) TransformToJSON(edgexcontext *appcontext.Context, params ...
 	}
 	return false, errors.New("Unexpected type received")
 }
+
+// TransformToCBOR transforms an EdgeX event to CBOR.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f Conversion) TransformToCBOR(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+	edgexcontext.LoggingClient.Debug("Transforming to CBOR")
+	if event, ok := params[0].(models.Event); ok {
+		data, err := cbor.Marshal(event)
+		if err != nil {
+			return false, fmt.Errorf("unable to marshal Event to CBOR: %s", err.Error())
+		}
+		edgexcontext.ResponseContentType = clients.ContentTypeCBOR
+		return true, data
+	}
+	return false, errors.New("Unexpected type received")
+}
+
+// TransformToProtobuf transforms an EdgeX event to Protocol Buffers using the Event message schema published
+// in proto/event.proto.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f Conversion) TransformToProtobuf(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+	edgexcontext.LoggingClient.Debug("Transforming to Protobuf")
+	if event, ok := params[0].(models.Event); ok {
+		edgexcontext.ResponseContentType = ContentTypeProtobuf
+		return true, marshalEventProtobuf(event)
+	}
+	return false, errors.New("Unexpected type received")
+}
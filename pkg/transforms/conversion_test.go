@@ -28,6 +28,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/encoding/protowire"
 
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
 )
@@ -172,3 +174,169 @@ func TestTransformToJSONMultipleParametersTwoEvents(t *testing.T) {
 	assert.Equal(t, expectedResult, result.(string))
 
 }
+
+func TestTransformToCBOR(t *testing.T) {
+	eventIn := models.Event{
+		Device:   devID1,
+		Readings: []models.Reading{{Name: "temperature", Value: "21"}},
+	}
+	conv := NewConversion()
+
+	continuePipeline, result := conv.TransformToCBOR(context, eventIn)
+
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, clients.ContentTypeCBOR, context.ResponseContentType)
+
+	eventOut := models.Event{}
+	require.NoError(t, cbor.Unmarshal(result.([]byte), &eventOut))
+	assert.Equal(t, devID1, eventOut.Device)
+	require.Len(t, eventOut.Readings, 1)
+	assert.Equal(t, "21", eventOut.Readings[0].Value)
+}
+
+func TestTransformToCBORNoParameters(t *testing.T) {
+	conv := NewConversion()
+	continuePipeline, result := conv.TransformToCBOR(context)
+
+	assert.Equal(t, "No Event Received", result.(error).Error())
+	assert.False(t, continuePipeline)
+}
+
+func TestTransformToCBORNotAnEvent(t *testing.T) {
+	conv := NewConversion()
+	continuePipeline, result := conv.TransformToCBOR(context, "")
+
+	assert.Equal(t, "Unexpected type received", result.(error).Error())
+	assert.False(t, continuePipeline)
+}
+
+func TestTransformToProtobuf(t *testing.T) {
+	eventIn := models.Event{
+		ID:     "event-id",
+		Device: devID1,
+		Origin: 1471806386919,
+		Tags:   map[string]string{"site": "north", "line": "2"},
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "21", ValueType: models.ValueTypeInt32, Origin: 1471806386919},
+			{Name: "image", ValueType: models.ValueTypeBinary, BinaryValue: []byte{1, 2, 3}, MediaType: "image/png"},
+		},
+	}
+	conv := NewConversion()
+
+	continuePipeline, result := conv.TransformToProtobuf(context, eventIn)
+
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, ContentTypeProtobuf, context.ResponseContentType)
+
+	eventOut := unmarshalEventProtobuf(t, result.([]byte))
+	assert.Equal(t, eventIn, eventOut)
+}
+
+func TestTransformToProtobufNoParameters(t *testing.T) {
+	conv := NewConversion()
+	continuePipeline, result := conv.TransformToProtobuf(context)
+
+	assert.Equal(t, "No Event Received", result.(error).Error())
+	assert.False(t, continuePipeline)
+}
+
+func TestTransformToProtobufNotAnEvent(t *testing.T) {
+	conv := NewConversion()
+	continuePipeline, result := conv.TransformToProtobuf(context, "")
+
+	assert.Equal(t, "Unexpected type received", result.(error).Error())
+	assert.False(t, continuePipeline)
+}
+
+// unmarshalEventProtobuf decodes the Event message as defined by proto/event.proto
+func unmarshalEventProtobuf(t *testing.T, data []byte) models.Event {
+	event := models.Event{}
+	consumeProtobufFields(t, data, func(field protowire.Number, value interface{}) {
+		switch field {
+		case eventFieldID:
+			event.ID = string(value.([]byte))
+		case eventFieldPushed:
+			event.Pushed = int64(value.(uint64))
+		case eventFieldDevice:
+			event.Device = string(value.([]byte))
+		case eventFieldCreated:
+			event.Created = int64(value.(uint64))
+		case eventFieldModified:
+			event.Modified = int64(value.(uint64))
+		case eventFieldOrigin:
+			event.Origin = int64(value.(uint64))
+		case eventFieldReadings:
+			event.Readings = append(event.Readings, unmarshalReadingProtobuf(t, value.([]byte)))
+		case eventFieldTags:
+			var key, tagValue string
+			consumeProtobufFields(t, value.([]byte), func(field protowire.Number, value interface{}) {
+				if field == mapEntryFieldKey {
+					key = string(value.([]byte))
+				} else {
+					tagValue = string(value.([]byte))
+				}
+			})
+			if event.Tags == nil {
+				event.Tags = make(map[string]string)
+			}
+			event.Tags[key] = tagValue
+		}
+	})
+	return event
+}
+
+func unmarshalReadingProtobuf(t *testing.T, data []byte) models.Reading {
+	reading := models.Reading{}
+	consumeProtobufFields(t, data, func(field protowire.Number, value interface{}) {
+		switch field {
+		case readingFieldID:
+			reading.Id = string(value.([]byte))
+		case readingFieldPushed:
+			reading.Pushed = int64(value.(uint64))
+		case readingFieldCreated:
+			reading.Created = int64(value.(uint64))
+		case readingFieldOrigin:
+			reading.Origin = int64(value.(uint64))
+		case readingFieldModified:
+			reading.Modified = int64(value.(uint64))
+		case readingFieldDevice:
+			reading.Device = string(value.([]byte))
+		case readingFieldName:
+			reading.Name = string(value.([]byte))
+		case readingFieldValue:
+			reading.Value = string(value.([]byte))
+		case readingFieldValueType:
+			reading.ValueType = string(value.([]byte))
+		case readingFieldFloatEncoding:
+			reading.FloatEncoding = string(value.([]byte))
+		case readingFieldBinaryValue:
+			reading.BinaryValue = value.([]byte)
+		case readingFieldMediaType:
+			reading.MediaType = string(value.([]byte))
+		}
+	})
+	return reading
+}
+
+func consumeProtobufFields(t *testing.T, data []byte, handle func(field protowire.Number, value interface{})) {
+	for len(data) > 0 {
+		field, wireType, length := protowire.ConsumeTag(data)
+		require.True(t, length > 0, "invalid protobuf tag")
+		data = data[length:]
+
+		switch wireType {
+		case protowire.VarintType:
+			value, length := protowire.ConsumeVarint(data)
+			require.True(t, length > 0, "invalid protobuf varint")
+			handle(field, value)
+			data = data[length:]
+		case protowire.BytesType:
+			value, length := protowire.ConsumeBytes(data)
+			require.True(t, length > 0, "invalid protobuf bytes")
+			handle(field, value)
+			data = data[length:]
+		default:
+			require.Fail(t, "unexpected protobuf wire type")
+		}
+	}
+}
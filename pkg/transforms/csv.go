@@ -0,0 +1,270 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+const (
+	// ContentTypeCSV is the response content type for CSV data
+	ContentTypeCSV = "text/csv"
+
+	// CSV columns which can be written for each reading
+	CSVColumnDevice    = "device"
+	CSVColumnName      = "name"
+	CSVColumnValue     = "value"
+	CSVColumnOrigin    = "origin"
+	CSVColumnValueType = "valueType"
+	CSVColumnUnits     = "units"
+	CSVColumnTags      = "tags"
+)
+
+// DefaultCSVColumns are the columns used when no columns are specified
+var DefaultCSVColumns = []string{CSVColumnDevice, CSVColumnName, CSVColumnValue, CSVColumnValueType, CSVColumnOrigin}
+
+var csvColumns = []string{
+	CSVColumnDevice,
+	CSVColumnName,
+	CSVColumnValue,
+	CSVColumnOrigin,
+	CSVColumnValueType,
+	CSVColumnUnits,
+	CSVColumnTags,
+}
+
+// CSVConversion transforms EdgeX events to CSV with one row per reading
+type CSVConversion struct {
+	// Columns are the reading fields written for each row, in order
+	Columns []string
+	// Delimiter is the field delimiter, defaults to a comma
+	Delimiter rune
+	// IncludeHeader specifies whether the first row contains the column names
+	IncludeHeader bool
+
+	// units caches the units of the value descriptors, keyed by reading name
+	units     map[string]csvUnits
+	unitsLock sync.Mutex
+	now       func() time.Time
+}
+
+// csvUnits are the cached units of a value descriptor. expires is only set for a value descriptor which couldn't be
+// retrieved, which is cached as having no units until then.
+type csvUnits struct {
+	units   string
+	expires time.Time
+}
+
+// csvUnitsFailureTTL is how long a value descriptor which can't be retrieved is cached as having no units
+const csvUnitsFailureTTL = time.Minute
+
+// NewCSVConversion creates, initializes and returns a new instance of CSVConversion. An empty columns list uses
+// DefaultCSVColumns and an empty delimiter uses a comma. An error is returned for an unknown column or a delimiter
+// which isn't a single character.
+func NewCSVConversion(columns []string, delimiter string, includeHeader bool) (*CSVConversion, error) {
+	if len(columns) == 0 {
+		columns = DefaultCSVColumns
+	}
+
+	// Column names are matched case insensitively, so valuetype is accepted for valueType
+	validColumns := make([]string, len(columns))
+	for index, column := range columns {
+		for _, valid := range csvColumns {
+			if strings.EqualFold(column, valid) {
+				validColumns[index] = valid
+				break
+			}
+		}
+
+		if len(validColumns[index]) == 0 {
+			return nil, fmt.Errorf("unknown CSV column '%s'", column)
+		}
+	}
+
+	conversion := &CSVConversion{
+		Columns:       validColumns,
+		Delimiter:     ',',
+		IncludeHeader: includeHeader,
+		units:         make(map[string]csvUnits),
+		now:           time.Now,
+	}
+
+	if len(delimiter) > 0 {
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return nil, fmt.Errorf("CSV delimiter '%s' must be a single character", delimiter)
+		}
+
+		conversion.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+		if conversion.Delimiter == '"' || conversion.Delimiter == '\r' || conversion.Delimiter == '\n' {
+			return nil, fmt.Errorf("invalid CSV delimiter '%s'", delimiter)
+		}
+	}
+
+	return conversion, nil
+}
+
+// TransformToCSV transforms an EdgeX event to CSV with one row per reading. Batched events, as output by
+// BatchConfig, are transformed to a single CSV document. The units column is populated from the units tag
+// added by ScaleReadings, otherwise from the reading's value descriptor, which requires the ValueDescriptorClient.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f *CSVConversion) TransformToCSV(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+	edgexcontext.LoggingClient.Debug("Transforming to CSV")
+
+	var events []models.Event
+
+	switch data := params[0].(type) {
+	case models.Event:
+		events = []models.Event{data}
+	case []models.Event:
+		events = data
+	case []byte:
+		// Batched data is a JSON array of events
+		if err := json.Unmarshal(data, &events); err != nil {
+			return false, fmt.Errorf("unable to unmarshal batched Events: %s", err.Error())
+		}
+	default:
+		return false, errors.New("Unexpected type received")
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	writer.Comma = f.Delimiter
+
+	if f.IncludeHeader {
+		if err := writer.Write(f.Columns); err != nil {
+			return false, fmt.Errorf("unable to write CSV header: %s", err.Error())
+		}
+	}
+
+	row := make([]string, len(f.Columns))
+	for _, event := range events {
+		for _, reading := range event.Readings {
+			for index, column := range f.Columns {
+				row[index] = f.columnValue(edgexcontext, column, event, reading)
+			}
+
+			if err := writer.Write(row); err != nil {
+				return false, fmt.Errorf("unable to write CSV row: %s", err.Error())
+			}
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return false, fmt.Errorf("unable to write CSV: %s", err.Error())
+	}
+
+	edgexcontext.ResponseContentType = ContentTypeCSV
+	return true, buffer.String()
+}
+
+func (f *CSVConversion) columnValue(edgexcontext *appcontext.Context, column string, event models.Event, reading models.Reading) string {
+	switch column {
+	case CSVColumnDevice:
+		if len(reading.Device) > 0 {
+			return reading.Device
+		}
+		return event.Device
+	case CSVColumnName:
+		return reading.Name
+	case CSVColumnValue:
+		return reading.Value
+	case CSVColumnOrigin:
+		if reading.Origin != 0 {
+			return strconv.FormatInt(reading.Origin, 10)
+		}
+		return strconv.FormatInt(event.Origin, 10)
+	case CSVColumnValueType:
+		return reading.ValueType
+	case CSVColumnUnits:
+		// Units recorded by ScaleReadings take precedence over the value descriptor's units
+		if units, ok := event.Tags[reading.Name+UnitsTagSuffix]; ok {
+			return units
+		}
+		return f.lookupUnits(edgexcontext, reading.Name)
+	case CSVColumnTags:
+		return formatTags(event.Tags)
+	default:
+		return ""
+	}
+}
+
+// lookupUnits returns the units of the value descriptor for the reading, which are cached once retrieved. A value
+// descriptor which can't be retrieved is logged and cached as having no units for csvUnitsFailureTTL.
+func (f *CSVConversion) lookupUnits(edgexcontext *appcontext.Context, name string) string {
+	f.unitsLock.Lock()
+	cached, ok := f.units[name]
+	f.unitsLock.Unlock()
+
+	if ok && (cached.expires.IsZero() || f.now().Before(cached.expires)) {
+		return cached.units
+	}
+
+	// The lock isn't held while the value descriptor is retrieved, so the readings of other Events aren't held up
+	cached = csvUnits{expires: f.now().Add(csvUnitsFailureTTL)}
+	if edgexcontext.ValueDescriptorClient != nil {
+		descriptor, err := edgexcontext.ValueDescriptorClient.ValueDescriptorForName(gocontext.Background(), name)
+		if err != nil {
+			edgexcontext.LoggingClient.Warn(fmt.Sprintf("Unable to retrieve units for '%s': %s", name, err.Error()))
+		} else {
+			cached = csvUnits{units: descriptor.UomLabel}
+		}
+	}
+
+	f.unitsLock.Lock()
+	f.units[name] = cached
+	f.unitsLock.Unlock()
+
+	return cached.units
+}
+
+// formatTags formats the tags as key=value pairs, sorted by key and separated by semicolons
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for index, key := range keys {
+		pairs[index] = key + "=" + tags[key]
+	}
+
+	return strings.Join(pairs, ";")
+}
@@ -0,0 +1,226 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCSVTestEvent(device string) models.Event {
+	return models.Event{
+		Device: device,
+		Origin: 1000,
+		Tags:   map[string]string{"site": "north", "line": "2"},
+		Readings: []models.Reading{
+			{Device: device, Name: "temperature", Value: "21.5", ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation, Origin: 1001},
+			{Device: device, Name: "humidity", Value: "40", ValueType: models.ValueTypeInt32},
+		},
+	}
+}
+
+func cacheUnits(conversion *CSVConversion, name string, units string) {
+	conversion.units[name] = csvUnits{units: units}
+}
+
+// fakeValueDescriptorClient only implements ValueDescriptorForName, counting the lookups
+type fakeValueDescriptorClient struct {
+	coredata.ValueDescriptorClient
+	descriptors map[string]models.ValueDescriptor
+	lookups     int
+}
+
+func (client *fakeValueDescriptorClient) ValueDescriptorForName(_ syscontext.Context, name string) (models.ValueDescriptor, error) {
+	client.lookups++
+	descriptor, ok := client.descriptors[name]
+	if !ok {
+		return models.ValueDescriptor{}, errors.New("value descriptor not found")
+	}
+	return descriptor, nil
+}
+
+func TestNewCSVConversion(t *testing.T) {
+	tests := []struct {
+		Name              string
+		Columns           []string
+		Delimiter         string
+		ExpectedColumns   []string
+		ExpectedDelimiter rune
+		ExpectError       bool
+	}{
+		{"Defaults", nil, "", DefaultCSVColumns, ',', false},
+		{"Case insensitive columns", []string{"Device", "valuetype"}, ";", []string{CSVColumnDevice, CSVColumnValueType}, ';', false},
+		{"Tab delimiter", []string{CSVColumnValue}, "\t", []string{CSVColumnValue}, '\t', false},
+		{"Unknown column", []string{"bogus"}, "", nil, 0, true},
+		{"Multi character delimiter", nil, "||", nil, 0, true},
+		{"Quote delimiter", nil, `"`, nil, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			conversion, err := NewCSVConversion(test.Columns, test.Delimiter, true)
+			if test.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedColumns, conversion.Columns)
+			assert.Equal(t, test.ExpectedDelimiter, conversion.Delimiter)
+		})
+	}
+}
+
+func TestTransformToCSV(t *testing.T) {
+	event := newCSVTestEvent(devID1)
+	eventJSON, err := json.Marshal(event)
+	require.NoError(t, err)
+	event2 := newCSVTestEvent(devID2)
+	event2JSON, err := json.Marshal(event2)
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name          string
+		Columns       []string
+		Delimiter     string
+		IncludeHeader bool
+		Data          interface{}
+		Expected      string
+	}{
+		{
+			"Default columns with header",
+			nil, "", true,
+			event,
+			"device,name,value,valueType,origin\n" +
+				"id1,temperature,21.5,Float64,1001\n" +
+				"id1,humidity,40,Int32,1000\n",
+		},
+		{
+			"No header",
+			[]string{CSVColumnName, CSVColumnValue}, "", false,
+			event,
+			"temperature,21.5\nhumidity,40\n",
+		},
+		{
+			"Semicolon delimiter with tags",
+			[]string{CSVColumnName, CSVColumnTags}, ";", true,
+			event,
+			"name;tags\ntemperature;\"line=2;site=north\"\nhumidity;\"line=2;site=north\"\n",
+		},
+		{
+			"Batched events",
+			[]string{CSVColumnDevice, CSVColumnName}, "", true,
+			[]byte("[" + string(eventJSON) + "," + string(event2JSON) + "]"),
+			"device,name\nid1,temperature\nid1,humidity\nid2,temperature\nid2,humidity\n",
+		},
+		{
+			"Units without value descriptor client",
+			[]string{CSVColumnName, CSVColumnUnits}, "", false,
+			event,
+			"temperature,\nhumidity,\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			conversion, err := NewCSVConversion(test.Columns, test.Delimiter, test.IncludeHeader)
+			require.NoError(t, err)
+
+			continuePipeline, result := conversion.TransformToCSV(context, test.Data)
+			require.True(t, continuePipeline, result)
+			assert.Equal(t, test.Expected, result.(string))
+			assert.Equal(t, ContentTypeCSV, context.ResponseContentType)
+		})
+	}
+}
+
+func TestTransformToCSVUnitsCached(t *testing.T) {
+	conversion, err := NewCSVConversion([]string{CSVColumnName, CSVColumnUnits}, "", false)
+	require.NoError(t, err)
+	cacheUnits(conversion, "temperature", "degC")
+	cacheUnits(conversion, "humidity", "%RH")
+
+	continuePipeline, result := conversion.TransformToCSV(context, newCSVTestEvent(devID1))
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, "temperature,degC\nhumidity,%RH\n", result.(string))
+}
+
+func TestTransformToCSVUnitsLookup(t *testing.T) {
+	client := &fakeValueDescriptorClient{descriptors: map[string]models.ValueDescriptor{"temperature": {UomLabel: "degC"}}}
+	edgexcontext := &appcontext.Context{
+		LoggingClient:         logClient,
+		ValueDescriptorClient: client,
+	}
+
+	conversion, err := NewCSVConversion([]string{CSVColumnName, CSVColumnUnits}, "", false)
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	conversion.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		continuePipeline, result := conversion.TransformToCSV(edgexcontext, newCSVTestEvent(devID1))
+		require.True(t, continuePipeline, result)
+		assert.Equal(t, "temperature,degC\nhumidity,\n", result.(string))
+	}
+	assert.Equal(t, 2, client.lookups, "units and missing value descriptors should be looked up once while cached")
+
+	clock.current = clock.current.Add(csvUnitsFailureTTL)
+	conversion.TransformToCSV(edgexcontext, newCSVTestEvent(devID1))
+	assert.Equal(t, 3, client.lookups, "missing value descriptor should be looked up again once the failure expires")
+}
+
+func TestTransformToCSVUnitsFromTags(t *testing.T) {
+	conversion, err := NewCSVConversion([]string{CSVColumnName, CSVColumnUnits}, "", false)
+	require.NoError(t, err)
+	cacheUnits(conversion, "humidity", "%RH")
+
+	event := newCSVTestEvent(devID1)
+	event.Tags["temperature"+UnitsTagSuffix] = "degC"
+
+	continuePipeline, result := conversion.TransformToCSV(context, event)
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, "temperature,degC\nhumidity,%RH\n", result.(string))
+}
+
+func TestTransformToCSVNoParameters(t *testing.T) {
+	conversion, _ := NewCSVConversion(nil, "", true)
+	continuePipeline, result := conversion.TransformToCSV(context)
+
+	assert.Equal(t, "No Event Received", result.(error).Error())
+	assert.False(t, continuePipeline)
+}
+
+func TestTransformToCSVNotAnEvent(t *testing.T) {
+	conversion, _ := NewCSVConversion(nil, "", true)
+	continuePipeline, result := conversion.TransformToCSV(context, "")
+
+	assert.Equal(t, "Unexpected type received", result.(error).Error())
+	assert.False(t, continuePipeline)
+
+	continuePipeline, result = conversion.TransformToCSV(context, []byte("not json"))
+	assert.Contains(t, result.(error).Error(), "unable to unmarshal batched Events")
+	assert.False(t, continuePipeline)
+}
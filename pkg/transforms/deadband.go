@@ -0,0 +1,139 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// forwardedReading is the last value forwarded for a device's reading
+type forwardedReading struct {
+	value  string
+	number float64
+	sent   int64
+}
+
+// Deadband filters out readings whose value hasn't changed by more than the deadband since the value last
+// forwarded for the same device and reading name. The deadband is either an absolute amount or a percentage of
+// the last forwarded value. Readings with non-numeric values are only forwarded when their value changes.
+type Deadband struct {
+	Deadband float64
+	// Percent specifies the Deadband is a percentage of the last forwarded value
+	Percent bool
+	// Heartbeat forces a reading to be forwarded when it hasn't been for the interval, zero disables the heartbeat
+	Heartbeat time.Duration
+
+	// last is keyed by device name and reading name
+	last map[string]forwardedReading
+	lock sync.Mutex
+	now  func() time.Time
+}
+
+// NewDeadband creates, initializes and returns a new instance of Deadband. When percent is true the deadband is
+// a percentage, i.e. 5 for 5%. The heartbeat, i.e. "15m", is optional and disabled when empty.
+func NewDeadband(deadband float64, percent bool, heartbeat string) (*Deadband, error) {
+	if deadband < 0 || math.IsNaN(deadband) || math.IsInf(deadband, 0) {
+		return nil, fmt.Errorf("invalid deadband %v, must be a finite number not less than zero", deadband)
+	}
+
+	var parsedHeartbeat time.Duration
+	if len(heartbeat) > 0 {
+		var err error
+		parsedHeartbeat, err = time.ParseDuration(heartbeat)
+		if err != nil {
+			return nil, fmt.Errorf("invalid heartbeat '%s': %s", heartbeat, err.Error())
+		}
+
+		if parsedHeartbeat < 0 {
+			return nil, errors.New("heartbeat must not be negative")
+		}
+	}
+
+	return &Deadband{
+		Deadband:  deadband,
+		Percent:   percent,
+		Heartbeat: parsedHeartbeat,
+		last:      make(map[string]forwardedReading),
+		now:       time.Now,
+	}, nil
+}
+
+// FilterByDeadband removes the readings whose value is within the deadband of the value last forwarded for the
+// device and reading name, unless the heartbeat interval has passed since it was last forwarded. The first value
+// of each reading is always forwarded. The Event is dropped when no readings remain.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (deadband *Deadband) FilterByDeadband(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	edgexcontext.LoggingClient.Debug("Filtering by Deadband")
+
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	deadband.lock.Lock()
+	defer deadband.lock.Unlock()
+
+	now := deadband.now().UnixNano()
+	var readings []models.Reading
+
+	for _, reading := range event.Readings {
+		key := event.Device + "/" + reading.Name
+		number, err := parseReadingValue(reading)
+		numeric := err == nil
+
+		last, seen := deadband.last[key]
+		if seen && !deadband.changed(last, reading.Value, number, numeric) &&
+			(deadband.Heartbeat == 0 || now-last.sent < int64(deadband.Heartbeat)) {
+			edgexcontext.LoggingClient.Trace(fmt.Sprintf("Reading filtered out: %s", reading.Name))
+			continue
+		}
+
+		deadband.last[key] = forwardedReading{value: reading.Value, number: number, sent: now}
+		readings = append(readings, reading)
+	}
+
+	if len(readings) == 0 {
+		return false, nil
+	}
+
+	event.Readings = readings
+	return true, event
+}
+
+func (deadband *Deadband) changed(last forwardedReading, value string, number float64, numeric bool) bool {
+	if !numeric {
+		return value != last.value
+	}
+
+	limit := deadband.Deadband
+	if deadband.Percent {
+		limit = math.Abs(last.number) * deadband.Deadband / 100
+	}
+
+	return math.Abs(number-last.number) > limit
+}
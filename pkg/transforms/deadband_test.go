@@ -0,0 +1,172 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeadbandEvent(device string, values map[string]string) models.Event {
+	event := models.Event{Device: device}
+	for _, name := range []string{"temperature", "humidity", "status"} {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		valueType := models.ValueTypeFloat64
+		if name == "status" {
+			valueType = models.ValueTypeString
+		}
+		event.Readings = append(event.Readings, models.Reading{Device: device, Name: name, Value: value, ValueType: valueType, FloatEncoding: models.ENotation})
+	}
+	return event
+}
+
+func readingNames(event models.Event) []string {
+	var names []string
+	for _, reading := range event.Readings {
+		names = append(names, reading.Name)
+	}
+	return names
+}
+
+func TestNewDeadbandErrors(t *testing.T) {
+	_, err := NewDeadband(-1, false, "")
+	assert.Error(t, err)
+
+	_, err = NewDeadband(1, false, "abc")
+	assert.Error(t, err)
+
+	_, err = NewDeadband(1, false, "-1m")
+	assert.Error(t, err)
+}
+
+func TestFilterByDeadband(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Deadband float64
+		Percent  bool
+		Values   []map[string]string
+		Expected [][]string
+	}{
+		{
+			Name:     "Absolute",
+			Deadband: 0.5,
+			Values: []map[string]string{
+				{"temperature": "20.0", "humidity": "40"},
+				{"temperature": "20.5", "humidity": "41"},
+				{"temperature": "20.6", "humidity": "41.2"},
+				{"temperature": "20.7", "humidity": "41.3"},
+			},
+			Expected: [][]string{{"temperature", "humidity"}, {"humidity"}, {"temperature"}, nil},
+		},
+		{
+			Name:     "Percent",
+			Deadband: 10,
+			Percent:  true,
+			Values: []map[string]string{
+				{"temperature": "100"},
+				{"temperature": "109"},
+				{"temperature": "89"},
+				{"temperature": "80"},
+			},
+			Expected: [][]string{{"temperature"}, nil, {"temperature"}, {"temperature"}},
+		},
+		{
+			Name: "Non-numeric",
+			Values: []map[string]string{
+				{"status": "on"},
+				{"status": "on"},
+				{"status": "off"},
+			},
+			Expected: [][]string{{"status"}, nil, {"status"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			deadband, err := NewDeadband(test.Deadband, test.Percent, "")
+			require.NoError(t, err)
+
+			for index, values := range test.Values {
+				continuePipeline, result := deadband.FilterByDeadband(context, newDeadbandEvent(devID1, values))
+				if test.Expected[index] == nil {
+					assert.False(t, continuePipeline, "event %d should be dropped", index)
+					assert.Nil(t, result)
+					continue
+				}
+
+				require.True(t, continuePipeline, "event %d should be forwarded", index)
+				assert.Equal(t, test.Expected[index], readingNames(result.(models.Event)))
+			}
+		})
+	}
+}
+
+func TestFilterByDeadbandPerDevice(t *testing.T) {
+	deadband, err := NewDeadband(1, false, "")
+	require.NoError(t, err)
+
+	continuePipeline, _ := deadband.FilterByDeadband(context, newDeadbandEvent(devID1, map[string]string{"temperature": "20"}))
+	assert.True(t, continuePipeline)
+
+	continuePipeline, _ = deadband.FilterByDeadband(context, newDeadbandEvent(devID2, map[string]string{"temperature": "20"}))
+	assert.True(t, continuePipeline, "first value of another device should be forwarded")
+}
+
+func TestFilterByDeadbandHeartbeat(t *testing.T) {
+	deadband, err := NewDeadband(1, false, "1m")
+	require.NoError(t, err)
+
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	deadband.now = clock.now
+	event := newDeadbandEvent(devID1, map[string]string{"temperature": "20"})
+
+	continuePipeline, _ := deadband.FilterByDeadband(context, event)
+	assert.True(t, continuePipeline)
+
+	clock.current = clock.current.Add(30 * time.Second)
+	continuePipeline, _ = deadband.FilterByDeadband(context, event)
+	assert.False(t, continuePipeline)
+
+	clock.current = clock.current.Add(30 * time.Second)
+	continuePipeline, _ = deadband.FilterByDeadband(context, event)
+	assert.True(t, continuePipeline, "unchanged reading should be forwarded after the heartbeat interval")
+
+	clock.current = clock.current.Add(time.Second)
+	continuePipeline, _ = deadband.FilterByDeadband(context, event)
+	assert.False(t, continuePipeline, "heartbeat should restart when the reading is forwarded")
+}
+
+func TestFilterByDeadbandErrors(t *testing.T) {
+	deadband, err := NewDeadband(1, false, "")
+	require.NoError(t, err)
+
+	continuePipeline, result := deadband.FilterByDeadband(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = deadband.FilterByDeadband(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
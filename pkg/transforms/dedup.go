@@ -0,0 +1,349 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// Keys which can be used to identify duplicate Events
+const (
+	DeduplicationKeyEventID       = "eventid"
+	DeduplicationKeyChecksum      = "checksum"
+	DeduplicationKeyCorrelationID = "correlationid"
+	DeduplicationKeyHash          = "hash"
+)
+
+// DefaultDeduplicationMaxEntries is the number of keys remembered when no maximum is specified
+const DefaultDeduplicationMaxEntries = 10000
+
+type deduplicationEntry struct {
+	Key     string
+	Expires int64
+}
+
+// deduplicationOperation is a change to the persisted keys, which is applied to the store outside of the lock.
+// The id is only set when removing a persisted key which was loaded, otherwise it is looked up by key.
+type deduplicationOperation struct {
+	entry  deduplicationEntry
+	remove bool
+	id     string
+}
+
+// Deduplication drops Events which have already been seen within the window. The keys seen are kept in memory,
+// bounded by MaxEntries with the oldest keys forgotten first. When a StoreKey is specified and Store and Forward
+// is enabled the keys are also persisted to the Store and Forward database under the StoreKey, so duplicates are
+// still caught after the service restarts. The keys are persisted in batches outside of the lock, by one goroutine
+// at a time, so Events are never held up waiting on the store operations of other Events.
+type Deduplication struct {
+	Window     time.Duration
+	KeyType    string
+	MaxEntries int
+	StoreKey   string
+
+	// entries holds the deduplicationEntry of each key in the order seen, which is also the order they expire
+	entries *list.List
+	keys    map[string]*list.Element
+	loaded  bool
+	lock    sync.Mutex
+	// pending are the operations waiting to be applied to the store, in order
+	pending []deduplicationOperation
+	// persisting is set while a goroutine applies the pending operations
+	persisting bool
+	// ids are the ids of the persisted keys, only used while applying the operations or loading
+	ids map[string]string
+	now func() time.Time
+}
+
+// NewDeduplication creates, initializes and returns a new instance of Deduplication which drops Events whose
+// key, one of eventid, checksum, correlationid or hash, has been seen within the window, i.e. "10m". When
+// maxEntries is zero DefaultDeduplicationMaxEntries is used.
+func NewDeduplication(window string, keyType string, maxEntries int) (*Deduplication, error) {
+	return NewPersistentDeduplication(window, keyType, maxEntries, "")
+}
+
+// NewPersistentDeduplication creates, initializes and returns a new instance of Deduplication which persists
+// the keys seen under the storeKey. The storeKey must not be the service key, which is used by Store and Forward
+// for the data to retry.
+func NewPersistentDeduplication(window string, keyType string, maxEntries int, storeKey string) (*Deduplication, error) {
+	parsedWindow, err := time.ParseDuration(window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid deduplication window '%s': %s", window, err.Error())
+	}
+
+	if parsedWindow <= 0 {
+		return nil, errors.New("deduplication window must be greater than zero")
+	}
+
+	switch keyType {
+	case DeduplicationKeyEventID, DeduplicationKeyChecksum, DeduplicationKeyCorrelationID, DeduplicationKeyHash:
+	default:
+		return nil, fmt.Errorf("unknown deduplication key '%s'", keyType)
+	}
+
+	if maxEntries < 0 {
+		return nil, errors.New("maximum entries must not be negative")
+	}
+
+	if maxEntries == 0 {
+		maxEntries = DefaultDeduplicationMaxEntries
+	}
+
+	return &Deduplication{
+		Window:     parsedWindow,
+		KeyType:    keyType,
+		MaxEntries: maxEntries,
+		StoreKey:   storeKey,
+		entries:    list.New(),
+		keys:       make(map[string]*list.Element),
+		ids:        make(map[string]string),
+		now:        time.Now,
+	}, nil
+}
+
+// Deduplicate stops the pipeline when the key of the received data has already been seen within the window,
+// otherwise the data is passed on unchanged. Data without a key, i.e. no correlation ID, is always passed on.
+// It will return an error and stop the pipeline if no data is received or if the hash key is used and a
+// non-edgex event is received.
+func (dedup *Deduplication) Deduplicate(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Data Received")
+	}
+
+	key, err := dedup.key(edgexcontext, params[0])
+	if err != nil {
+		return false, err
+	}
+
+	if len(key) == 0 {
+		edgexcontext.LoggingClient.Debug("No " + dedup.KeyType + " key available for deduplication, passing data on")
+		return true, params[0]
+	}
+
+	persist := len(dedup.StoreKey) > 0 && edgexcontext.PersistentStore != nil
+	duplicate := dedup.seen(edgexcontext, key, persist)
+
+	if persist {
+		dedup.persistPending(edgexcontext.PersistentStore, edgexcontext.LoggingClient)
+	}
+
+	if duplicate {
+		edgexcontext.LoggingClient.Debug("Dropping duplicate data", clients.CorrelationHeader, edgexcontext.CorrelationID)
+		return false, nil
+	}
+
+	return true, params[0]
+}
+
+// seen returns true when the key has been seen within the window, otherwise the key is remembered
+func (dedup *Deduplication) seen(edgexcontext *appcontext.Context, key string, persist bool) bool {
+	dedup.lock.Lock()
+	defer dedup.lock.Unlock()
+
+	if len(dedup.StoreKey) > 0 && !dedup.loaded {
+		dedup.loaded = true
+		if persist {
+			dedup.load(edgexcontext)
+		} else {
+			edgexcontext.LoggingClient.Warn("Deduplication keys will not be persisted since StoreAndForward is not enabled")
+		}
+	}
+
+	now := dedup.now().UnixNano()
+	dedup.evict(now, persist)
+
+	if _, ok := dedup.keys[key]; ok {
+		return true
+	}
+
+	entry := deduplicationEntry{Key: key, Expires: now + int64(dedup.Window)}
+	dedup.keys[key] = dedup.entries.PushBack(entry)
+
+	// Make room for the new key
+	dedup.evict(now, persist)
+
+	if persist {
+		dedup.pending = append(dedup.pending, deduplicationOperation{entry: entry})
+	}
+
+	return false
+}
+
+func (dedup *Deduplication) key(edgexcontext *appcontext.Context, data interface{}) (string, error) {
+	switch dedup.KeyType {
+	case DeduplicationKeyEventID:
+		if event, ok := data.(models.Event); ok && len(event.ID) > 0 {
+			return event.ID, nil
+		}
+		return edgexcontext.EventID, nil
+
+	case DeduplicationKeyChecksum:
+		return edgexcontext.EventChecksum, nil
+
+	case DeduplicationKeyCorrelationID:
+		return edgexcontext.CorrelationID, nil
+
+	default:
+		event, ok := data.(models.Event)
+		if !ok {
+			return "", errors.New("Unexpected type received")
+		}
+		return hashEvent(event), nil
+	}
+}
+
+// hashEvent returns the SHA-256 hash of the Event's device, origin and readings
+func hashEvent(event models.Event) string {
+	hash := sha256.New()
+
+	write := func(values ...string) {
+		for _, value := range values {
+			// The length prefix prevents different values from hashing the same when concatenated
+			hash.Write([]byte(strconv.Itoa(len(value)) + ":" + value))
+		}
+	}
+
+	write(event.Device, strconv.FormatInt(event.Origin, 10))
+	for _, reading := range event.Readings {
+		write(reading.Name, reading.ValueType, reading.Value, string(reading.BinaryValue))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// evict removes the expired keys and the oldest keys beyond MaxEntries
+func (dedup *Deduplication) evict(now int64, persist bool) {
+	for element := dedup.entries.Front(); element != nil; element = dedup.entries.Front() {
+		entry := element.Value.(deduplicationEntry)
+		if entry.Expires > now && dedup.entries.Len() <= dedup.MaxEntries {
+			return
+		}
+
+		dedup.entries.Remove(element)
+		delete(dedup.keys, entry.Key)
+
+		if persist {
+			dedup.pending = append(dedup.pending, deduplicationOperation{entry: entry, remove: true})
+		}
+	}
+}
+
+// load adds the persisted keys which haven't expired and queues the removal of those which have. It is called
+// with the lock held before any operations are queued, so no operations are being applied.
+func (dedup *Deduplication) load(edgexcontext *appcontext.Context) {
+	persisted, err := edgexcontext.PersistentStore.Retrieve(dedup.StoreKey)
+	if err != nil {
+		edgexcontext.LoggingClient.Error("Unable to load persisted deduplication keys", "error", err)
+		return
+	}
+
+	now := dedup.now().UnixNano()
+	var entries []deduplicationEntry
+
+	for id, data := range persisted {
+		var entry deduplicationEntry
+		if err := json.Unmarshal(data, &entry); err != nil || entry.Expires <= now {
+			dedup.pending = append(dedup.pending, deduplicationOperation{remove: true, id: id})
+			continue
+		}
+		dedup.ids[entry.Key] = id
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Expires < entries[j].Expires })
+
+	for _, entry := range entries {
+		if _, ok := dedup.keys[entry.Key]; !ok {
+			dedup.keys[entry.Key] = dedup.entries.PushBack(entry)
+		}
+	}
+
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Loaded %d persisted deduplication keys", len(dedup.keys)))
+}
+
+// persistPending applies the pending operations to the store, without holding the lock while doing so. When another
+// goroutine is already applying them it returns straight away, since that goroutine also applies those queued
+// meanwhile.
+func (dedup *Deduplication) persistPending(store appcontext.PersistentStore, loggingClient logger.LoggingClient) {
+	dedup.lock.Lock()
+	if dedup.persisting {
+		dedup.lock.Unlock()
+		return
+	}
+
+	dedup.persisting = true
+	for len(dedup.pending) > 0 {
+		operations := dedup.pending
+		dedup.pending = nil
+		dedup.lock.Unlock()
+
+		for _, operation := range operations {
+			if err := dedup.apply(store, operation); err != nil {
+				loggingClient.Error("Unable to persist deduplication key", "error", err)
+			}
+		}
+
+		dedup.lock.Lock()
+	}
+
+	dedup.persisting = false
+	dedup.lock.Unlock()
+}
+
+func (dedup *Deduplication) apply(store appcontext.PersistentStore, operation deduplicationOperation) error {
+	if operation.remove {
+		id := operation.id
+		if len(id) == 0 {
+			id = dedup.ids[operation.entry.Key]
+			delete(dedup.ids, operation.entry.Key)
+		}
+
+		// No id when persisting the key failed
+		if len(id) == 0 {
+			return nil
+		}
+
+		return store.Remove(dedup.StoreKey, id)
+	}
+
+	data, err := json.Marshal(operation.entry)
+	if err != nil {
+		return err
+	}
+
+	id, err := store.Store(dedup.StoreKey, data)
+	if err != nil {
+		return err
+	}
+
+	dedup.ids[operation.entry.Key] = id
+	return nil
+}
@@ -0,0 +1,315 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePersistentStore keeps the persisted data in memory, keyed by store key and then id. When block is set Store
+// waits for it to be closed.
+type fakePersistentStore struct {
+	data   map[string]map[string][]byte
+	nextID int
+	block  chan struct{}
+	lock   sync.Mutex
+}
+
+func newFakePersistentStore() *fakePersistentStore {
+	return &fakePersistentStore{data: make(map[string]map[string][]byte)}
+}
+
+func (store *fakePersistentStore) Store(storeKey string, data []byte) (string, error) {
+	if store.block != nil {
+		<-store.block
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if store.data[storeKey] == nil {
+		store.data[storeKey] = make(map[string][]byte)
+	}
+	store.nextID++
+	id := strconv.Itoa(store.nextID)
+	store.data[storeKey][id] = data
+	return id, nil
+}
+
+func (store *fakePersistentStore) Retrieve(storeKey string) (map[string][]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	data := make(map[string][]byte)
+	for id, item := range store.data[storeKey] {
+		data[id] = item
+	}
+	return data, nil
+}
+
+func (store *fakePersistentStore) Remove(storeKey string, id string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	if _, ok := store.data[storeKey][id]; !ok {
+		return errors.New("not found")
+	}
+	delete(store.data[storeKey], id)
+	return nil
+}
+
+func (store *fakePersistentStore) persisted(storeKey string) []string {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	var data []string
+	for _, item := range store.data[storeKey] {
+		data = append(data, string(item))
+	}
+	sort.Strings(data)
+	return data
+}
+
+func newDeduplicationContext(correlationID string) *appcontext.Context {
+	return &appcontext.Context{
+		CorrelationID: correlationID,
+		LoggingClient: logClient,
+	}
+}
+
+func TestNewDeduplicationErrors(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Window     string
+		KeyType    string
+		MaxEntries int
+	}{
+		{"Bad window", "abc", DeduplicationKeyHash, 0},
+		{"Zero window", "0s", DeduplicationKeyHash, 0},
+		{"Bad key", "1m", "device", 0},
+		{"Negative max entries", "1m", DeduplicationKeyHash, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewDeduplication(test.Window, test.KeyType, test.MaxEntries)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDeduplicateByHash(t *testing.T) {
+	dedup, err := NewDeduplication("1m", DeduplicationKeyHash, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDeduplicationMaxEntries, dedup.MaxEntries)
+
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	dedup.now = clock.now
+
+	event := models.Event{
+		Device:   devID1,
+		Origin:   1000,
+		Readings: []models.Reading{{Name: "temperature", Value: "21", ValueType: models.ValueTypeInt32}},
+	}
+	changed := event
+	changed.Readings = []models.Reading{{Name: "temperature", Value: "22", ValueType: models.ValueTypeInt32}}
+
+	continuePipeline, result := dedup.Deduplicate(context, event)
+	assert.True(t, continuePipeline)
+	assert.Equal(t, event, result)
+
+	continuePipeline, result = dedup.Deduplicate(context, event)
+	assert.False(t, continuePipeline, "duplicate event should stop the pipeline")
+	assert.Nil(t, result)
+
+	continuePipeline, _ = dedup.Deduplicate(context, changed)
+	assert.True(t, continuePipeline, "event with different readings is not a duplicate")
+
+	// The key expires after the window
+	clock.current = clock.current.Add(time.Minute)
+	continuePipeline, _ = dedup.Deduplicate(context, event)
+	assert.True(t, continuePipeline, "event should no longer be a duplicate after the window")
+}
+
+func TestDeduplicateByContextKeys(t *testing.T) {
+	tests := []struct {
+		Name    string
+		KeyType string
+		Context func(value string) *appcontext.Context
+	}{
+		{"Event ID", DeduplicationKeyEventID, func(value string) *appcontext.Context {
+			ctx := newDeduplicationContext("")
+			ctx.EventID = value
+			return ctx
+		}},
+		{"Checksum", DeduplicationKeyChecksum, func(value string) *appcontext.Context {
+			ctx := newDeduplicationContext("")
+			ctx.EventChecksum = value
+			return ctx
+		}},
+		{"Correlation ID", DeduplicationKeyCorrelationID, func(value string) *appcontext.Context {
+			return newDeduplicationContext(value)
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dedup, err := NewDeduplication("1m", test.KeyType, 0)
+			require.NoError(t, err)
+
+			continuePipeline, _ := dedup.Deduplicate(test.Context("abc"), []byte(plainString))
+			assert.True(t, continuePipeline)
+
+			continuePipeline, _ = dedup.Deduplicate(test.Context("abc"), []byte(plainString))
+			assert.False(t, continuePipeline)
+
+			continuePipeline, _ = dedup.Deduplicate(test.Context("def"), []byte(plainString))
+			assert.True(t, continuePipeline)
+
+			// Data without a key is always passed on
+			continuePipeline, _ = dedup.Deduplicate(test.Context(""), []byte(plainString))
+			assert.True(t, continuePipeline)
+			continuePipeline, _ = dedup.Deduplicate(test.Context(""), []byte(plainString))
+			assert.True(t, continuePipeline)
+		})
+	}
+}
+
+func TestDeduplicateMaxEntries(t *testing.T) {
+	dedup, err := NewDeduplication("1h", DeduplicationKeyCorrelationID, 2)
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b", "c"} {
+		continuePipeline, _ := dedup.Deduplicate(newDeduplicationContext(key), plainString)
+		assert.True(t, continuePipeline)
+	}
+
+	assert.Equal(t, 2, dedup.entries.Len())
+
+	// The oldest key has been forgotten
+	continuePipeline, _ := dedup.Deduplicate(newDeduplicationContext("a"), plainString)
+	assert.True(t, continuePipeline)
+	continuePipeline, _ = dedup.Deduplicate(newDeduplicationContext("c"), plainString)
+	assert.False(t, continuePipeline)
+}
+
+func TestDeduplicatePersisted(t *testing.T) {
+	store := newFakePersistentStore()
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+
+	newContext := func(correlationID string) *appcontext.Context {
+		ctx := newDeduplicationContext(correlationID)
+		ctx.PersistentStore = store
+		return ctx
+	}
+
+	first, err := NewPersistentDeduplication("1m", DeduplicationKeyCorrelationID, 0, "test-dedup")
+	require.NoError(t, err)
+	first.now = clock.now
+
+	continuePipeline, _ := first.Deduplicate(newContext("abc"), plainString)
+	assert.True(t, continuePipeline)
+	require.Len(t, store.persisted("test-dedup"), 1)
+
+	// A new instance, as after a restart, loads the persisted keys
+	second, err := NewPersistentDeduplication("1m", DeduplicationKeyCorrelationID, 0, "test-dedup")
+	require.NoError(t, err)
+	second.now = clock.now
+
+	continuePipeline, _ = second.Deduplicate(newContext("abc"), plainString)
+	assert.False(t, continuePipeline, "persisted key should be a duplicate after restart")
+
+	// Expired keys are removed from the store
+	clock.current = clock.current.Add(time.Minute)
+	continuePipeline, _ = second.Deduplicate(newContext("def"), plainString)
+	assert.True(t, continuePipeline)
+	persisted := store.persisted("test-dedup")
+	require.Len(t, persisted, 1)
+	assert.Contains(t, persisted[0], "def")
+
+	// Keys evicted by a newer instance are removed using the loaded ids
+	clock.current = clock.current.Add(time.Minute)
+	third, err := NewPersistentDeduplication("1m", DeduplicationKeyCorrelationID, 0, "test-dedup")
+	require.NoError(t, err)
+	third.now = clock.now
+	continuePipeline, _ = third.Deduplicate(newContext("ghi"), plainString)
+	assert.True(t, continuePipeline)
+	persisted = store.persisted("test-dedup")
+	require.Len(t, persisted, 1)
+	assert.Contains(t, persisted[0], "ghi")
+}
+
+func TestDeduplicatePersistedOutsideLock(t *testing.T) {
+	store := newFakePersistentStore()
+	store.block = make(chan struct{})
+
+	newContext := func(correlationID string) *appcontext.Context {
+		ctx := newDeduplicationContext(correlationID)
+		ctx.PersistentStore = store
+		return ctx
+	}
+
+	dedup, err := NewPersistentDeduplication("1m", DeduplicationKeyCorrelationID, 0, "test-dedup")
+	require.NoError(t, err)
+
+	done := make(chan bool)
+	go func() {
+		continuePipeline, _ := dedup.Deduplicate(newContext("abc"), plainString)
+		done <- continuePipeline
+	}()
+
+	// Wait for the first key to be persisting, which is blocked
+	require.Eventually(t, func() bool {
+		dedup.lock.Lock()
+		defer dedup.lock.Unlock()
+		return dedup.persisting
+	}, time.Second, time.Millisecond)
+
+	// Other Events aren't held up by the store
+	continuePipeline, _ := dedup.Deduplicate(newContext("def"), plainString)
+	assert.True(t, continuePipeline)
+	continuePipeline, _ = dedup.Deduplicate(newContext("abc"), plainString)
+	assert.False(t, continuePipeline)
+
+	close(store.block)
+	assert.True(t, <-done)
+
+	// The goroutine persisting also persists the keys queued meanwhile
+	persisted := store.persisted("test-dedup")
+	require.Len(t, persisted, 2)
+	assert.Contains(t, persisted[0], "abc")
+	assert.Contains(t, persisted[1], "def")
+}
+
+func TestDeduplicateErrors(t *testing.T) {
+	dedup, err := NewDeduplication("1m", DeduplicationKeyHash, 0)
+	require.NoError(t, err)
+
+	continuePipeline, result := dedup.Deduplicate(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Data Received")
+
+	continuePipeline, result = dedup.Deduplicate(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
@@ -0,0 +1,56 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+type Encoding struct {
+}
+
+// NewEncoding creates, initializes and returns a new instance of Encoding
+func NewEncoding() Encoding {
+	return Encoding{}
+}
+
+// DecodeBase64 decodes base64 encoded data received as either a string or []byte and returns the decoded data
+// as a []byte. Leading and trailing whitespace, such as a trailing newline, is ignored.
+func (encoding Encoding) DecodeBase64(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		// We didn't receive a result
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Decoding base64")
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return false, fmt.Errorf("unable to decode base64 data: %s", err.Error())
+	}
+
+	return true, decoded
+}
@@ -0,0 +1,61 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeBase64(t *testing.T) {
+	encoding := NewEncoding()
+
+	tests := []struct {
+		Name          string
+		Data          interface{}
+		Expected      string
+		ErrorExpected bool
+	}{
+		{"String", "VGVzdCBkYXRh", "Test data", false},
+		{"Bytes with trailing newline", []byte("VGVzdCBkYXRh\n"), "Test data", false},
+		{"Invalid", "not base64!", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			continuePipeline, result := encoding.DecodeBase64(context, test.Data)
+			if test.ErrorExpected {
+				assert.False(t, continuePipeline)
+				require.Error(t, result.(error))
+				return
+			}
+
+			require.True(t, continuePipeline)
+			assert.Equal(t, test.Expected, string(result.([]byte)))
+		})
+	}
+}
+
+func TestDecodeBase64NoData(t *testing.T) {
+	encoding := NewEncoding()
+
+	continuePipeline, result := encoding.DecodeBase64(context)
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "No Data Received", result.(error).Error())
+}
@@ -54,6 +54,9 @@ func pkcs5Padding(ciphertext []byte, blockSize int) []byte {
 
 // EncryptWithAES encrypts a string, []byte, or json.Marshaller type using AES encryption.
 // It will return a Base64 encode []byte of the encrypted data.
+//
+// Deprecated: The key is derived from the SHA-1 of the configured key and the IV is static.
+// Use AESGCM.EncryptWithAESGCM instead.
 func (aesData Encryption) EncryptWithAES(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 	if len(params) < 1 {
 		return false, errors.New("no data received to encrypt")
@@ -19,6 +19,7 @@ package transforms
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
@@ -138,3 +139,69 @@ func (f Filter) FilterByValueDescriptor(edgexcontext *appcontext.Context, params
 	}
 	return thereExistReadings, returnResult
 }
+
+// FilterByValueType filters for readings of certain value types, such as Int32, Float64, Bool and so forth.
+// Readings whose ValueType is not in FilterValues, compared case-insensitively, are removed. When FilterOut is true
+// the readings whose ValueType is in FilterValues are removed instead. The Event is dropped when no readings remain.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f Filter) FilterByValueType(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+
+	edgexcontext.LoggingClient.Debug("Filtering by ValueType")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	// No filter values, so pass all event and all readings thru, rather than filtering them all out.
+	if len(f.FilterValues) == 0 {
+		return true, event
+	}
+
+	return filterEventReadings(edgexcontext, event, f.FilterOut, func(reading models.Reading) bool {
+		for _, valueType := range f.FilterValues {
+			if strings.EqualFold(reading.ValueType, valueType) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// filterEventReadings keeps the Event's readings which match, or when filterOut is true those which don't match,
+// and drops the Event when no readings remain
+func filterEventReadings(edgexcontext *appcontext.Context, event models.Event, filterOut bool,
+	matches func(reading models.Reading) bool) (bool, interface{}) {
+	var readings []models.Reading
+
+	for _, reading := range event.Readings {
+		if matches(reading) != filterOut {
+			edgexcontext.LoggingClient.Trace(fmt.Sprintf("Reading accepted: %s", reading.Name))
+			readings = append(readings, reading)
+		} else {
+			edgexcontext.LoggingClient.Trace(fmt.Sprintf("Reading filtered out: %s", reading.Name))
+		}
+	}
+
+	if len(readings) == 0 {
+		return false, nil
+	}
+
+	event.Readings = readings
+	return true, event
+}
+
+// filterEvent passes on the Event when it matches, or when filterOut is true when it doesn't match
+func filterEvent(edgexcontext *appcontext.Context, event models.Event, filterOut bool, matches bool) (bool, interface{}) {
+	if matches == filterOut {
+		edgexcontext.LoggingClient.Trace(fmt.Sprintf("Event not accepted: %s", event.Device))
+		return false, nil
+	}
+
+	edgexcontext.LoggingClient.Trace(fmt.Sprintf("Event accepted: %s", event.Device))
+	return true, event
+}
@@ -273,3 +273,43 @@ func TestFilterByValueDescriptorExtraParameters(t *testing.T) {
 	assert.True(t, continuePipeline, "Pipeline should continue")
 	assert.Len(t, res.(models.Event).Readings, 1, "Event should have one reading")
 }
+
+func TestFilterByValueType(t *testing.T) {
+	eventIn := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: descriptor1, ValueType: models.ValueTypeInt32},
+			{Name: descriptor2, ValueType: models.ValueTypeFloat64},
+			{Name: descriptor3, ValueType: models.ValueTypeString},
+		},
+	}
+
+	tests := []struct {
+		Name         string
+		FilterValues []string
+		FilterOut    bool
+		Expected     []string
+	}{
+		{"Filter for", []string{"int32", "Float64"}, false, []string{descriptor1, descriptor2}},
+		{"Filter out", []string{models.ValueTypeString}, true, []string{descriptor1, descriptor2}},
+		{"No filter values", nil, false, []string{descriptor1, descriptor2, descriptor3}},
+		{"No match", []string{models.ValueTypeBool}, false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter := NewFilter(test.FilterValues)
+			filter.FilterOut = test.FilterOut
+
+			continuePipeline, result := filter.FilterByValueType(context, eventIn)
+			if test.Expected == nil {
+				assert.False(t, continuePipeline)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.True(t, continuePipeline)
+			assert.Equal(t, test.Expected, readingNames(result.(models.Event)))
+		})
+	}
+}
@@ -29,6 +29,9 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
 )
 
+// forwardedHeaderKeys are the context values which are sent as HTTP headers when present
+var forwardedHeaderKeys = []string{ContentEncodingKey, SignatureKey, SignatureAlgorithmKey}
+
 // HTTPSender ...
 type HTTPSender struct {
 	URL              string
@@ -48,14 +51,13 @@ func NewHTTPSender(url string, mimeType string, persistOnError bool) HTTPSender
 }
 
 // NewHTTPSenderWithSecretHeader creates, initializes and returns a new instance of HTTPSender configured to use a secret header
-func NewHTTPSenderWithSecretHeader(url string, mimeType string, persistOnError bool, httpHeaderSecretName1 string, httpHeaderSecretName2 string, secretPath string) HTTPSender {
+func NewHTTPSenderWithSecretHeader(url string, mimeType string, persistOnError bool, httpHeaderSecretName string, secretPath string) HTTPSender {
 	return HTTPSender{
-		URL:               url,
-		MimeType:          mimeType,
-		PersistOnError:    persistOnError,
-		SecretHeaderName1: httpHeaderSecretName1,
-		SecretHeaderName2: httpHeaderSecretName2,
-		SecretPath:        secretPath,
+		URL:              url,
+		MimeType:         mimeType,
+		PersistOnError:   persistOnError,
+		SecretHeaderName: httpHeaderSecretName,
+		SecretPath:       secretPath,
 	}
 }
 
@@ -100,16 +102,21 @@ func (sender HTTPSender) httpSend(edgexcontext *appcontext.Context, params []int
 	}
 	var theSecrets map[string]string
 	if usingSecrets {
-		theSecrets, err = edgexcontext.GetSecrets(sender.SecretPath, sender.SecretHeaderName1, sender.SecretHeaderName2)
+		theSecrets, err = edgexcontext.GetSecrets(sender.SecretPath, sender.SecretHeaderName)
 		if err != nil {
 			return false, err
 		}
-		req.Header.Set(sender.SecretHeaderName1, theSecrets[sender.SecretHeaderName1])
-		req.Header.Set(sender.SecretHeaderName2, theSecrets[sender.SecretHeaderName2])
+		req.Header.Set(sender.SecretHeaderName, theSecrets[sender.SecretHeaderName])
 	}
 
 	req.Header.Set("Content-Type", sender.MimeType)
 
+	for _, key := range forwardedHeaderKeys {
+		if value, ok := edgexcontext.GetValue(key); ok {
+			req.Header.Set(key, value)
+		}
+	}
+
 	edgexcontext.LoggingClient.Debug("POSTing data")
 	response, err := client.Do(req)
 	if err != nil {
@@ -138,15 +145,15 @@ func (sender HTTPSender) httpSend(edgexcontext *appcontext.Context, params []int
 
 func (sender HTTPSender) determineIfUsingSecrets() (bool, error) {
 	//check if one field but not others are provided for secrets
-	if sender.SecretPath != "" && sender.SecretHeaderName1 == "" {
+	if sender.SecretPath != "" && sender.SecretHeaderName == "" {
 		return false, errors.New("SecretPath was specified but no header name was provided")
 	}
-	if sender.SecretHeaderName1 != "" && sender.SecretPath == "" {
+	if sender.SecretHeaderName != "" && sender.SecretPath == "" {
 		return false, errors.New("HTTP Header Secret Name was provided but no SecretPath was provided")
 	}
 
 	// not using secrets if both are blank
-	if sender.SecretHeaderName1 == "" && sender.SecretPath == "" {
+	if sender.SecretHeaderName == "" && sender.SecretPath == "" {
 		return false, nil
 	}
 	// using secrets, all required fields are provided
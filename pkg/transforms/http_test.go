@@ -190,6 +190,23 @@ func TestHTTPPostPutWithSecrets(t *testing.T) {
 	}
 }
 
+func TestHTTPPostWithContentEncoding(t *testing.T) {
+	var receivedEncoding string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedEncoding = r.Header.Get(ContentEncodingKey)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	context.AddValue(ContentEncodingKey, ContentEncodingGZIP)
+	defer context.RemoveValue(ContentEncodingKey)
+
+	sender := NewHTTPSender(ts.URL+path, "application/octet-stream", false)
+	continuePipeline, _ := sender.HTTPPost(context, []byte("compressed"))
+	assert.True(t, continuePipeline, "Pipeline should continue")
+	assert.Equal(t, ContentEncodingGZIP, receivedEncoding)
+}
+
 func TestHTTPPostNoParameterPassed(t *testing.T) {
 	sender := NewHTTPSender("", "", false)
 	continuePipeline, result := sender.HTTPPost(context)
@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// EventJoiner correlates the Events from a set of devices whose origins are within a time tolerance of each other
+// and merges them into a single Event
+type EventJoiner struct {
+	Devices   []string
+	Tolerance time.Duration
+	// DeviceName is the device of the merged Events
+	DeviceName string
+
+	// events holds the latest Event received from each device
+	events map[string]models.Event
+	lock   sync.Mutex
+	now    func() time.Time
+}
+
+// NewEventJoiner creates, initializes and returns a new instance of EventJoiner which merges the Events of the devices,
+// of which there must be at least two, whose origins are within the tolerance, i.e. "500ms", into an Event for the
+// deviceName
+func NewEventJoiner(devices []string, tolerance string, deviceName string) (*EventJoiner, error) {
+	if len(devices) < 2 {
+		return nil, errors.New("at least two devices must be specified to join Events")
+	}
+
+	seen := make(map[string]bool)
+	for _, device := range devices {
+		if seen[device] {
+			return nil, fmt.Errorf("device '%s' specified more than once", device)
+		}
+		seen[device] = true
+	}
+
+	parsedTolerance, err := time.ParseDuration(tolerance)
+	if err != nil {
+		return nil, fmt.Errorf("invalid join tolerance '%s': %s", tolerance, err.Error())
+	}
+
+	if parsedTolerance < 0 {
+		return nil, errors.New("join tolerance must not be negative")
+	}
+
+	if len(deviceName) == 0 {
+		return nil, errors.New("device name of the joined Events must be specified")
+	}
+
+	return &EventJoiner{
+		Devices:    devices,
+		Tolerance:  parsedTolerance,
+		DeviceName: deviceName,
+		events:     make(map[string]models.Event),
+		now:        time.Now,
+	}, nil
+}
+
+// JoinEvents holds the latest Event from each of the Devices until there is an Event from every device with origins
+// within the Tolerance, then returns an Event for the DeviceName containing all of their readings and tags, with the
+// latest of their origins. Otherwise the pipeline is stopped. Events held which are older than the Tolerance compared
+// to the received Event are discarded. Events from other devices are passed on unchanged. Events without an origin
+// are given the time received.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (joiner *EventJoiner) JoinEvents(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	if !joiner.isJoined(event.Device) {
+		return true, event
+	}
+
+	if event.Origin == 0 {
+		event.Origin = joiner.now().UnixNano()
+	}
+
+	joiner.lock.Lock()
+	defer joiner.lock.Unlock()
+
+	joiner.events[event.Device] = event
+
+	for device, held := range joiner.events {
+		if event.Origin-held.Origin > int64(joiner.Tolerance) {
+			delete(joiner.events, device)
+		}
+	}
+
+	if len(joiner.events) < len(joiner.Devices) {
+		edgexcontext.LoggingClient.Debug(fmt.Sprintf("Joining Events, holding %d of %d", len(joiner.events), len(joiner.Devices)))
+		return false, nil
+	}
+
+	var earliest, latest int64
+	for _, held := range joiner.events {
+		if earliest == 0 || held.Origin < earliest {
+			earliest = held.Origin
+		}
+		if held.Origin > latest {
+			latest = held.Origin
+		}
+	}
+
+	// Held Events can be later than the received Event when they arrive out of order
+	if latest-earliest > int64(joiner.Tolerance) {
+		edgexcontext.LoggingClient.Debug("Joining Events, held Events are not within the tolerance")
+		return false, nil
+	}
+
+	joined := models.Event{
+		Device: joiner.DeviceName,
+		Origin: latest,
+	}
+
+	for _, device := range joiner.Devices {
+		held := joiner.events[device]
+		joined.Readings = append(joined.Readings, held.Readings...)
+		for key, value := range held.Tags {
+			if joined.Tags == nil {
+				joined.Tags = make(map[string]string)
+			}
+			joined.Tags[key] = value
+		}
+	}
+
+	joiner.events = make(map[string]models.Event)
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Joined Events of %d devices", len(joiner.Devices)))
+
+	return true, joined
+}
+
+func (joiner *EventJoiner) isJoined(device string) bool {
+	for _, joined := range joiner.Devices {
+		if device == joined {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJoinEvent(device string, origin time.Duration, readingName string, tags map[string]string) models.Event {
+	return models.Event{
+		Device:   device,
+		Origin:   int64(origin),
+		Tags:     tags,
+		Readings: []models.Reading{{Device: device, Name: readingName, Value: "1"}},
+	}
+}
+
+func TestNewEventJoinerErrors(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Devices    []string
+		Tolerance  string
+		DeviceName string
+	}{
+		{"Single device", []string{devID1}, "1s", "joined"},
+		{"Duplicate device", []string{devID1, devID1}, "1s", "joined"},
+		{"Bad tolerance", []string{devID1, devID2}, "abc", "joined"},
+		{"Negative tolerance", []string{devID1, devID2}, "-1s", "joined"},
+		{"Missing device name", []string{devID1, devID2}, "1s", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewEventJoiner(test.Devices, test.Tolerance, test.DeviceName)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJoinEvents(t *testing.T) {
+	joiner, err := NewEventJoiner([]string{devID1, devID2}, "1s", "joined")
+	require.NoError(t, err)
+
+	continuePipeline, result := joiner.JoinEvents(context, newJoinEvent(devID1, 10*time.Second, "temperature", map[string]string{"site": "north"}))
+	assert.False(t, continuePipeline, "should wait for the other device")
+	assert.Nil(t, result)
+
+	continuePipeline, result = joiner.JoinEvents(context, newJoinEvent(devID2, 10*time.Second+500*time.Millisecond, "humidity", map[string]string{"line": "2"}))
+	require.True(t, continuePipeline)
+
+	joined := result.(models.Event)
+	assert.Equal(t, "joined", joined.Device)
+	assert.Equal(t, int64(10*time.Second+500*time.Millisecond), joined.Origin)
+	assert.Equal(t, []string{"temperature", "humidity"}, readingNames(joined))
+	assert.Equal(t, devID1, joined.Readings[0].Device, "readings should keep their device")
+	assert.Equal(t, map[string]string{"site": "north", "line": "2"}, joined.Tags)
+
+	// Joined Events are not held any longer
+	continuePipeline, _ = joiner.JoinEvents(context, newJoinEvent(devID2, 11*time.Second, "humidity", nil))
+	assert.False(t, continuePipeline)
+}
+
+func TestJoinEventsOutsideTolerance(t *testing.T) {
+	joiner, err := NewEventJoiner([]string{devID1, devID2}, "1s", "joined")
+	require.NoError(t, err)
+
+	continuePipeline, _ := joiner.JoinEvents(context, newJoinEvent(devID1, 10*time.Second, "temperature", nil))
+	assert.False(t, continuePipeline)
+
+	// Too late, so the held Event is discarded
+	continuePipeline, _ = joiner.JoinEvents(context, newJoinEvent(devID2, 12*time.Second, "humidity", nil))
+	assert.False(t, continuePipeline)
+
+	continuePipeline, result := joiner.JoinEvents(context, newJoinEvent(devID1, 12*time.Second+100*time.Millisecond, "temperature", nil))
+	require.True(t, continuePipeline)
+	assert.Equal(t, int64(12*time.Second+100*time.Millisecond), result.(models.Event).Origin)
+}
+
+func TestJoinEventsOtherDevice(t *testing.T) {
+	joiner, err := NewEventJoiner([]string{devID1, devID2}, "1s", "joined")
+	require.NoError(t, err)
+
+	event := newJoinEvent("other", time.Second, "temperature", nil)
+	continuePipeline, result := joiner.JoinEvents(context, event)
+	assert.True(t, continuePipeline)
+	assert.Equal(t, event, result)
+}
+
+func TestJoinEventsErrors(t *testing.T) {
+	joiner, err := NewEventJoiner([]string{devID1, devID2}, "1s", "joined")
+	require.NoError(t, err)
+
+	continuePipeline, result := joiner.JoinEvents(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = joiner.JoinEvents(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
@@ -0,0 +1,315 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+// FieldMapping maps the value selected by a JSONPath expression to a target field
+type FieldMapping struct {
+	// Path is the JSONPath expression selecting the value, i.e. $.sensors[0].temperature
+	Path string
+	// Target is the field the value is mapped to. For documents dots in the target create nested objects,
+	// for Events the target is the reading name.
+	Target string
+
+	path jsonPath
+}
+
+// NewFieldMapping creates, initializes and returns a new FieldMapping. An error is returned if the JSONPath
+// expression is invalid or the target is empty.
+func NewFieldMapping(path string, target string) (FieldMapping, error) {
+	parsed, err := parseJSONPath(path)
+	if err != nil {
+		return FieldMapping{}, err
+	}
+
+	target = strings.TrimSpace(target)
+	if len(target) == 0 {
+		return FieldMapping{}, fmt.Errorf("missing target field for JSONPath '%s'", path)
+	}
+
+	return FieldMapping{Path: parsed.expression, Target: target, path: parsed}, nil
+}
+
+// ParseFieldMappings parses a comma separated list of target=JSONPath mappings,
+// i.e. "temperature=$.sensor.temp, humidity=$.sensor.hum". Commas within quoted field names, i.e. $['a,b'], don't
+// separate mappings.
+func ParseFieldMappings(mappings string) ([]FieldMapping, error) {
+	var result []FieldMapping
+
+	for _, mapping := range util.DeleteEmptyAndTrim(splitFieldMappings(mappings)) {
+		parts := strings.SplitN(mapping, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid field mapping '%s', must be in the form target=JSONPath", mapping)
+		}
+
+		fieldMapping, err := NewFieldMapping(strings.TrimSpace(parts[1]), parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, fieldMapping)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no field mappings specified")
+	}
+
+	return result, nil
+}
+
+// splitFieldMappings splits the mappings on the commas which aren't within the quoted field name of a JSONPath
+// bracket segment. A quoted name ends at the quote followed by ']', as when the JSONPath is parsed.
+func splitFieldMappings(mappings string) []string {
+	var result []string
+	start := 0
+
+	for index := 0; index < len(mappings); index++ {
+		switch mappings[index] {
+		case '[':
+			remaining := mappings[index+1:]
+			if strings.HasPrefix(remaining, "'") || strings.HasPrefix(remaining, `"`) {
+				if end := strings.Index(remaining[1:], remaining[:1]+"]"); end >= 0 {
+					// Skip to the closing ']'
+					index += end + 3
+				}
+			}
+		case ',':
+			result = append(result, mappings[start:index])
+			start = index + 1
+		}
+	}
+
+	return append(result, mappings[start:])
+}
+
+// JSONMapper extracts fields from arbitrary JSON using JSONPath expressions and builds a new JSON document or
+// an EdgeX Event from them. Mappings whose path isn't found in the data are omitted.
+type JSONMapper struct {
+	Mappings []FieldMapping
+	// DeviceName is the device of the Events built by MapToEvent. When it is a JSONPath expression,
+	// i.e. starts with '$', the device name is extracted from the data.
+	DeviceName string
+}
+
+// NewJSONMapper creates, initializes and returns a new instance of JSONMapper
+func NewJSONMapper(mappings []FieldMapping, deviceName string) (*JSONMapper, error) {
+	if len(mappings) == 0 {
+		return nil, errors.New("no field mappings specified")
+	}
+
+	if strings.HasPrefix(deviceName, "$") {
+		if _, err := parseJSONPath(deviceName); err != nil {
+			return nil, err
+		}
+	}
+
+	return &JSONMapper{
+		Mappings:   mappings,
+		DeviceName: deviceName,
+	}, nil
+}
+
+// MapToJSON builds a new JSON document from the fields extracted from the JSON data.
+// It will return an error and stop the pipeline if the data isn't JSON or none of the mapped fields are found.
+func (mapper *JSONMapper) MapToJSON(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Mapping JSON fields to document")
+
+	document, err := unmarshalJSONDocument(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	result := make(map[string]interface{})
+	found := 0
+
+	for _, mapping := range mapper.Mappings {
+		value, ok := mapping.path.evaluate(document)
+		if !ok {
+			continue
+		}
+
+		if err := setNestedField(result, mapping.Target, value); err != nil {
+			return false, err
+		}
+		found++
+	}
+
+	if found == 0 {
+		return false, errors.New("none of the mapped fields were found in the data")
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return false, fmt.Errorf("unable to marshal mapped document: %s", err.Error())
+	}
+
+	edgexcontext.ResponseContentType = clients.ContentTypeJSON
+	return true, data
+}
+
+// MapToEvent builds an EdgeX Event from the fields extracted from the JSON data, with a reading for each
+// field found. The reading name is the mapping's target and the value type is determined from the JSON value.
+// It will return an error and stop the pipeline if the data isn't JSON or none of the mapped fields are found.
+func (mapper *JSONMapper) MapToEvent(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Mapping JSON fields to Event")
+
+	document, err := unmarshalJSONDocument(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	deviceName := mapper.DeviceName
+	if strings.HasPrefix(deviceName, "$") {
+		// Validated by NewJSONMapper
+		path, _ := parseJSONPath(deviceName)
+		value, ok := path.evaluate(document)
+		if !ok {
+			return false, fmt.Errorf("device name not found at '%s'", mapper.DeviceName)
+		}
+		deviceName = fmt.Sprintf("%v", value)
+	}
+
+	origin := time.Now().UnixNano()
+	event := models.Event{
+		Device: deviceName,
+		Origin: origin,
+	}
+
+	for _, mapping := range mapper.Mappings {
+		value, ok := mapping.path.evaluate(document)
+		if !ok {
+			continue
+		}
+
+		reading, err := newMappedReading(deviceName, mapping.Target, value)
+		if err != nil {
+			return false, err
+		}
+		reading.Origin = origin
+
+		event.Readings = append(event.Readings, reading)
+	}
+
+	if len(event.Readings) == 0 {
+		return false, errors.New("none of the mapped fields were found in the data")
+	}
+
+	return true, event
+}
+
+func unmarshalJSONDocument(param interface{}) (interface{}, error) {
+	data, err := util.CoerceType(param)
+	if err != nil {
+		return nil, err
+	}
+
+	// UseNumber preserves integers, which would otherwise become float64
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal JSON data: %s", err.Error())
+	}
+
+	return document, nil
+}
+
+// setNestedField sets the value in the document, creating nested objects for each dot in the target
+func setNestedField(document map[string]interface{}, target string, value interface{}) error {
+	fields := strings.Split(target, ".")
+	current := document
+
+	for _, field := range fields[:len(fields)-1] {
+		child, ok := current[field]
+		if !ok {
+			child = make(map[string]interface{})
+			current[field] = child
+		}
+
+		object, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unable to map target '%s', '%s' is already mapped to a value", target, field)
+		}
+		current = object
+	}
+
+	current[fields[len(fields)-1]] = value
+	return nil
+}
+
+func newMappedReading(deviceName string, name string, value interface{}) (models.Reading, error) {
+	reading := models.Reading{
+		Device: deviceName,
+		Name:   name,
+	}
+
+	switch typed := value.(type) {
+	case json.Number:
+		if integer, err := typed.Int64(); err == nil {
+			reading.ValueType = models.ValueTypeInt64
+			reading.Value = strconv.FormatInt(integer, 10)
+		} else {
+			float, err := typed.Float64()
+			if err != nil {
+				return reading, fmt.Errorf("unable to convert '%s' to a number for reading '%s'", typed, name)
+			}
+			reading.ValueType = models.ValueTypeFloat64
+			reading.FloatEncoding = models.ENotation
+			reading.Value = strconv.FormatFloat(float, 'e', -1, 64)
+		}
+
+	case bool:
+		reading.ValueType = models.ValueTypeBool
+		reading.Value = strconv.FormatBool(typed)
+
+	case string:
+		reading.ValueType = models.ValueTypeString
+		reading.Value = typed
+
+	default:
+		// Objects, arrays, and null are kept as JSON
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return reading, fmt.Errorf("unable to marshal value for reading '%s': %s", name, err.Error())
+		}
+		reading.ValueType = models.ValueTypeString
+		reading.Value = string(data)
+	}
+
+	return reading, nil
+}
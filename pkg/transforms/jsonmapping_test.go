@@ -0,0 +1,186 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mappingTestPayload = `{
+	"id": "sensor01",
+	"sensor": {"temp": 21.5, "hum": 40, "ok": true, "state": "running"},
+	"location": {"lat": 1, "lon": 2},
+	"values": [1, 2]
+}`
+
+func TestParseFieldMappings(t *testing.T) {
+	mappings, err := ParseFieldMappings("temperature=$.sensor.temp, humidity = $['sensor']['hum']")
+	require.NoError(t, err)
+	require.Len(t, mappings, 2)
+	assert.Equal(t, "temperature", mappings[0].Target)
+	assert.Equal(t, "$.sensor.temp", mappings[0].Path)
+	assert.Equal(t, "humidity", mappings[1].Target)
+	assert.Equal(t, "$['sensor']['hum']", mappings[1].Path)
+
+	// Commas within quoted field names don't separate mappings
+	mappings, err = ParseFieldMappings(`total=$['a,b'], first=$["c,d"][0], last=$.e`)
+	require.NoError(t, err)
+	require.Len(t, mappings, 3)
+	assert.Equal(t, "$['a,b']", mappings[0].Path)
+	assert.Equal(t, `$["c,d"][0]`, mappings[1].Path)
+	assert.Equal(t, "$.e", mappings[2].Path)
+
+	tests := []struct {
+		Name     string
+		Mappings string
+	}{
+		{"Empty", ""},
+		{"Missing path", "temperature"},
+		{"Missing target", "=$.sensor.temp"},
+		{"Invalid path", "temperature=sensor.temp"},
+		{"Unterminated field name", "temperature=$['a,b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ParseFieldMappings(test.Mappings)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMapToJSON(t *testing.T) {
+	mappings, err := ParseFieldMappings("temp=$.sensor.temp, reading.humidity=$.sensor.hum, reading.ok=$.sensor.ok, position=$.location, missing=$.missing")
+	require.NoError(t, err)
+	mapper, err := NewJSONMapper(mappings, "")
+	require.NoError(t, err)
+
+	continuePipeline, result := mapper.MapToJSON(context, []byte(mappingTestPayload))
+	require.True(t, continuePipeline, result)
+	assert.JSONEq(t, `{"temp":21.5,"reading":{"humidity":40,"ok":true},"position":{"lat":1,"lon":2}}`, string(result.([]byte)))
+	assert.Equal(t, clients.ContentTypeJSON, context.ResponseContentType)
+}
+
+func TestMapToJSONErrors(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Mappings string
+		Data     interface{}
+		Expected string
+	}{
+		{"Not JSON", "temp=$.sensor.temp", "not json", "unable to unmarshal JSON data"},
+		{"No fields found", "temp=$.missing", []byte(mappingTestPayload), "none of the mapped fields were found in the data"},
+		{"Conflicting targets", "a=$.id, a.b=$.sensor.temp", []byte(mappingTestPayload), "unable to map target 'a.b'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mappings, err := ParseFieldMappings(test.Mappings)
+			require.NoError(t, err)
+			mapper, err := NewJSONMapper(mappings, "")
+			require.NoError(t, err)
+
+			continuePipeline, result := mapper.MapToJSON(context, test.Data)
+			assert.False(t, continuePipeline)
+			assert.Contains(t, result.(error).Error(), test.Expected)
+		})
+	}
+
+	mapper, _ := NewJSONMapper([]FieldMapping{{}}, "")
+	continuePipeline, result := mapper.MapToJSON(context)
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "No Data Received", result.(error).Error())
+}
+
+func TestMapToEvent(t *testing.T) {
+	mappings, err := ParseFieldMappings("temperature=$.sensor.temp, humidity=$.sensor.hum, ok=$.sensor.ok, state=$.sensor.state, values=$.values, missing=$.missing")
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name           string
+		DeviceName     string
+		ExpectedDevice string
+	}{
+		{"Static device name", "device01", "device01"},
+		{"Device name from data", "$.id", "sensor01"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mapper, err := NewJSONMapper(mappings, test.DeviceName)
+			require.NoError(t, err)
+
+			continuePipeline, result := mapper.MapToEvent(context, []byte(mappingTestPayload))
+			require.True(t, continuePipeline, result)
+
+			event := result.(models.Event)
+			assert.Equal(t, test.ExpectedDevice, event.Device)
+			assert.NotZero(t, event.Origin)
+			require.Len(t, event.Readings, 5)
+
+			expected := []struct {
+				name      string
+				value     string
+				valueType string
+			}{
+				{"temperature", "2.15e+01", models.ValueTypeFloat64},
+				{"humidity", "40", models.ValueTypeInt64},
+				{"ok", "true", models.ValueTypeBool},
+				{"state", "running", models.ValueTypeString},
+				{"values", "[1,2]", models.ValueTypeString},
+			}
+
+			for index, reading := range event.Readings {
+				assert.Equal(t, test.ExpectedDevice, reading.Device)
+				assert.Equal(t, expected[index].name, reading.Name)
+				assert.Equal(t, expected[index].value, reading.Value)
+				assert.Equal(t, expected[index].valueType, reading.ValueType)
+				assert.Equal(t, event.Origin, reading.Origin)
+			}
+			assert.Equal(t, models.ENotation, event.Readings[0].FloatEncoding)
+		})
+	}
+}
+
+func TestMapToEventErrors(t *testing.T) {
+	mappings, err := ParseFieldMappings("temperature=$.sensor.temp")
+	require.NoError(t, err)
+
+	mapper, err := NewJSONMapper(mappings, "$.missing")
+	require.NoError(t, err)
+	continuePipeline, result := mapper.MapToEvent(context, []byte(mappingTestPayload))
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "device name not found at '$.missing'", result.(error).Error())
+
+	mappings, err = ParseFieldMappings("temperature=$.missing")
+	require.NoError(t, err)
+	mapper, err = NewJSONMapper(mappings, "device01")
+	require.NoError(t, err)
+	continuePipeline, result = mapper.MapToEvent(context, []byte(mappingTestPayload))
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "none of the mapped fields were found in the data", result.(error).Error())
+
+	_, err = NewJSONMapper(nil, "device01")
+	assert.Error(t, err)
+	_, err = NewJSONMapper(mappings, "$[bad")
+	assert.Error(t, err)
+}
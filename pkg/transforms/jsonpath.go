@@ -0,0 +1,190 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type jsonPathSegmentType int
+
+const (
+	jsonPathField jsonPathSegmentType = iota
+	jsonPathIndex
+	jsonPathWildcard
+)
+
+type jsonPathSegment struct {
+	segmentType jsonPathSegmentType
+	field       string
+	index       int
+}
+
+// jsonPath is a parsed JSONPath expression. The supported subset is the root ($), child fields in dot (.name)
+// or bracket (['name']) notation, array indexes ([0], negative indexes count from the end) and wildcards
+// (.* or [*]), which is sufficient for extracting fields from JSON payloads.
+type jsonPath struct {
+	expression string
+	segments   []jsonPathSegment
+	definite   bool
+}
+
+func parseJSONPath(expression string) (jsonPath, error) {
+	path := jsonPath{expression: expression, definite: true}
+
+	remaining := strings.TrimSpace(expression)
+	if !strings.HasPrefix(remaining, "$") {
+		return path, fmt.Errorf("JSONPath '%s' must start with '$'", expression)
+	}
+	remaining = remaining[1:]
+
+	for len(remaining) > 0 {
+		var segment jsonPathSegment
+		var err error
+
+		switch remaining[0] {
+		case '.':
+			segment, remaining, err = parseJSONPathDotSegment(remaining[1:])
+		case '[':
+			segment, remaining, err = parseJSONPathBracketSegment(remaining[1:])
+		default:
+			err = fmt.Errorf("unexpected '%c'", remaining[0])
+		}
+
+		if err != nil {
+			return path, fmt.Errorf("invalid JSONPath '%s': %s", expression, err.Error())
+		}
+
+		if segment.segmentType == jsonPathWildcard {
+			path.definite = false
+		}
+		path.segments = append(path.segments, segment)
+	}
+
+	return path, nil
+}
+
+func parseJSONPathDotSegment(remaining string) (jsonPathSegment, string, error) {
+	end := strings.IndexAny(remaining, ".[")
+	if end < 0 {
+		end = len(remaining)
+	}
+
+	name := remaining[:end]
+	switch name {
+	case "":
+		return jsonPathSegment{}, "", fmt.Errorf("missing field name")
+	case "*":
+		return jsonPathSegment{segmentType: jsonPathWildcard}, remaining[end:], nil
+	default:
+		return jsonPathSegment{segmentType: jsonPathField, field: name}, remaining[end:], nil
+	}
+}
+
+func parseJSONPathBracketSegment(remaining string) (jsonPathSegment, string, error) {
+	if strings.HasPrefix(remaining, "'") || strings.HasPrefix(remaining, `"`) {
+		quote := remaining[:1]
+		end := strings.Index(remaining[1:], quote+"]")
+		if end < 0 {
+			return jsonPathSegment{}, "", fmt.Errorf("unterminated field name")
+		}
+		return jsonPathSegment{segmentType: jsonPathField, field: remaining[1 : end+1]}, remaining[end+3:], nil
+	}
+
+	end := strings.Index(remaining, "]")
+	if end < 0 {
+		return jsonPathSegment{}, "", fmt.Errorf("missing ']'")
+	}
+
+	content := strings.TrimSpace(remaining[:end])
+	if content == "*" {
+		return jsonPathSegment{segmentType: jsonPathWildcard}, remaining[end+1:], nil
+	}
+
+	index, err := strconv.Atoi(content)
+	if err != nil {
+		return jsonPathSegment{}, "", fmt.Errorf("invalid array index '%s'", content)
+	}
+
+	return jsonPathSegment{segmentType: jsonPathIndex, index: index}, remaining[end+1:], nil
+}
+
+// evaluate returns the value selected by the path from the unmarshaled JSON and whether it was found.
+// Paths containing wildcards return a []interface{} of all the matches.
+func (path jsonPath) evaluate(document interface{}) (interface{}, bool) {
+	values := []interface{}{document}
+
+	for _, segment := range path.segments {
+		var next []interface{}
+
+		for _, value := range values {
+			switch segment.segmentType {
+			case jsonPathField:
+				if object, ok := value.(map[string]interface{}); ok {
+					if child, ok := object[segment.field]; ok {
+						next = append(next, child)
+					}
+				}
+
+			case jsonPathIndex:
+				if array, ok := value.([]interface{}); ok {
+					index := segment.index
+					if index < 0 {
+						index += len(array)
+					}
+					if index >= 0 && index < len(array) {
+						next = append(next, array[index])
+					}
+				}
+
+			case jsonPathWildcard:
+				switch container := value.(type) {
+				case []interface{}:
+					next = append(next, container...)
+				case map[string]interface{}:
+					for _, key := range sortedKeys(container) {
+						next = append(next, container[key])
+					}
+				}
+			}
+		}
+
+		values = next
+	}
+
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	if path.definite {
+		return values[0], true
+	}
+
+	return values, true
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
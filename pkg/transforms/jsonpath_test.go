@@ -0,0 +1,85 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPathEvaluate(t *testing.T) {
+	document := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"id": "sensor01",
+		"sensor": {"temp": 21.5, "hum": 40, "field.with.dots": true},
+		"values": [1, 2, 3],
+		"points": [{"v": "a"}, {"v": "b"}]
+	}`), &document))
+
+	tests := []struct {
+		Name          string
+		Path          string
+		Expected      interface{}
+		ExpectedFound bool
+	}{
+		{"Root", "$", document, true},
+		{"Dot notation", "$.sensor.temp", 21.5, true},
+		{"Bracket notation", "$['sensor'][\"hum\"]", float64(40), true},
+		{"Bracket field with dots", "$.sensor['field.with.dots']", true, true},
+		{"Array index", "$.values[1]", float64(2), true},
+		{"Negative array index", "$.values[-1]", float64(3), true},
+		{"Array wildcard", "$.points[*].v", []interface{}{"a", "b"}, true},
+		{"Dot wildcard", "$.points.*.v", []interface{}{"a", "b"}, true},
+		{"Missing field", "$.sensor.missing", nil, false},
+		{"Index out of range", "$.values[3]", nil, false},
+		{"Index on object", "$.sensor[0]", nil, false},
+		{"Wildcard no matches", "$.id[*]", nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			path, err := parseJSONPath(test.Path)
+			require.NoError(t, err)
+
+			value, found := path.evaluate(document)
+			assert.Equal(t, test.ExpectedFound, found)
+			assert.Equal(t, test.Expected, value)
+		})
+	}
+}
+
+func TestJSONPathParseErrors(t *testing.T) {
+	tests := []string{
+		"sensor.temp",
+		"$.",
+		"$..temp",
+		"$[0",
+		"$['temp]",
+		"$[abc]",
+		"$temp",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			_, err := parseJSONPath(test)
+			assert.Error(t, err)
+		})
+	}
+}
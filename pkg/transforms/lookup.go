@@ -0,0 +1,333 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// DefaultLookupReloadInterval is how often the lookup table file is checked for changes when no interval is specified
+const DefaultLookupReloadInterval = 10 * time.Second
+
+// LookupTable adds the columns of the row matching the Event's device name, or the value of the Event's KeyTag, from a
+// CSV or JSON lookup table file to the Event's tags, i.e. asset IDs, locations and cost centres. The file is checked
+// for changes every ReloadInterval and reloaded when it has changed.
+//
+// CSV files have a header row naming the columns, the key is in the KeyColumn, which defaults to the first column.
+// JSON files either contain an object whose keys are the lookup keys and whose values are objects of the columns,
+// i.e. {"Boiler-01": {"asset": "A-1001", "costcentre": "CC-7"}}, or an array of objects of the columns which
+// requires the KeyColumn, i.e. [{"device": "Boiler-01", "asset": "A-1001"}].
+type LookupTable struct {
+	Path      string
+	KeyColumn string
+	// KeyTag is the tag whose value is looked up, the device name is looked up when empty
+	KeyTag string
+	// Columns are the columns added as tags, all the columns except the KeyColumn are added when empty
+	Columns        []string
+	ReloadInterval time.Duration
+
+	// table is keyed by the lookup key, the rows are keyed by column name
+	table   map[string]map[string]string
+	modTime time.Time
+	size    int64
+	checked time.Time
+	lock    sync.Mutex
+	now     func() time.Time
+}
+
+// NewLookupTable creates, initializes and returns a new instance of LookupTable, loading the CSV or JSON file at the
+// path, where the format is determined by the file's extension, .json for JSON and otherwise CSV. The reloadInterval,
+// i.e. "1m", is optional and defaults to DefaultLookupReloadInterval.
+func NewLookupTable(path string, keyColumn string, keyTag string, columns []string, reloadInterval string) (*LookupTable, error) {
+	if len(path) == 0 {
+		return nil, errors.New("lookup table file path is required")
+	}
+
+	interval := DefaultLookupReloadInterval
+	if len(reloadInterval) > 0 {
+		var err error
+		interval, err = time.ParseDuration(reloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reload interval '%s': %s", reloadInterval, err.Error())
+		}
+
+		if interval <= 0 {
+			return nil, errors.New("reload interval must be greater than zero")
+		}
+	}
+
+	lookup := &LookupTable{
+		Path:           path,
+		KeyColumn:      keyColumn,
+		KeyTag:         keyTag,
+		Columns:        columns,
+		ReloadInterval: interval,
+		now:            time.Now,
+	}
+
+	if err := lookup.load(); err != nil {
+		return nil, err
+	}
+	lookup.checked = lookup.now()
+
+	return lookup, nil
+}
+
+// AddLookupTags adds the columns of the lookup table row matching the Event's key to the Event's tags. Empty values
+// are not added and Events whose key isn't in the table are passed on unchanged. When the file has changed but can't
+// be reloaded the error is logged and the previously loaded table continues to be used.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (lookup *LookupTable) AddLookupTags(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	edgexcontext.LoggingClient.Debug("Adding Lookup Table Tags")
+
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	key := event.Device
+	if len(lookup.KeyTag) > 0 {
+		key = event.Tags[lookup.KeyTag]
+	}
+
+	lookup.lock.Lock()
+	lookup.reloadIfChanged(edgexcontext)
+	row := lookup.table[key]
+	lookup.lock.Unlock()
+
+	if len(row) == 0 {
+		return true, event
+	}
+
+	event.Tags = copyTags(event.Tags)
+	if event.Tags == nil {
+		event.Tags = make(map[string]string)
+	}
+
+	for column, value := range row {
+		event.Tags[column] = value
+	}
+
+	return true, event
+}
+
+// reloadIfChanged reloads the table when the ReloadInterval has passed since the file was last checked and the file's
+// modification time or size has changed, the caller must hold the lock
+func (lookup *LookupTable) reloadIfChanged(edgexcontext *appcontext.Context) {
+	now := lookup.now()
+	if now.Sub(lookup.checked) < lookup.ReloadInterval {
+		return
+	}
+	lookup.checked = now
+
+	info, err := os.Stat(lookup.Path)
+	if err != nil {
+		edgexcontext.LoggingClient.Error(fmt.Sprintf("Unable to check lookup table file '%s'", lookup.Path), "error", err)
+		return
+	}
+
+	if info.ModTime().Equal(lookup.modTime) && info.Size() == lookup.size {
+		return
+	}
+
+	if err := lookup.load(); err != nil {
+		edgexcontext.LoggingClient.Error("Unable to reload lookup table, continuing to use the previous table", "error", err)
+		return
+	}
+
+	edgexcontext.LoggingClient.Info(fmt.Sprintf("Reloaded lookup table file '%s' with %d rows", lookup.Path, len(lookup.table)))
+}
+
+// load reads and parses the file, replacing the table only when successful
+func (lookup *LookupTable) load() error {
+	info, err := os.Stat(lookup.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read lookup table file '%s': %s", lookup.Path, err.Error())
+	}
+
+	content, err := ioutil.ReadFile(lookup.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read lookup table file '%s': %s", lookup.Path, err.Error())
+	}
+
+	var rows []map[string]string
+	var keyed map[string]map[string]string
+	if strings.EqualFold(filepath.Ext(lookup.Path), ".json") {
+		keyed, rows, err = parseJSONLookupTable(content)
+	} else {
+		rows, err = parseCSVLookupTable(content)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse lookup table file '%s': %s", lookup.Path, err.Error())
+	}
+
+	table := make(map[string]map[string]string)
+	for key, row := range keyed {
+		table[key] = lookup.selectColumns(row)
+	}
+
+	for index, row := range rows {
+		keyColumn := lookup.KeyColumn
+		if len(keyColumn) == 0 {
+			keyColumn = row[csvFirstColumn]
+		}
+
+		key, ok := row[keyColumn]
+		if !ok || len(keyColumn) == 0 {
+			return fmt.Errorf("lookup table file '%s' row %d has no '%s' key column", lookup.Path, index+1, keyColumn)
+		}
+
+		delete(row, csvFirstColumn)
+		delete(row, keyColumn)
+		table[key] = lookup.selectColumns(row)
+	}
+
+	lookup.table = table
+	lookup.modTime = info.ModTime()
+	lookup.size = info.Size()
+	return nil
+}
+
+// selectColumns returns the non-empty values of the Columns, or of all the columns when no Columns are specified
+func (lookup *LookupTable) selectColumns(row map[string]string) map[string]string {
+	selected := make(map[string]string)
+
+	if len(lookup.Columns) == 0 {
+		for column, value := range row {
+			if len(value) > 0 {
+				selected[column] = value
+			}
+		}
+		return selected
+	}
+
+	for _, column := range lookup.Columns {
+		if value := row[column]; len(value) > 0 {
+			selected[column] = value
+		}
+	}
+	return selected
+}
+
+// csvFirstColumn is the entry added to each CSV row holding the name of the first column, the default key column.
+// It can't clash with a column name since those are trimmed.
+const csvFirstColumn = " first"
+
+func parseCSVLookupTable(content []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	header := make([]string, len(records[0]))
+	for index, column := range records[0] {
+		header[index] = strings.TrimSpace(column)
+	}
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := map[string]string{csvFirstColumn: header[0]}
+		for index, value := range record {
+			row[header[index]] = strings.TrimSpace(value)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseJSONLookupTable parses either an object keyed by the lookup key or an array of rows
+func parseJSONLookupTable(content []byte) (map[string]map[string]string, []map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, nil, err
+	}
+
+	switch value := document.(type) {
+	case map[string]interface{}:
+		keyed := make(map[string]map[string]string, len(value))
+		for key, item := range value {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("value of '%s' is not an object", key)
+			}
+			keyed[key] = jsonLookupRow(row)
+		}
+		return keyed, nil, nil
+
+	case []interface{}:
+		rows := make([]map[string]string, 0, len(value))
+		for index, item := range value {
+			row, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("row %d is not an object", index+1)
+			}
+			rows = append(rows, jsonLookupRow(row))
+		}
+		return nil, rows, nil
+
+	default:
+		return nil, nil, errors.New("must contain an object or an array of objects")
+	}
+}
+
+// jsonLookupRow converts the values of the row to strings, values which aren't strings, numbers or booleans are
+// converted to JSON
+func jsonLookupRow(row map[string]interface{}) map[string]string {
+	converted := make(map[string]string, len(row))
+	for column, value := range row {
+		switch typed := value.(type) {
+		case nil:
+			converted[column] = ""
+		case string:
+			converted[column] = typed
+		case json.Number, bool:
+			converted[column] = fmt.Sprint(typed)
+		default:
+			encoded, _ := json.Marshal(typed)
+			converted[column] = string(encoded)
+		}
+	}
+	return converted
+}
@@ -0,0 +1,161 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lookupCSV = `device, asset, location, costcentre
+Boiler-01, A-1001, "Building 1, Floor 2", CC-7
+Boiler-02, A-1002, ,CC-8
+`
+
+func writeLookupFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestAddLookupTags(t *testing.T) {
+	csvFile := writeLookupFile(t, "assets.csv", lookupCSV)
+	keyedFile := writeLookupFile(t, "assets.json",
+		`{"Boiler-01": {"asset": "A-1001", "floor": 2, "active": true, "position": {"x": 1}}}`)
+	arrayFile := writeLookupFile(t, "sites.json",
+		`[{"site": "plant-1", "region": "north"}, {"site": "plant-2", "region": "south", "manager": null}]`)
+
+	tests := []struct {
+		name      string
+		path      string
+		keyColumn string
+		keyTag    string
+		columns   []string
+		event     models.Event
+		expected  map[string]string
+	}{
+		{"CSV first column", csvFile, "", "", nil, models.Event{Device: "Boiler-01"},
+			map[string]string{"asset": "A-1001", "location": "Building 1, Floor 2", "costcentre": "CC-7"}},
+		{"CSV empty value", csvFile, "", "", nil, models.Event{Device: "Boiler-02"},
+			map[string]string{"asset": "A-1002", "costcentre": "CC-8"}},
+		{"CSV columns", csvFile, "device", "", []string{"costcentre"}, models.Event{Device: "Boiler-01"},
+			map[string]string{"costcentre": "CC-7"}},
+		{"CSV key tag", csvFile, "asset", "asset", []string{"device"}, models.Event{Tags: map[string]string{"asset": "A-1002"}},
+			map[string]string{"asset": "A-1002", "device": "Boiler-02"}},
+		{"CSV no match", csvFile, "", "", nil, models.Event{Device: devID1, Tags: map[string]string{"site": "plant-1"}},
+			map[string]string{"site": "plant-1"}},
+		{"JSON object", keyedFile, "", "", nil, models.Event{Device: "Boiler-01"},
+			map[string]string{"asset": "A-1001", "floor": "2", "active": "true", "position": `{"x":1}`}},
+		{"JSON array", arrayFile, "site", "site", nil, models.Event{Device: devID1, Tags: map[string]string{"site": "plant-2"}},
+			map[string]string{"site": "plant-2", "region": "south"}},
+		{"JSON missing tag", arrayFile, "site", "site", nil, models.Event{Device: devID1}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup, err := NewLookupTable(tt.path, tt.keyColumn, tt.keyTag, tt.columns, "")
+			require.NoError(t, err)
+
+			original := copyTags(tt.event.Tags)
+			continuePipeline, result := lookup.AddLookupTags(context, tt.event)
+			require.True(t, continuePipeline)
+			assert.Equal(t, tt.expected, result.(models.Event).Tags)
+			assert.Equal(t, original, tt.event.Tags, "tags of the received event should not be modified")
+		})
+	}
+}
+
+func TestNewLookupTableErrors(t *testing.T) {
+	csvFile := writeLookupFile(t, "assets.csv", lookupCSV)
+
+	tests := []struct {
+		name           string
+		path           string
+		keyColumn      string
+		reloadInterval string
+	}{
+		{"No path", "", "", ""},
+		{"Missing file", csvFile + ".missing", "", ""},
+		{"Unknown key column", csvFile, "serial", ""},
+		{"Bad reload interval", csvFile, "", "often"},
+		{"Zero reload interval", csvFile, "", "0s"},
+		{"Empty CSV", writeLookupFile(t, "empty.csv", ""), "", ""},
+		{"Bad CSV", writeLookupFile(t, "bad.csv", "device,asset\nBoiler-01\n"), "", ""},
+		{"Bad JSON", writeLookupFile(t, "bad.json", `{"Boiler-01": `), "", ""},
+		{"JSON not an object", writeLookupFile(t, "value.json", `"Boiler-01"`), "", ""},
+		{"JSON row not an object", writeLookupFile(t, "row.json", `{"Boiler-01": "A-1001"}`), "", ""},
+		{"JSON array without key column", writeLookupFile(t, "array.json", `[{"device": "Boiler-01"}]`), "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLookupTable(tt.path, tt.keyColumn, "", nil, tt.reloadInterval)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAddLookupTagsReload(t *testing.T) {
+	path := writeLookupFile(t, "assets.csv", "device,asset\nBoiler-01,A-1001\n")
+	lookup, err := NewLookupTable(path, "", "", nil, "1m")
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	lookup.now = clock.now
+	lookup.checked = clock.current
+
+	assetOf := func() string {
+		_, result := lookup.AddLookupTags(context, models.Event{Device: "Boiler-01"})
+		return result.(models.Event).Tags["asset"]
+	}
+
+	modified := time.Now().Add(time.Hour)
+	require.NoError(t, ioutil.WriteFile(path, []byte("device,asset\nBoiler-01,A-2002\n"), 0644))
+	require.NoError(t, os.Chtimes(path, modified, modified))
+	assert.Equal(t, "A-1001", assetOf(), "file should not be checked before the reload interval")
+
+	clock.current = clock.current.Add(time.Minute)
+	assert.Equal(t, "A-2002", assetOf(), "changed file should be reloaded")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("device,asset\nBoiler-01\n"), 0644))
+	require.NoError(t, os.Chtimes(path, modified.Add(time.Hour), modified.Add(time.Hour)))
+	clock.current = clock.current.Add(time.Minute)
+	assert.Equal(t, "A-2002", assetOf(), "previous table should be used when the file can't be reloaded")
+
+	require.NoError(t, os.Remove(path))
+	clock.current = clock.current.Add(time.Minute)
+	assert.Equal(t, "A-2002", assetOf(), "previous table should be used when the file is removed")
+}
+
+func TestAddLookupTagsErrors(t *testing.T) {
+	lookup, err := NewLookupTable(writeLookupFile(t, "assets.csv", lookupCSV), "", "", nil, "")
+	require.NoError(t, err)
+
+	continuePipeline, result := lookup.AddLookupTags(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = lookup.AddLookupTags(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
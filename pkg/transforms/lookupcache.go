@@ -0,0 +1,98 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"sync"
+	"time"
+)
+
+// lookupEntry is a cached lookup result, done is closed once the lookup has completed
+type lookupEntry struct {
+	value   interface{}
+	err     error
+	expires time.Time
+	done    chan struct{}
+}
+
+// lookupCache caches the results of remote lookups, i.e. of the devices looked up from core-metadata by
+// DeviceMetadataEnricher, keyed by what is looked up. Failed lookups are cached for the failureTTL so a missing item
+// or an unavailable service doesn't cause a remote call for every Event, and concurrent lookups of the same key are collapsed into one remote call. The lock isn't held during the
+// remote call, so lookups of other keys aren't held up by it.
+type lookupCache struct {
+	// ttl is how long results are cached, zero doesn't cache them
+	ttl time.Duration
+	// failureTTL is how long failures are cached, zero doesn't cache them
+	failureTTL time.Duration
+	entries    map[string]*lookupEntry
+	lock       sync.Mutex
+	now        func() time.Time
+}
+
+func newLookupCache(ttl time.Duration, failureTTL time.Duration) *lookupCache {
+	return &lookupCache{
+		ttl:        ttl,
+		failureTTL: failureTTL,
+		entries:    make(map[string]*lookupEntry),
+		now:        time.Now,
+	}
+}
+
+// get returns the cached result for the key, calling lookup when there is none or it has expired. When a lookup of
+// the key is already in progress its result is waited for rather than calling lookup again.
+func (cache *lookupCache) get(key string, lookup func() (interface{}, error)) (interface{}, error) {
+	cache.lock.Lock()
+	entry, ok := cache.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if cache.now().Before(entry.expires) {
+				cache.lock.Unlock()
+				return entry.value, entry.err
+			}
+		default:
+			// In progress, so wait for its result
+			cache.lock.Unlock()
+			<-entry.done
+			return entry.value, entry.err
+		}
+	}
+
+	entry = &lookupEntry{done: make(chan struct{})}
+	cache.entries[key] = entry
+	cache.lock.Unlock()
+
+	value, err := lookup()
+
+	ttl := cache.ttl
+	if err != nil {
+		ttl = cache.failureTTL
+	}
+
+	cache.lock.Lock()
+	entry.value = value
+	entry.err = err
+	if ttl == 0 {
+		delete(cache.entries, key)
+	} else {
+		entry.expires = cache.now().Add(ttl)
+	}
+	close(entry.done)
+	cache.lock.Unlock()
+
+	return value, err
+}
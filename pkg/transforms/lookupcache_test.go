@@ -0,0 +1,104 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCacheExpiry(t *testing.T) {
+	tests := []struct {
+		Name             string
+		TTL              time.Duration
+		FailureTTL       time.Duration
+		Err              error
+		ExpectedLookups  int
+		ExpectedAfterTTL int
+	}{
+		{"Cached", time.Minute, time.Second, nil, 1, 2},
+		{"Not cached", 0, time.Second, nil, 2, 3},
+		{"Failure cached", time.Second, time.Minute, errors.New("failed"), 1, 2},
+		{"Failure not cached", time.Minute, 0, errors.New("failed"), 2, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cache := newLookupCache(test.TTL, test.FailureTTL)
+			clock := &fakeClock{current: time.Unix(1000, 0)}
+			cache.now = clock.now
+
+			lookups := 0
+			lookup := func() (interface{}, error) {
+				lookups++
+				return "value", test.Err
+			}
+
+			for i := 0; i < 2; i++ {
+				value, err := cache.get("key", lookup)
+				assert.Equal(t, "value", value)
+				assert.Equal(t, test.Err, err)
+			}
+			assert.Equal(t, test.ExpectedLookups, lookups)
+
+			clock.current = clock.current.Add(time.Hour)
+			_, _ = cache.get("key", lookup)
+			assert.Equal(t, test.ExpectedAfterTTL, lookups)
+		})
+	}
+}
+
+func TestLookupCacheCollapsesConcurrentLookups(t *testing.T) {
+	cache := newLookupCache(time.Minute, time.Minute)
+
+	var lookups int32
+	release := make(chan struct{})
+	lookup := func() (interface{}, error) {
+		atomic.AddInt32(&lookups, 1)
+		<-release
+		return "value", nil
+	}
+
+	var started, finished sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		started.Add(1)
+		finished.Add(1)
+		go func() {
+			defer finished.Done()
+			started.Done()
+			value, err := cache.get("key", lookup)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", value)
+		}()
+	}
+	started.Wait()
+
+	// A lookup of another key isn't held up by the one in progress
+	value, err := cache.get("other", func() (interface{}, error) { return "other value", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "other value", value)
+
+	close(release)
+	finished.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lookups))
+}
@@ -0,0 +1,236 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+// Device metadata fields which can be added by DeviceMetadataEnricher
+const (
+	DeviceMetadataDescription   = "description"
+	DeviceMetadataLabels        = "labels"
+	DeviceMetadataLocation      = "location"
+	DeviceMetadataService       = "service"
+	DeviceMetadataProfile       = "profile"
+	DeviceMetadataManufacturer  = "manufacturer"
+	DeviceMetadataModel         = "model"
+	DeviceMetadataProfileLabels = "profilelabels"
+)
+
+// DefaultDeviceMetadataFields are the fields added when none are specified
+var DefaultDeviceMetadataFields = []string{DeviceMetadataProfile, DeviceMetadataLabels, DeviceMetadataLocation}
+
+// Where DeviceMetadataEnricher adds the device metadata
+const (
+	// DeviceMetadataTargetTags adds the fields to the Event's tags
+	DeviceMetadataTargetTags = "tags"
+	// DeviceMetadataTargetContext adds the fields to the context's metadata values, see appcontext.Context.AddValue
+	DeviceMetadataTargetContext = "context"
+)
+
+// DefaultDeviceMetadataCacheTTL is how long the metadata of a device is cached when no TTL is specified
+const DefaultDeviceMetadataCacheTTL = 5 * time.Minute
+
+// deviceMetadataFailureTTL is how long a device which can't be looked up is cached as failed
+const deviceMetadataFailureTTL = 30 * time.Second
+
+// DeviceMetadataEnricher adds fields of the Event's device from core-metadata, i.e. its profile, labels and
+// location, to the Event's tags or to the context's metadata values. The fields of each device are cached for the
+// CacheTTL so core-metadata isn't called for every Event. Failed lookups are cached for a shorter time and
+// concurrent lookups of a device are collapsed into one call.
+type DeviceMetadataEnricher struct {
+	Fields []string
+	Target string
+	// CacheTTL is how long the fields of a device are cached, as set by NewDeviceMetadataEnricher
+	CacheTTL time.Duration
+
+	// cache is keyed by device name
+	cache *lookupCache
+}
+
+// NewDeviceMetadataEnricher creates, initializes and returns a new instance of DeviceMetadataEnricher. When no
+// fields are specified DefaultDeviceMetadataFields are used, the target is one of tags or context and the cacheTTL,
+// i.e. "10m", is optional and defaults to DefaultDeviceMetadataCacheTTL.
+func NewDeviceMetadataEnricher(fields []string, target string, cacheTTL string) (*DeviceMetadataEnricher, error) {
+	if len(fields) == 0 {
+		fields = DefaultDeviceMetadataFields
+	}
+
+	for _, field := range fields {
+		switch field {
+		case DeviceMetadataDescription, DeviceMetadataLabels, DeviceMetadataLocation, DeviceMetadataService,
+			DeviceMetadataProfile, DeviceMetadataManufacturer, DeviceMetadataModel, DeviceMetadataProfileLabels:
+		default:
+			return nil, fmt.Errorf("unknown device metadata field '%s'", field)
+		}
+	}
+
+	switch target {
+	case DeviceMetadataTargetTags, DeviceMetadataTargetContext:
+	default:
+		return nil, fmt.Errorf("unknown device metadata target '%s'", target)
+	}
+
+	ttl := DefaultDeviceMetadataCacheTTL
+	if len(cacheTTL) > 0 {
+		var err error
+		ttl, err = time.ParseDuration(cacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache TTL '%s': %s", cacheTTL, err.Error())
+		}
+
+		if ttl < 0 {
+			return nil, errors.New("cache TTL must not be negative")
+		}
+	}
+
+	return &DeviceMetadataEnricher{
+		Fields:   fields,
+		Target:   target,
+		CacheTTL: ttl,
+		cache:    newLookupCache(ttl, deviceMetadataFailureTTL),
+	}, nil
+}
+
+// AddDeviceMetadata adds the Fields of the Event's device, looked up by name from core-metadata or the cache, to the
+// Event's tags or the context's metadata values. Fields without a value are not added. When the device can't be
+// looked up the error is logged and the Event is passed on unchanged, the lookup is retried once the failure has
+// been cached for 30 seconds.
+// This function will return an error and stop the pipeline if a non-edgex event is received, if no data is received
+// or if the Metadata client is missing from the Clients configuration.
+func (enricher *DeviceMetadataEnricher) AddDeviceMetadata(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	edgexcontext.LoggingClient.Debug("Adding Device Metadata")
+
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	if edgexcontext.DeviceClient == nil {
+		return false, fmt.Errorf("unable to add device metadata: '%s' is missing from Clients configuration", common.CoreMetadataClientName)
+	}
+
+	fields, err := enricher.deviceFields(edgexcontext, event.Device)
+	if err != nil {
+		edgexcontext.LoggingClient.Error(fmt.Sprintf("Unable to look up metadata of device %s", event.Device),
+			"error", err, clients.CorrelationHeader, edgexcontext.CorrelationID)
+		return true, event
+	}
+
+	if enricher.Target == DeviceMetadataTargetContext {
+		for key, value := range fields {
+			edgexcontext.AddValue(key, value)
+		}
+		return true, event
+	}
+
+	if len(fields) > 0 {
+		event.Tags = copyTags(event.Tags)
+		if event.Tags == nil {
+			event.Tags = make(map[string]string)
+		}
+
+		for key, value := range fields {
+			event.Tags[key] = value
+		}
+	}
+
+	return true, event
+}
+
+// deviceFields returns the cached fields of the device, looking them up when they aren't cached or have expired
+func (enricher *DeviceMetadataEnricher) deviceFields(edgexcontext *appcontext.Context, deviceName string) (map[string]string, error) {
+	fields, err := enricher.cache.get(deviceName, func() (interface{}, error) {
+		ctx := syscontext.WithValue(syscontext.Background(), clients.CorrelationHeader, edgexcontext.CorrelationID)
+		device, err := edgexcontext.DeviceClient.DeviceForName(ctx, deviceName)
+		if err != nil {
+			return nil, err
+		}
+
+		return enricher.fieldsOf(device), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return fields.(map[string]string), nil
+}
+
+func (enricher *DeviceMetadataEnricher) fieldsOf(device models.Device) map[string]string {
+	fields := make(map[string]string)
+
+	for _, field := range enricher.Fields {
+		var value string
+
+		switch field {
+		case DeviceMetadataDescription:
+			value = device.Description
+		case DeviceMetadataLabels:
+			value = strings.Join(device.Labels, ",")
+		case DeviceMetadataLocation:
+			value = locationValue(device.Location)
+		case DeviceMetadataService:
+			value = device.Service.Name
+		case DeviceMetadataProfile:
+			value = device.Profile.Name
+		case DeviceMetadataManufacturer:
+			value = device.Profile.Manufacturer
+		case DeviceMetadataModel:
+			value = device.Profile.Model
+		case DeviceMetadataProfileLabels:
+			value = strings.Join(device.Profile.Labels, ",")
+		}
+
+		if len(value) > 0 {
+			fields[field] = value
+		}
+	}
+
+	return fields
+}
+
+// locationValue returns the device's location, which may be any JSON value, as a string. Locations which
+// aren't strings are returned as JSON.
+func locationValue(location interface{}) string {
+	switch value := location.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("%v", value)
+		}
+		return string(encoded)
+	}
+}
@@ -0,0 +1,230 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/metadata"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeviceClient only implements DeviceForName, counting the lookups
+type fakeDeviceClient struct {
+	metadata.DeviceClient
+	devices map[string]models.Device
+	lookups int
+	err     error
+}
+
+func (client *fakeDeviceClient) DeviceForName(_ syscontext.Context, name string) (models.Device, error) {
+	client.lookups++
+	if client.err != nil {
+		return models.Device{}, client.err
+	}
+
+	device, ok := client.devices[name]
+	if !ok {
+		return models.Device{}, errors.New("device not found")
+	}
+	return device, nil
+}
+
+// blockingDeviceClient holds each lookup until released
+type blockingDeviceClient struct {
+	*fakeDeviceClient
+	calls   int32
+	release chan struct{}
+}
+
+func (client *blockingDeviceClient) DeviceForName(ctx syscontext.Context, name string) (models.Device, error) {
+	atomic.AddInt32(&client.calls, 1)
+	<-client.release
+	return client.fakeDeviceClient.DeviceForName(ctx, name)
+}
+
+func newMetadataContext() (*appcontext.Context, *fakeDeviceClient) {
+	client := &fakeDeviceClient{devices: map[string]models.Device{
+		devID1: {
+			DescribedObject: models.DescribedObject{Description: "Boiler room sensor"},
+			Name:            devID1,
+			Labels:          []string{"boiler", "floor-1"},
+			Location:        map[string]interface{}{"building": "north"},
+			Service:         models.DeviceService{Name: "device-modbus"},
+			Profile: models.DeviceProfile{
+				Name:         "Temperature-Sensor",
+				Manufacturer: "Acme",
+				Model:        "T-100",
+			},
+		},
+	}}
+
+	return &appcontext.Context{LoggingClient: logClient, DeviceClient: client}, client
+}
+
+func TestNewDeviceMetadataEnricherErrors(t *testing.T) {
+	_, err := NewDeviceMetadataEnricher([]string{"firmware"}, DeviceMetadataTargetTags, "")
+	assert.Error(t, err)
+
+	_, err = NewDeviceMetadataEnricher(nil, "readings", "")
+	assert.Error(t, err)
+
+	_, err = NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "soon")
+	assert.Error(t, err)
+
+	_, err = NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "-1m")
+	assert.Error(t, err)
+
+	enricher, err := NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultDeviceMetadataFields, enricher.Fields)
+	assert.Equal(t, DefaultDeviceMetadataCacheTTL, enricher.CacheTTL)
+}
+
+func TestAddDeviceMetadataTags(t *testing.T) {
+	edgexcontext, _ := newMetadataContext()
+	enricher, err := NewDeviceMetadataEnricher([]string{DeviceMetadataDescription, DeviceMetadataLabels,
+		DeviceMetadataLocation, DeviceMetadataService, DeviceMetadataProfile, DeviceMetadataManufacturer,
+		DeviceMetadataModel, DeviceMetadataProfileLabels}, DeviceMetadataTargetTags, "")
+	require.NoError(t, err)
+
+	event := models.Event{Device: devID1, Tags: map[string]string{"site": "plant-1"}}
+	continuePipeline, result := enricher.AddDeviceMetadata(edgexcontext, event)
+	require.True(t, continuePipeline)
+
+	expected := map[string]string{
+		"site":                     "plant-1",
+		DeviceMetadataDescription:  "Boiler room sensor",
+		DeviceMetadataLabels:       "boiler,floor-1",
+		DeviceMetadataLocation:     `{"building":"north"}`,
+		DeviceMetadataService:      "device-modbus",
+		DeviceMetadataProfile:      "Temperature-Sensor",
+		DeviceMetadataManufacturer: "Acme",
+		DeviceMetadataModel:        "T-100",
+	}
+	assert.Equal(t, expected, result.(models.Event).Tags, "empty profile labels should not be added")
+	assert.Equal(t, map[string]string{"site": "plant-1"}, event.Tags, "tags of the received event should not be modified")
+}
+
+func TestAddDeviceMetadataContext(t *testing.T) {
+	edgexcontext, _ := newMetadataContext()
+	enricher, err := NewDeviceMetadataEnricher([]string{DeviceMetadataProfile}, DeviceMetadataTargetContext, "")
+	require.NoError(t, err)
+
+	event := models.Event{Device: devID1}
+	continuePipeline, result := enricher.AddDeviceMetadata(edgexcontext, event)
+	require.True(t, continuePipeline)
+	assert.Equal(t, event, result)
+
+	value, ok := edgexcontext.GetValue(DeviceMetadataProfile)
+	require.True(t, ok)
+	assert.Equal(t, "Temperature-Sensor", value)
+}
+
+func TestAddDeviceMetadataCache(t *testing.T) {
+	edgexcontext, client := newMetadataContext()
+	enricher, err := NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "1m")
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	enricher.cache.now = clock.now
+
+	for i := 0; i < 3; i++ {
+		continuePipeline, _ := enricher.AddDeviceMetadata(edgexcontext, models.Event{Device: devID1})
+		require.True(t, continuePipeline)
+	}
+	assert.Equal(t, 1, client.lookups, "device should be looked up once while cached")
+
+	clock.current = clock.current.Add(time.Minute)
+	enricher.AddDeviceMetadata(edgexcontext, models.Event{Device: devID1})
+	assert.Equal(t, 2, client.lookups, "device should be looked up again once the cache expires")
+}
+
+func TestAddDeviceMetadataLookupFailure(t *testing.T) {
+	edgexcontext, client := newMetadataContext()
+	enricher, err := NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "")
+	require.NoError(t, err)
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	enricher.cache.now = clock.now
+
+	event := models.Event{Device: devID2}
+	continuePipeline, result := enricher.AddDeviceMetadata(edgexcontext, event)
+	assert.True(t, continuePipeline, "event should be passed on when the device can't be looked up")
+	assert.Equal(t, event, result)
+
+	enricher.AddDeviceMetadata(edgexcontext, event)
+	assert.Equal(t, 1, client.lookups, "failed lookup should be cached")
+
+	clock.current = clock.current.Add(deviceMetadataFailureTTL)
+	client.err = errors.New("metadata unavailable")
+	enricher.AddDeviceMetadata(edgexcontext, event)
+	assert.Equal(t, 2, client.lookups, "device should be looked up again once the failure expires")
+}
+
+func TestAddDeviceMetadataConcurrentLookups(t *testing.T) {
+	edgexcontext, _ := newMetadataContext()
+	client := &blockingDeviceClient{fakeDeviceClient: edgexcontext.DeviceClient.(*fakeDeviceClient), release: make(chan struct{})}
+	edgexcontext.DeviceClient = client
+	enricher, err := NewDeviceMetadataEnricher([]string{DeviceMetadataProfile}, DeviceMetadataTargetTags, "")
+	require.NoError(t, err)
+
+	var wait sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wait.Add(1)
+		go func(index int) {
+			defer wait.Done()
+			_, results[index] = enricher.AddDeviceMetadata(edgexcontext, models.Event{Device: devID1})
+		}(i)
+	}
+
+	// Give the other Events time to wait for the lookup in progress before completing it
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&client.calls) == 1 }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(client.release)
+	wait.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&client.calls), "concurrent lookups of a device should be collapsed")
+	for _, result := range results {
+		assert.Equal(t, "Temperature-Sensor", result.(models.Event).Tags[DeviceMetadataProfile])
+	}
+}
+
+func TestAddDeviceMetadataErrors(t *testing.T) {
+	enricher, err := NewDeviceMetadataEnricher(nil, DeviceMetadataTargetTags, "")
+	require.NoError(t, err)
+	edgexcontext, _ := newMetadataContext()
+
+	continuePipeline, result := enricher.AddDeviceMetadata(edgexcontext)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = enricher.AddDeviceMetadata(edgexcontext, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+
+	continuePipeline, result = enricher.AddDeviceMetadata(context, models.Event{Device: devID1})
+	assert.False(t, continuePipeline)
+	assert.Contains(t, result.(error).Error(), "'Metadata' is missing from Clients configuration")
+}
@@ -17,12 +17,16 @@
 package transforms
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 
@@ -31,6 +35,9 @@ import (
 	"github.com/jcerato/app-functions-sdk-go/pkg/util"
 )
 
+// mqttV5ConnectTimeout is how long to wait for the MQTT 5 connection to be (re)established before giving up on the export
+const mqttV5ConnectTimeout = 10 * time.Second
+
 // MQTTSecretSender ...
 type MQTTSecretSender struct {
 	lock                 sync.Mutex
@@ -39,6 +46,7 @@ type MQTTSecretSender struct {
 	persistOnError       bool
 	opts                 *MQTT.ClientOptions
 	secretsLastRetrieved time.Time
+	connectionV5         *autopaho.ConnectionManager
 }
 
 // MQTTSecretConfig ...
@@ -62,6 +70,12 @@ type MQTTSecretConfig struct {
 	// AuthMode indicates what to use when connecting to the broker. Options are "none", "cacert" , "usernamepassword", "clientcert".
 	// If a CA Cert exists in the SecretPath then it will be used for all modes except "none".
 	AuthMode string
+	// ProtocolVersion is the MQTT protocol version to connect with. Options are "3.1.1" (default) and "5".
+	// Note that MQTT 5 connections are always re-established when lost.
+	ProtocolVersion string
+	// ResponseTopic is the MQTT 5 response topic sent with each message for request/reply exchanges.
+	// The CorrelationID is sent as the correlation data.
+	ResponseTopic string
 }
 
 // NewMQTTSecretSender ...
@@ -147,6 +161,15 @@ func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, param
 	if err != nil {
 		return false, err
 	}
+
+	useV5, err := secure.IsProtocolVersion5(sender.mqttConfig.ProtocolVersion)
+	if err != nil {
+		return false, err
+	}
+	if useV5 {
+		return sender.mqttSendV5(edgexcontext, exportData)
+	}
+
 	// if we havent initialized the client yet OR the cache has been invalidated (due to new/updated secrets) we need to (re)initialize the client
 	if sender.client == nil || sender.secretsLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
 		err := sender.initializeMQTTClient(edgexcontext)
@@ -174,6 +197,108 @@ func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, param
 	return true, nil
 }
 
+func (sender *MQTTSecretSender) initializeMQTTClientV5(edgexcontext *appcontext.Context) error {
+	sender.lock.Lock()
+	defer sender.lock.Unlock()
+
+	// If the conditions changed while waiting for the lock, i.e. other thread completed the initialization,
+	// then skip doing anything
+	if sender.connectionV5 != nil && !sender.secretsLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
+		return nil
+	}
+
+	brokerUrl, err := url.Parse(sender.mqttConfig.BrokerAddress)
+	if err != nil {
+		return fmt.Errorf("invalid MQTT BrokerAddress '%s': %s", sender.mqttConfig.BrokerAddress, err.Error())
+	}
+
+	config := autopaho.ClientConfig{
+		BrokerUrls: []*url.URL{brokerUrl},
+		OnConnectError: func(err error) {
+			edgexcontext.LoggingClient.Error(fmt.Sprintf("Could not connect to mqtt server for export: %s", err.Error()))
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: sender.mqttConfig.ClientId,
+		},
+	}
+
+	mqttFactory := secure.NewMqttFactory(
+		edgexcontext.LoggingClient,
+		edgexcontext.SecretProvider,
+		sender.mqttConfig.AuthMode,
+		sender.mqttConfig.SecretPath,
+		sender.mqttConfig.SkipCertVerify,
+	)
+
+	if err := mqttFactory.ConfigureV5(&config); err != nil {
+		return err
+	}
+
+	// Secrets have changed so must reconnect using the new secrets
+	if sender.connectionV5 != nil {
+		_ = sender.connectionV5.Disconnect(gocontext.Background())
+	}
+
+	edgexcontext.LoggingClient.Info("Connecting to mqtt server for MQTT 5 export")
+	connection, err := autopaho.NewConnection(gocontext.Background(), config)
+	if err != nil {
+		return err
+	}
+
+	sender.connectionV5 = connection
+	sender.secretsLastRetrieved = time.Now()
+
+	return nil
+}
+
+func (sender *MQTTSecretSender) mqttSendV5(edgexcontext *appcontext.Context, exportData []byte) (bool, interface{}) {
+	// if we havent initialized the connection yet OR the cache has been invalidated (due to new/updated secrets) we need to (re)initialize the connection
+	if sender.connectionV5 == nil || sender.secretsLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
+		err := sender.initializeMQTTClientV5(edgexcontext)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), mqttV5ConnectTimeout)
+	defer cancel()
+	if err := sender.connectionV5.AwaitConnection(ctx); err != nil {
+		sender.setRetryData(edgexcontext, exportData)
+		subMessage := "dropping event"
+		if sender.persistOnError {
+			subMessage = "persisting Event for later retry"
+		}
+		return false, fmt.Errorf("Could not connect to mqtt server for export, %s. Error: %s", subMessage, err.Error())
+	}
+
+	properties := &paho.PublishProperties{
+		ContentType:     edgexcontext.ResponseContentType,
+		CorrelationData: []byte(edgexcontext.CorrelationID),
+		ResponseTopic:   sender.mqttConfig.ResponseTopic,
+	}
+	for key, value := range edgexcontext.GetAllValues() {
+		properties.User.Add(key, value)
+	}
+
+	publish := &paho.Publish{
+		Topic:      sender.mqttConfig.Topic,
+		QoS:        sender.mqttConfig.QoS,
+		Retain:     sender.mqttConfig.Retain,
+		Payload:    exportData,
+		Properties: properties,
+	}
+
+	if _, err := sender.connectionV5.Publish(gocontext.Background(), publish); err != nil {
+		sender.setRetryData(edgexcontext, exportData)
+		return false, err
+	}
+
+	edgexcontext.LoggingClient.Debug("Sent data to MQTT 5 Broker")
+	edgexcontext.LoggingClient.Trace("Data exported", "Transport", "MQTT", clients.CorrelationHeader, edgexcontext.CorrelationID)
+
+	return true, nil
+}
+
 func (sender *MQTTSecretSender) setRetryData(ctx *appcontext.Context, exportData []byte) {
 	if sender.persistOnError {
 		ctx.RetryData = exportData
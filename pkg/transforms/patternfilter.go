@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// PatternFilter filters on device and reading names matching glob or regular expression patterns
+type PatternFilter struct {
+	Patterns []string
+	// Regex specifies the Patterns are regular expressions rather than globs
+	Regex     bool
+	FilterOut bool
+
+	matchers []*regexp.Regexp
+}
+
+// NewPatternFilter creates, initializes and returns a new instance of PatternFilter. Glob patterns support '*'
+// matching any sequence of characters and '?' matching any single character, i.e. "Random-*-Device". Regular
+// expressions use the Go syntax and must match the whole name, i.e. "Random-(Float|Integer)-Device".
+func NewPatternFilter(patterns []string, regex bool, filterOut bool) (*PatternFilter, error) {
+	filter := &PatternFilter{
+		Patterns:  patterns,
+		Regex:     regex,
+		FilterOut: filterOut,
+	}
+
+	for _, pattern := range patterns {
+		matcher, err := compilePattern(pattern, regex)
+		if err != nil {
+			return nil, err
+		}
+		filter.matchers = append(filter.matchers, matcher)
+	}
+
+	return filter, nil
+}
+
+// FilterByDeviceNamePattern filters for Events from devices whose name matches one of the Patterns. When FilterOut
+// is true the Events from matching devices are filtered out instead.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f *PatternFilter) FilterByDeviceNamePattern(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+
+	edgexcontext.LoggingClient.Debug("Filtering by DeviceName pattern")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	// No patterns to filter for, so pass events thru rather than filtering them all out.
+	if len(f.matchers) == 0 {
+		return true, event
+	}
+
+	return filterEvent(edgexcontext, event, f.FilterOut, f.matches(event.Device))
+}
+
+// FilterByReadingNamePattern filters for readings whose name matches one of the Patterns, removing the others.
+// When FilterOut is true the matching readings are removed instead. The Event is dropped when no readings remain.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f *PatternFilter) FilterByReadingNamePattern(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+
+	edgexcontext.LoggingClient.Debug("Filtering by ReadingName pattern")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	// No patterns, so pass all event and all readings thru, rather than filtering them all out.
+	if len(f.matchers) == 0 {
+		return true, event
+	}
+
+	return filterEventReadings(edgexcontext, event, f.FilterOut, func(reading models.Reading) bool {
+		return f.matches(reading.Name)
+	})
+}
+
+func (f *PatternFilter) matches(name string) bool {
+	for _, matcher := range f.matchers {
+		if matcher.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// TagFilter filters on the Event's tags
+type TagFilter struct {
+	// Tags are the tags the Event must have, the values may be glob patterns, i.e. "line-*"
+	Tags      map[string]string
+	FilterOut bool
+
+	matchers map[string]*regexp.Regexp
+}
+
+// NewTagFilter creates, initializes and returns a new instance of TagFilter
+func NewTagFilter(tags map[string]string, filterOut bool) (*TagFilter, error) {
+	filter := &TagFilter{
+		Tags:      tags,
+		FilterOut: filterOut,
+		matchers:  make(map[string]*regexp.Regexp),
+	}
+
+	for key, value := range tags {
+		matcher, err := compilePattern(value, false)
+		if err != nil {
+			return nil, err
+		}
+		filter.matchers[key] = matcher
+	}
+
+	return filter, nil
+}
+
+// FilterByTags filters for Events which have all of the Tags with a matching value. When FilterOut is true the
+// matching Events are filtered out instead.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f *TagFilter) FilterByTags(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+
+	edgexcontext.LoggingClient.Debug("Filtering by Tags")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	// No tags to filter for, so pass events thru rather than filtering them all out.
+	if len(f.matchers) == 0 {
+		return true, event
+	}
+
+	matches := true
+	for key, matcher := range f.matchers {
+		value, ok := event.Tags[key]
+		if !ok || !matcher.MatchString(value) {
+			matches = false
+			break
+		}
+	}
+
+	return filterEvent(edgexcontext, event, f.FilterOut, matches)
+}
+
+// compilePattern compiles the glob or regular expression pattern to a regular expression matching the whole name
+func compilePattern(pattern string, regex bool) (*regexp.Regexp, error) {
+	expression := pattern
+	if !regex {
+		expression = strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+		expression = strings.ReplaceAll(expression, `\?`, ".")
+	}
+
+	matcher, err := regexp.Compile("^(?:" + expression + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %s", pattern, err.Error())
+	}
+
+	return matcher, nil
+}
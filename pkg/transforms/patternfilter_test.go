@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPatternFilterInvalidRegex(t *testing.T) {
+	_, err := NewPatternFilter([]string{"Random-(Float"}, true, false)
+	assert.Error(t, err)
+}
+
+func TestFilterByDeviceNamePattern(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Patterns  []string
+		Regex     bool
+		FilterOut bool
+		Device    string
+		Expected  bool
+	}{
+		{"Glob match", []string{"Random-*-Device"}, false, false, "Random-Float-Device", true},
+		{"Glob single character", []string{"Sensor-?"}, false, false, "Sensor-1", true},
+		{"Glob no match", []string{"Random-*-Device"}, false, false, "Modbus-Device", false},
+		{"Glob must match whole name", []string{"Random"}, false, false, "Random-Float-Device", false},
+		{"Glob dot is literal", []string{"a.c"}, false, false, "abc", false},
+		{"Regex match", []string{"Random-(Float|Integer)-Device"}, true, false, "Random-Integer-Device", true},
+		{"Regex no match", []string{"Random-(Float|Integer)-Device"}, true, false, "Random-Boolean-Device", false},
+		{"Second pattern match", []string{"Modbus-*", "Random-*"}, false, false, "Random-Float-Device", true},
+		{"Filter out match", []string{"Random-*"}, false, true, "Random-Float-Device", false},
+		{"Filter out no match", []string{"Random-*"}, false, true, "Modbus-Device", true},
+		{"No patterns", nil, false, false, "Modbus-Device", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter, err := NewPatternFilter(test.Patterns, test.Regex, test.FilterOut)
+			require.NoError(t, err)
+
+			event := models.Event{Device: test.Device}
+			continuePipeline, result := filter.FilterByDeviceNamePattern(context, event)
+			assert.Equal(t, test.Expected, continuePipeline)
+			if test.Expected {
+				assert.Equal(t, event, result)
+			} else {
+				assert.Nil(t, result)
+			}
+		})
+	}
+}
+
+func TestFilterByReadingNamePattern(t *testing.T) {
+	event := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: "temperature"},
+			{Name: "temperature_avg"},
+			{Name: "humidity"},
+		},
+	}
+
+	filter, err := NewPatternFilter([]string{"temp*"}, false, false)
+	require.NoError(t, err)
+
+	continuePipeline, result := filter.FilterByReadingNamePattern(context, event)
+	require.True(t, continuePipeline)
+	assert.Equal(t, []string{"temperature", "temperature_avg"}, readingNames(result.(models.Event)))
+
+	filter.FilterOut = true
+	continuePipeline, result = filter.FilterByReadingNamePattern(context, event)
+	require.True(t, continuePipeline)
+	assert.Equal(t, []string{"humidity"}, readingNames(result.(models.Event)))
+
+	filter, err = NewPatternFilter([]string{"pressure.*"}, true, false)
+	require.NoError(t, err)
+	continuePipeline, result = filter.FilterByReadingNamePattern(context, event)
+	assert.False(t, continuePipeline, "event should be dropped when no readings remain")
+	assert.Nil(t, result)
+}
+
+func TestFilterByTags(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Tags      map[string]string
+		FilterOut bool
+		Expected  bool
+	}{
+		{"Match", map[string]string{"site": "north"}, false, true},
+		{"Match all", map[string]string{"site": "north", "line": "2"}, false, true},
+		{"Glob value", map[string]string{"site": "n*"}, false, true},
+		{"Wrong value", map[string]string{"site": "south"}, false, false},
+		{"Missing tag", map[string]string{"site": "north", "zone": "a"}, false, false},
+		{"Filter out match", map[string]string{"site": "north"}, true, false},
+		{"Filter out no match", map[string]string{"site": "south"}, true, true},
+		{"No tags", nil, false, true},
+	}
+
+	event := models.Event{Device: devID1, Tags: map[string]string{"site": "north", "line": "2"}}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			filter, err := NewTagFilter(test.Tags, test.FilterOut)
+			require.NoError(t, err)
+
+			continuePipeline, result := filter.FilterByTags(context, event)
+			assert.Equal(t, test.Expected, continuePipeline)
+			if test.Expected {
+				assert.Equal(t, event, result)
+			} else {
+				assert.Nil(t, result)
+			}
+		})
+	}
+}
+
+func TestAttributeFiltersErrors(t *testing.T) {
+	patternFilter, err := NewPatternFilter([]string{"*"}, false, false)
+	require.NoError(t, err)
+	tagFilter, err := NewTagFilter(map[string]string{"site": "*"}, false)
+	require.NoError(t, err)
+	valueFilter, err := NewValueFilter([]ValueCondition{{ReadingName: "*", Operator: ">", Value: 0}}, false)
+	require.NoError(t, err)
+
+	functions := map[string]appcontext.AppFunction{
+		"FilterByDeviceNamePattern":  patternFilter.FilterByDeviceNamePattern,
+		"FilterByReadingNamePattern": patternFilter.FilterByReadingNamePattern,
+		"FilterByTags":               tagFilter.FilterByTags,
+		"FilterByValue":              valueFilter.FilterByValue,
+		"FilterByValueType":          NewFilter([]string{models.ValueTypeInt32}).FilterByValueType,
+	}
+
+	for name, function := range functions {
+		t.Run(name, func(t *testing.T) {
+			continuePipeline, result := function(context)
+			assert.False(t, continuePipeline)
+			assert.EqualError(t, result.(error), "no Event Received")
+
+			continuePipeline, result = function(context, plainString)
+			assert.False(t, continuePipeline)
+			assert.EqualError(t, result.(error), "type received is not an Event")
+		})
+	}
+}
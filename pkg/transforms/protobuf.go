@@ -0,0 +1,130 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"sort"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContentTypeProtobuf is the response content type for Protocol Buffers encoded data
+const ContentTypeProtobuf = "application/x-protobuf"
+
+// Field numbers of the Event and Reading messages in proto/event.proto
+const (
+	eventFieldID       protowire.Number = 1
+	eventFieldPushed   protowire.Number = 2
+	eventFieldDevice   protowire.Number = 3
+	eventFieldCreated  protowire.Number = 4
+	eventFieldModified protowire.Number = 5
+	eventFieldOrigin   protowire.Number = 6
+	eventFieldReadings protowire.Number = 7
+	eventFieldTags     protowire.Number = 8
+
+	readingFieldID            protowire.Number = 1
+	readingFieldPushed        protowire.Number = 2
+	readingFieldCreated       protowire.Number = 3
+	readingFieldOrigin        protowire.Number = 4
+	readingFieldModified      protowire.Number = 5
+	readingFieldDevice        protowire.Number = 6
+	readingFieldName          protowire.Number = 7
+	readingFieldValue         protowire.Number = 8
+	readingFieldValueType     protowire.Number = 9
+	readingFieldFloatEncoding protowire.Number = 10
+	readingFieldBinaryValue   protowire.Number = 11
+	readingFieldMediaType     protowire.Number = 12
+
+	mapEntryFieldKey   protowire.Number = 1
+	mapEntryFieldValue protowire.Number = 2
+)
+
+// marshalEventProtobuf encodes the Event as the Event message defined in proto/event.proto. As with proto3,
+// fields with default values are omitted. Tags are encoded sorted by key so the output is deterministic.
+func marshalEventProtobuf(event models.Event) []byte {
+	var data []byte
+
+	data = appendProtobufString(data, eventFieldID, event.ID)
+	data = appendProtobufInt64(data, eventFieldPushed, event.Pushed)
+	data = appendProtobufString(data, eventFieldDevice, event.Device)
+	data = appendProtobufInt64(data, eventFieldCreated, event.Created)
+	data = appendProtobufInt64(data, eventFieldModified, event.Modified)
+	data = appendProtobufInt64(data, eventFieldOrigin, event.Origin)
+
+	for _, reading := range event.Readings {
+		data = protowire.AppendTag(data, eventFieldReadings, protowire.BytesType)
+		data = protowire.AppendBytes(data, marshalReadingProtobuf(reading))
+	}
+
+	keys := make([]string, 0, len(event.Tags))
+	for key := range event.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var entry []byte
+		entry = appendProtobufString(entry, mapEntryFieldKey, key)
+		entry = appendProtobufString(entry, mapEntryFieldValue, event.Tags[key])
+
+		data = protowire.AppendTag(data, eventFieldTags, protowire.BytesType)
+		data = protowire.AppendBytes(data, entry)
+	}
+
+	return data
+}
+
+func marshalReadingProtobuf(reading models.Reading) []byte {
+	var data []byte
+
+	data = appendProtobufString(data, readingFieldID, reading.Id)
+	data = appendProtobufInt64(data, readingFieldPushed, reading.Pushed)
+	data = appendProtobufInt64(data, readingFieldCreated, reading.Created)
+	data = appendProtobufInt64(data, readingFieldOrigin, reading.Origin)
+	data = appendProtobufInt64(data, readingFieldModified, reading.Modified)
+	data = appendProtobufString(data, readingFieldDevice, reading.Device)
+	data = appendProtobufString(data, readingFieldName, reading.Name)
+	data = appendProtobufString(data, readingFieldValue, reading.Value)
+	data = appendProtobufString(data, readingFieldValueType, reading.ValueType)
+	data = appendProtobufString(data, readingFieldFloatEncoding, reading.FloatEncoding)
+	if len(reading.BinaryValue) > 0 {
+		data = protowire.AppendTag(data, readingFieldBinaryValue, protowire.BytesType)
+		data = protowire.AppendBytes(data, reading.BinaryValue)
+	}
+	data = appendProtobufString(data, readingFieldMediaType, reading.MediaType)
+
+	return data
+}
+
+func appendProtobufString(data []byte, field protowire.Number, value string) []byte {
+	if len(value) == 0 {
+		return data
+	}
+
+	data = protowire.AppendTag(data, field, protowire.BytesType)
+	return protowire.AppendString(data, value)
+}
+
+func appendProtobufInt64(data []byte, field protowire.Number, value int64) []byte {
+	if value == 0 {
+		return data
+	}
+
+	data = protowire.AppendTag(data, field, protowire.VarintType)
+	return protowire.AppendVarint(data, uint64(value))
+}
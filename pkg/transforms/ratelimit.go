@@ -0,0 +1,389 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/telemetry"
+)
+
+// Keys the RateLimiter can limit the throughput by
+const (
+	RateLimitKeyGlobal  = "global"
+	RateLimitKeyDevice  = "device"
+	RateLimitKeyReading = "reading"
+)
+
+// Behaviours of the RateLimiter when the limit is exceeded
+const (
+	RateLimitModeDrop   = "drop"
+	RateLimitModeDelay  = "delay"
+	RateLimitModeLatest = "latest"
+)
+
+// RateLimiterDroppedCounter is the name of the counter, reported by the metrics endpoints, holding the number of
+// Events, or Readings when limiting by reading, dropped by the RateLimiters
+const RateLimiterDroppedCounter = "RateLimiterDropped"
+
+// tokenBucket holds the tokens available for a key, which are negative when tokens have been reserved for delayed
+// values. When a value is waiting for the next token, pending holds the latest value received for the key.
+type tokenBucket struct {
+	tokens  float64
+	last    int64
+	waiting bool
+	pending interface{}
+}
+
+// rateLimitItem is a value limited by its key, either the data received or one of the Event's readings
+type rateLimitItem struct {
+	key   string
+	value interface{}
+}
+
+// RateLimiter limits the throughput per key using token buckets, which allow Limit values per Interval with bursts
+// of up to Limit values. When the limit is exceeded the Mode determines whether the value is dropped, delayed until a
+// token is available, or only the latest value is kept and emitted when the next token is available. Delayed and
+// latest values are sent to the remaining pipeline functions in the background, so the calling goroutine is never
+// blocked waiting for a token. At most MaxBacklog values are delayed per key, further values are dropped.
+type RateLimiter struct {
+	Limit      int
+	Interval   time.Duration
+	KeyType    string
+	Mode       string
+	MaxBacklog int
+
+	buckets   map[string]*tokenBucket
+	dropped   *telemetry.Counter
+	lock      sync.Mutex
+	now       func() time.Time
+	afterFunc func(duration time.Duration, function func()) *time.Timer
+}
+
+// NewRateLimiter creates, initializes and returns a new instance of RateLimiter allowing limit values per interval,
+// i.e. 10 per "1s", for each key, one of global, device or reading, with the mode, one of drop, delay or latest,
+// determining what happens to the values exceeding the limit. The MaxBacklog defaults to the limit, so values are
+// delayed by at most the interval.
+func NewRateLimiter(limit int, interval string, keyType string, mode string) (*RateLimiter, error) {
+	if limit <= 0 {
+		return nil, errors.New("rate limit must be greater than zero")
+	}
+
+	parsedInterval, err := time.ParseDuration(interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limit interval '%s': %s", interval, err.Error())
+	}
+
+	if parsedInterval <= 0 {
+		return nil, errors.New("rate limit interval must be greater than zero")
+	}
+
+	switch keyType {
+	case RateLimitKeyGlobal, RateLimitKeyDevice, RateLimitKeyReading:
+	default:
+		return nil, fmt.Errorf("unknown rate limit key '%s'", keyType)
+	}
+
+	switch mode {
+	case RateLimitModeDrop, RateLimitModeDelay, RateLimitModeLatest:
+	default:
+		return nil, fmt.Errorf("unknown rate limit mode '%s'", mode)
+	}
+
+	return &RateLimiter{
+		Limit:      limit,
+		Interval:   parsedInterval,
+		KeyType:    keyType,
+		Mode:       mode,
+		MaxBacklog: limit,
+		buckets:    make(map[string]*tokenBucket),
+		dropped:    telemetry.GetCounter(RateLimiterDroppedCounter),
+		now:        time.Now,
+		afterFunc:  time.AfterFunc,
+	}, nil
+}
+
+// SetMaxBacklog sets the maximum number of values delayed per key in delay mode, beyond which values are dropped
+func (limiter *RateLimiter) SetMaxBacklog(maxBacklog int) error {
+	if maxBacklog <= 0 {
+		return fmt.Errorf("invalid rate limit backlog %d, must be greater than zero", maxBacklog)
+	}
+
+	limiter.MaxBacklog = maxBacklog
+	return nil
+}
+
+// RateLimit passes on the data when a token is available for its key, otherwise the data is handled according to the
+// Mode. When limiting by reading each of the Event's readings is limited separately and the Event is passed on with
+// the readings allowed through. In delay mode the pipeline is stopped and resumed with the data once its tokens are
+// available. In latest mode the first value exceeding the limit becomes the pending value for its key, which later
+// values replace, and the pipeline is resumed with the pending value once the next token is available. Values which
+// can't be delayed, since the backlog is full or the pipeline can't be resumed, are dropped.
+// The number of values dropped is reported through the RateLimiterDropped counter of the metrics endpoints.
+// It will return an error and stop the pipeline if no data is received or if limiting by device or reading and a
+// non-edgex event is received.
+func (limiter *RateLimiter) RateLimit(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Data Received")
+	}
+
+	items, err := limiter.items(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	if len(items) == 0 {
+		// Nothing to limit, i.e. an Event without readings when limiting by reading
+		return true, params[0]
+	}
+
+	var result []interface{}
+	var wait time.Duration
+	switch limiter.Mode {
+	case RateLimitModeDelay:
+		result, wait = limiter.delay(edgexcontext, items)
+	case RateLimitModeLatest:
+		result = limiter.latest(edgexcontext, items, params[0])
+	default:
+		result = limiter.drop(edgexcontext, items)
+	}
+
+	if len(result) == 0 {
+		return false, nil
+	}
+
+	output := limiter.output(params[0], result)
+	if wait > 0 {
+		edgexcontext.LoggingClient.Debug(fmt.Sprintf("Rate limit exceeded, delaying for %s", wait.String()))
+		resume := edgexcontext.ResumePipeline
+		limiter.afterFunc(wait, func() {
+			resume(output)
+		})
+		return false, nil
+	}
+
+	return true, output
+}
+
+func (limiter *RateLimiter) items(data interface{}) ([]rateLimitItem, error) {
+	if limiter.KeyType == RateLimitKeyGlobal {
+		return []rateLimitItem{{value: data}}, nil
+	}
+
+	event, ok := data.(models.Event)
+	if !ok {
+		return nil, errors.New("Unexpected type received")
+	}
+
+	if limiter.KeyType == RateLimitKeyDevice {
+		return []rateLimitItem{{key: event.Device, value: event}}, nil
+	}
+
+	items := make([]rateLimitItem, len(event.Readings))
+	for index, reading := range event.Readings {
+		items[index] = rateLimitItem{key: event.Device + "/" + reading.Name, value: reading}
+	}
+	return items, nil
+}
+
+// output returns the data to pass on with the values allowed through, which are the readings of the Event when
+// limiting by reading
+func (limiter *RateLimiter) output(data interface{}, values []interface{}) interface{} {
+	if limiter.KeyType != RateLimitKeyReading {
+		return values[0]
+	}
+
+	event := data.(models.Event)
+	event.Readings = make([]models.Reading, len(values))
+	for index, value := range values {
+		event.Readings[index] = value.(models.Reading)
+	}
+
+	return event
+}
+
+// drop returns the values for which a token is available and drops the others
+func (limiter *RateLimiter) drop(edgexcontext *appcontext.Context, items []rateLimitItem) []interface{} {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := limiter.now().UnixNano()
+	var result []interface{}
+	var dropped uint64
+
+	for _, item := range items {
+		bucket := limiter.refill(item.key, now)
+		if bucket.tokens < 1 {
+			dropped++
+			continue
+		}
+
+		bucket.tokens--
+		result = append(result, item.value)
+	}
+
+	limiter.reportDropped(edgexcontext, dropped)
+	return result
+}
+
+// delay reserves a token for each value and returns the values along with how long until the last of their tokens
+// is available. Values are dropped when their key's backlog is full or, when they would have to wait, the pipeline
+// can't be resumed.
+func (limiter *RateLimiter) delay(edgexcontext *appcontext.Context, items []rateLimitItem) ([]interface{}, time.Duration) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := limiter.now().UnixNano()
+	var result []interface{}
+	var wait time.Duration
+	var dropped uint64
+
+	for _, item := range items {
+		bucket := limiter.refill(item.key, now)
+		if bucket.tokens < 1 && (edgexcontext.ResumePipeline == nil || bucket.tokens-1 < -float64(limiter.MaxBacklog)) {
+			dropped++
+			continue
+		}
+
+		bucket.tokens--
+		result = append(result, item.value)
+		// Wait until the reserved token has been repaid
+		if bucketWait := limiter.untilTokens(bucket, 0); bucketWait > wait {
+			wait = bucketWait
+		}
+	}
+
+	limiter.reportDropped(edgexcontext, dropped)
+	return result, wait
+}
+
+// latest returns the values for which a token is available. The other values become the pending value for their
+// key, replacing, and so dropping, any previous pending value. When no value is waiting for the key's next token a
+// timer is started which resumes the pipeline with the pending values once the tokens are available.
+func (limiter *RateLimiter) latest(edgexcontext *appcontext.Context, items []rateLimitItem, data interface{}) []interface{} {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := limiter.now().UnixNano()
+	var result []interface{}
+	var waitingFor []*tokenBucket
+	var wait time.Duration
+	var dropped uint64
+
+	for _, item := range items {
+		bucket := limiter.refill(item.key, now)
+		if bucket.waiting {
+			dropped++
+			bucket.pending = item.value
+			continue
+		}
+
+		if bucket.tokens >= 1 {
+			bucket.tokens--
+			result = append(result, item.value)
+			continue
+		}
+
+		if edgexcontext.ResumePipeline == nil {
+			dropped++
+			continue
+		}
+
+		bucket.waiting = true
+		bucket.pending = item.value
+		waitingFor = append(waitingFor, bucket)
+		if bucketWait := limiter.untilTokens(bucket, 1); bucketWait > wait {
+			wait = bucketWait
+		}
+	}
+
+	limiter.reportDropped(edgexcontext, dropped)
+
+	if len(waitingFor) > 0 {
+		edgexcontext.LoggingClient.Debug(fmt.Sprintf("Rate limit exceeded, waiting %s for the next token", wait.String()))
+		resume := edgexcontext.ResumePipeline
+		limiter.afterFunc(wait, func() {
+			limiter.sendPending(waitingFor, data, resume)
+		})
+	}
+
+	return result
+}
+
+// sendPending takes a token from each of the buckets and resumes the pipeline with their pending values
+func (limiter *RateLimiter) sendPending(buckets []*tokenBucket, data interface{}, resume func(result interface{}) error) {
+	limiter.lock.Lock()
+	now := limiter.now().UnixNano()
+	pending := make([]interface{}, len(buckets))
+	for index, bucket := range buckets {
+		limiter.refillBucket(bucket, now)
+		bucket.tokens--
+		pending[index] = bucket.pending
+		bucket.pending = nil
+		bucket.waiting = false
+	}
+	limiter.lock.Unlock()
+
+	resume(limiter.output(data, pending))
+}
+
+// refill returns the key's bucket after adding the tokens accumulated since it was last refilled
+func (limiter *RateLimiter) refill(key string, now int64) *tokenBucket {
+	bucket, ok := limiter.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limiter.Limit), last: now}
+		limiter.buckets[key] = bucket
+		return bucket
+	}
+
+	limiter.refillBucket(bucket, now)
+	return bucket
+}
+
+func (limiter *RateLimiter) refillBucket(bucket *tokenBucket, now int64) {
+	if now > bucket.last {
+		bucket.tokens += float64(now-bucket.last) * float64(limiter.Limit) / float64(limiter.Interval)
+		if bucket.tokens > float64(limiter.Limit) {
+			bucket.tokens = float64(limiter.Limit)
+		}
+		bucket.last = now
+	}
+}
+
+// untilTokens returns how long until the bucket holds the number of tokens
+func (limiter *RateLimiter) untilTokens(bucket *tokenBucket, tokens float64) time.Duration {
+	needed := tokens - bucket.tokens
+	if needed <= 0 {
+		return 0
+	}
+
+	return time.Duration(needed * float64(limiter.Interval) / float64(limiter.Limit))
+}
+
+func (limiter *RateLimiter) reportDropped(edgexcontext *appcontext.Context, dropped uint64) {
+	if dropped == 0 {
+		return
+	}
+
+	limiter.dropped.Add(dropped)
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Rate limit exceeded, dropped %d values", dropped))
+}
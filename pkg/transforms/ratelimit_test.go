@@ -0,0 +1,224 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRateLimiter(t *testing.T, limit int, keyType string, mode string) (*RateLimiter, *fakeClock, *fakeTimers) {
+	limiter, err := NewRateLimiter(limit, "1s", keyType, mode)
+	require.NoError(t, err)
+
+	clock := &fakeClock{current: time.Unix(1000, 0)}
+	limiter.now = clock.now
+	timers := &fakeTimers{}
+	limiter.afterFunc = timers.afterFunc
+
+	return limiter, clock, timers
+}
+
+func newRateLimitEvent(device string, readingNames ...string) models.Event {
+	event := models.Event{Device: device}
+	for _, name := range readingNames {
+		event.Readings = append(event.Readings, models.Reading{Device: device, Name: name, Value: "1", ValueType: models.ValueTypeInt32})
+	}
+	return event
+}
+
+func TestNewRateLimiterErrors(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Limit    int
+		Interval string
+		KeyType  string
+		Mode     string
+	}{
+		{"Zero limit", 0, "1s", RateLimitKeyGlobal, RateLimitModeDrop},
+		{"Bad interval", 1, "abc", RateLimitKeyGlobal, RateLimitModeDrop},
+		{"Zero interval", 1, "0s", RateLimitKeyGlobal, RateLimitModeDrop},
+		{"Bad key", 1, "1s", "profile", RateLimitModeDrop},
+		{"Bad mode", 1, "1s", RateLimitKeyGlobal, "queue"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewRateLimiter(test.Limit, test.Interval, test.KeyType, test.Mode)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	limiter, clock, _ := newTestRateLimiter(t, 2, RateLimitKeyDevice, RateLimitModeDrop)
+	counter := telemetry.GetCounter(RateLimiterDroppedCounter)
+	before := counter.Count()
+
+	event1 := newRateLimitEvent(devID1, "temperature")
+	event2 := newRateLimitEvent(devID2, "temperature")
+
+	// Burst of up to the limit is allowed
+	for i := 0; i < 2; i++ {
+		continuePipeline, result := limiter.RateLimit(context, event1)
+		assert.True(t, continuePipeline)
+		assert.Equal(t, event1, result)
+	}
+
+	continuePipeline, result := limiter.RateLimit(context, event1)
+	assert.False(t, continuePipeline, "event over the limit should be dropped")
+	assert.Nil(t, result)
+
+	continuePipeline, _ = limiter.RateLimit(context, event2)
+	assert.True(t, continuePipeline, "other device has its own limit")
+
+	// One token is added every half second
+	clock.current = clock.current.Add(500 * time.Millisecond)
+	continuePipeline, _ = limiter.RateLimit(context, event1)
+	assert.True(t, continuePipeline)
+	continuePipeline, _ = limiter.RateLimit(context, event1)
+	assert.False(t, continuePipeline)
+
+	assert.Equal(t, uint64(2), counter.Count()-before)
+	assert.Equal(t, uint64(2), telemetry.Counters()[RateLimiterDroppedCounter]-before)
+}
+
+func TestRateLimitDropByReading(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(t, 1, RateLimitKeyReading, RateLimitModeDrop)
+
+	continuePipeline, _ := limiter.RateLimit(context, newRateLimitEvent(devID1, "temperature"))
+	assert.True(t, continuePipeline)
+
+	continuePipeline, result := limiter.RateLimit(context, newRateLimitEvent(devID1, "temperature", "humidity"))
+	require.True(t, continuePipeline)
+	event := result.(models.Event)
+	require.Len(t, event.Readings, 1)
+	assert.Equal(t, "humidity", event.Readings[0].Name)
+
+	continuePipeline, _ = limiter.RateLimit(context, newRateLimitEvent(devID1, "temperature", "humidity"))
+	assert.False(t, continuePipeline, "event should be dropped when all its readings are over the limit")
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	limiter, _, timers := newTestRateLimiter(t, 2, RateLimitKeyGlobal, RateLimitModeDelay)
+	edgexcontext, resumed := newResumableContext()
+	counter := telemetry.GetCounter(RateLimiterDroppedCounter)
+	before := counter.Count()
+
+	for i := 0; i < 2; i++ {
+		continuePipeline, result := limiter.RateLimit(edgexcontext, plainString)
+		assert.True(t, continuePipeline)
+		assert.Equal(t, plainString, result)
+	}
+	assert.Empty(t, timers.durations, "burst should not be delayed")
+
+	// Delayed values are sent in the background once their tokens are available
+	for i := 0; i < 2; i++ {
+		continuePipeline, result := limiter.RateLimit(edgexcontext, plainString)
+		assert.False(t, continuePipeline)
+		assert.Nil(t, result)
+	}
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, timers.durations)
+
+	// The backlog is full
+	continuePipeline, _ := limiter.RateLimit(edgexcontext, plainString)
+	assert.False(t, continuePipeline)
+	assert.Len(t, timers.durations, 2)
+	assert.Equal(t, uint64(1), counter.Count()-before)
+
+	for _, function := range timers.functions {
+		function()
+	}
+	require.Len(t, resumed, 2)
+	assert.Equal(t, plainString, <-resumed)
+
+	// Values which would be delayed are dropped when the pipeline can't be resumed
+	continuePipeline, _ = limiter.RateLimit(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.Equal(t, uint64(2), counter.Count()-before)
+}
+
+func TestRateLimitDelayByReading(t *testing.T) {
+	limiter, _, timers := newTestRateLimiter(t, 1, RateLimitKeyReading, RateLimitModeDelay)
+	require.NoError(t, limiter.SetMaxBacklog(1))
+	assert.Error(t, limiter.SetMaxBacklog(0))
+	edgexcontext, resumed := newResumableContext()
+
+	continuePipeline, _ := limiter.RateLimit(edgexcontext, newRateLimitEvent(devID1, "temperature"))
+	assert.True(t, continuePipeline)
+	continuePipeline, _ = limiter.RateLimit(edgexcontext, newRateLimitEvent(devID1, "temperature"))
+	assert.False(t, continuePipeline)
+
+	// The temperature backlog is full, so only the humidity reading is passed on
+	continuePipeline, result := limiter.RateLimit(edgexcontext, newRateLimitEvent(devID1, "temperature", "humidity"))
+	require.True(t, continuePipeline)
+	event := result.(models.Event)
+	require.Len(t, event.Readings, 1)
+	assert.Equal(t, "humidity", event.Readings[0].Name)
+
+	require.Len(t, timers.functions, 1)
+	timers.functions[0]()
+	event = (<-resumed).(models.Event)
+	require.Len(t, event.Readings, 1)
+	assert.Equal(t, "temperature", event.Readings[0].Name)
+}
+
+func TestRateLimitLatest(t *testing.T) {
+	limiter, clock, timers := newTestRateLimiter(t, 1, RateLimitKeyGlobal, RateLimitModeLatest)
+	edgexcontext, resumed := newResumableContext()
+	counter := telemetry.GetCounter(RateLimiterDroppedCounter)
+	before := counter.Count()
+
+	continuePipeline, result := limiter.RateLimit(edgexcontext, "first")
+	assert.True(t, continuePipeline)
+	assert.Equal(t, "first", result)
+
+	// Values received while waiting for the next token replace the pending value
+	for _, value := range []string{"second", "third", "fourth"} {
+		continuePipeline, _ := limiter.RateLimit(edgexcontext, value)
+		assert.False(t, continuePipeline)
+	}
+	assert.Equal(t, []time.Duration{time.Second}, timers.durations)
+	assert.Equal(t, uint64(2), counter.Count()-before)
+
+	clock.current = clock.current.Add(time.Second)
+	timers.functions[0]()
+	require.Len(t, resumed, 1)
+	assert.Equal(t, "fourth", <-resumed, "latest value should be emitted when the next token is available")
+
+	// Waiting again once the pending value has been sent
+	continuePipeline, _ = limiter.RateLimit(edgexcontext, "fifth")
+	assert.False(t, continuePipeline)
+	assert.Len(t, timers.durations, 2)
+}
+
+func TestRateLimitErrors(t *testing.T) {
+	limiter, _, _ := newTestRateLimiter(t, 1, RateLimitKeyDevice, RateLimitModeDrop)
+
+	continuePipeline, result := limiter.RateLimit(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Data Received")
+
+	continuePipeline, result = limiter.RateLimit(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
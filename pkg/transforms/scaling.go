@@ -0,0 +1,292 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+// UnitsTagSuffix is appended to the reading name to form the Event tag holding the reading's units,
+// i.e. the "temperature_units" tag holds the units of the temperature reading.
+const UnitsTagSuffix = "_units"
+
+// ScalingRule specifies how the value of the readings with the ReadingName are scaled. The value is
+// first multiplied by the Scale and the Offset added, then converted from the FromUnits to the ToUnits.
+type ScalingRule struct {
+	ReadingName string
+	// Scale multiplies the value, zero is treated as 1 so rules only converting units can leave it unset
+	Scale float64
+	// Offset is added to the scaled value
+	Offset float64
+	// FromUnits are the units of the scaled value. Optional, but required when ToUnits is set.
+	FromUnits string
+	// ToUnits are the units the value is converted to. When FromUnits is empty no conversion is done
+	// and the units are only recorded.
+	ToUnits string
+
+	convert func(float64) float64
+}
+
+// ParseScalingRules parses a comma separated list of rules, each in the form
+// "readingname: scale=<n> offset=<n> from=<units> to=<units>" where all the settings are optional,
+// i.e. "temperature: from=degF to=degC, level: scale=0.1 offset=-5 to=cm".
+func ParseScalingRules(rules string) ([]ScalingRule, error) {
+	var result []ScalingRule
+
+	for _, text := range util.DeleteEmptyAndTrim(strings.FieldsFunc(rules, util.SplitComma)) {
+		parts := strings.SplitN(text, ":", 2)
+		rule := ScalingRule{ReadingName: strings.TrimSpace(parts[0]), Scale: 1}
+		if len(rule.ReadingName) == 0 {
+			return nil, fmt.Errorf("missing reading name in scaling rule '%s'", text)
+		}
+
+		if len(parts) == 2 {
+			for _, setting := range strings.Fields(parts[1]) {
+				keyValue := strings.SplitN(setting, "=", 2)
+				if len(keyValue) != 2 {
+					return nil, fmt.Errorf("invalid setting '%s' in scaling rule '%s', must be key=value", setting, text)
+				}
+
+				var err error
+				switch strings.ToLower(keyValue[0]) {
+				case "scale":
+					rule.Scale, err = strconv.ParseFloat(keyValue[1], 64)
+					if err == nil && rule.Scale == 0 {
+						err = errors.New("scale can't be zero")
+					}
+				case "offset":
+					rule.Offset, err = strconv.ParseFloat(keyValue[1], 64)
+				case "from":
+					rule.FromUnits = keyValue[1]
+				case "to":
+					rule.ToUnits = keyValue[1]
+				default:
+					err = fmt.Errorf("unknown setting '%s'", keyValue[0])
+				}
+
+				if err != nil {
+					return nil, fmt.Errorf("invalid scaling rule '%s': %s", text, err.Error())
+				}
+			}
+		}
+
+		result = append(result, rule)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no scaling rules specified")
+	}
+
+	return result, nil
+}
+
+// Scaling scales and converts the units of reading values according to the rule for the reading's name.
+// Readings without a rule are left unchanged.
+type Scaling struct {
+	rules map[string]ScalingRule
+}
+
+// NewScaling creates, initializes and returns a new instance of Scaling. An error is returned if a
+// rule's units can't be converted or there are multiple rules for the same reading name.
+func NewScaling(rules []ScalingRule) (*Scaling, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no scaling rules specified")
+	}
+
+	scaling := &Scaling{rules: make(map[string]ScalingRule)}
+
+	for _, rule := range rules {
+		if _, exists := scaling.rules[rule.ReadingName]; exists {
+			return nil, fmt.Errorf("multiple scaling rules for reading '%s'", rule.ReadingName)
+		}
+
+		if rule.Scale == 0 {
+			rule.Scale = 1
+		}
+
+		if len(rule.FromUnits) > 0 {
+			if len(rule.ToUnits) == 0 {
+				return nil, fmt.Errorf("scaling rule for reading '%s' specifies from units without to units", rule.ReadingName)
+			}
+
+			var err error
+			rule.convert, err = unitConverter(rule.FromUnits, rule.ToUnits)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scaling rule for reading '%s': %s", rule.ReadingName, err.Error())
+			}
+		}
+
+		scaling.rules[rule.ReadingName] = rule
+	}
+
+	return scaling, nil
+}
+
+// ScaleReadings applies the scaling rules to the Event's readings. Integer readings are written back as
+// Float64 since scaling generally results in fractional values, float readings keep their type and float
+// encoding. When the rule has ToUnits the units are recorded in the Event's "<readingname>_units" tag.
+// It will return an error and stop the pipeline if a non-edgex event is received, if no data is received or
+// if a reading's value can't be parsed.
+func (scaling *Scaling) ScaleReadings(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+	edgexcontext.LoggingClient.Debug("Scaling readings")
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	// The readings and tags are copied so the received Event isn't modified
+	readings := make([]models.Reading, len(event.Readings))
+	copy(readings, event.Readings)
+	event.Readings = readings
+	event.Tags = copyTags(event.Tags)
+
+	for index, reading := range event.Readings {
+		rule, ok := scaling.rules[reading.Name]
+		if !ok {
+			continue
+		}
+
+		scaled, err := scaleReading(reading, rule)
+		if err != nil {
+			return false, fmt.Errorf("unable to scale reading '%s': %s", reading.Name, err.Error())
+		}
+		event.Readings[index] = scaled
+
+		if len(rule.ToUnits) > 0 {
+			if event.Tags == nil {
+				event.Tags = make(map[string]string)
+			}
+			event.Tags[reading.Name+UnitsTagSuffix] = rule.ToUnits
+		}
+	}
+
+	return true, event
+}
+
+func scaleReading(reading models.Reading, rule ScalingRule) (models.Reading, error) {
+	value, err := parseReadingValue(reading)
+	if err != nil {
+		return reading, err
+	}
+
+	value = value*rule.Scale + rule.Offset
+	if rule.convert != nil {
+		value = rule.convert(value)
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return reading, fmt.Errorf("scaled value %v is not a finite number", value)
+	}
+
+	if reading.ValueType != models.ValueTypeFloat32 {
+		reading.ValueType = models.ValueTypeFloat64
+	}
+
+	if reading.FloatEncoding != models.Base64Encoding {
+		reading.FloatEncoding = models.ENotation
+	}
+
+	reading.Value, err = formatFloatValue(value, reading.ValueType, reading.FloatEncoding)
+	return reading, err
+}
+
+// parseReadingValue parses the reading's value according to its ValueType and, for floats, its FloatEncoding
+func parseReadingValue(reading models.Reading) (float64, error) {
+	switch reading.ValueType {
+	case models.ValueTypeInt8, models.ValueTypeInt16, models.ValueTypeInt32, models.ValueTypeInt64:
+		value, err := strconv.ParseInt(reading.Value, 10, 64)
+		return float64(value), err
+
+	case models.ValueTypeUint8, models.ValueTypeUint16, models.ValueTypeUint32, models.ValueTypeUint64:
+		value, err := strconv.ParseUint(reading.Value, 10, 64)
+		return float64(value), err
+
+	case models.ValueTypeFloat32, models.ValueTypeFloat64:
+		if reading.FloatEncoding == models.Base64Encoding {
+			return decodeBase64Float(reading.Value, reading.ValueType)
+		}
+		return strconv.ParseFloat(reading.Value, 64)
+
+	default:
+		return 0, fmt.Errorf("value type '%s' can't be scaled", reading.ValueType)
+	}
+}
+
+// decodeBase64Float decodes the base64 encoded big-endian IEEE 754 value used by the EdgeX Base64 float encoding
+func decodeBase64Float(value string, valueType string) (float64, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return 0, err
+	}
+
+	reader := bytes.NewReader(data)
+	if valueType == models.ValueTypeFloat32 {
+		var float float32
+		err = binary.Read(reader, binary.BigEndian, &float)
+		return float64(float), err
+	}
+
+	var float float64
+	err = binary.Read(reader, binary.BigEndian, &float)
+	return float, err
+}
+
+func formatFloatValue(value float64, valueType string, floatEncoding string) (string, error) {
+	if floatEncoding == models.Base64Encoding {
+		buffer := &bytes.Buffer{}
+		var err error
+		if valueType == models.ValueTypeFloat32 {
+			err = binary.Write(buffer, binary.BigEndian, float32(value))
+		} else {
+			err = binary.Write(buffer, binary.BigEndian, value)
+		}
+		return base64.StdEncoding.EncodeToString(buffer.Bytes()), err
+	}
+
+	if valueType == models.ValueTypeFloat32 {
+		return strconv.FormatFloat(value, 'e', -1, 32), nil
+	}
+
+	return strconv.FormatFloat(value, 'e', -1, 64), nil
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(tags))
+	for key, value := range tags {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,228 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func base64Float64(value float64) string {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(value))
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func base64Float32(value float32) string {
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint32(data, math.Float32bits(value))
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestParseScalingRules(t *testing.T) {
+	rules, err := ParseScalingRules("temperature: from=degF to=degC, level: scale=0.1 offset=-5 to=cm, raw")
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+
+	assert.Equal(t, ScalingRule{ReadingName: "temperature", Scale: 1, FromUnits: "degF", ToUnits: "degC"}, rules[0])
+	assert.Equal(t, ScalingRule{ReadingName: "level", Scale: 0.1, Offset: -5, ToUnits: "cm"}, rules[1])
+	assert.Equal(t, ScalingRule{ReadingName: "raw", Scale: 1}, rules[2])
+
+	tests := []struct {
+		Name  string
+		Rules string
+	}{
+		{"Empty", ""},
+		{"Missing name", ": scale=2"},
+		{"Not key value", "temperature: scale"},
+		{"Unknown setting", "temperature: factor=2"},
+		{"Bad scale", "temperature: scale=abc"},
+		{"Zero scale", "temperature: scale=0"},
+		{"Bad offset", "temperature: offset=abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ParseScalingRules(test.Rules)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewScalingErrors(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Rules []ScalingRule
+	}{
+		{"No rules", nil},
+		{"Duplicate rules", []ScalingRule{{ReadingName: "a", Scale: 1}, {ReadingName: "a", Scale: 2}}},
+		{"From without to", []ScalingRule{{ReadingName: "a", Scale: 1, FromUnits: "degF"}}},
+		{"Unknown from units", []ScalingRule{{ReadingName: "a", Scale: 1, FromUnits: "bogus", ToUnits: "degC"}}},
+		{"Unknown to units", []ScalingRule{{ReadingName: "a", Scale: 1, FromUnits: "degF", ToUnits: "bogus"}}},
+		{"Different quantities", []ScalingRule{{ReadingName: "a", Scale: 1, FromUnits: "degF", ToUnits: "kPa"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewScaling(test.Rules)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUnitConverter(t *testing.T) {
+	tests := []struct {
+		From     string
+		To       string
+		Value    float64
+		Expected float64
+	}{
+		{"degF", "degC", 212, 100},
+		{"degF", "degC", 32, 0},
+		{"°C", "°F", -40, -40},
+		{"degC", "K", 0, 273.15},
+		{"psi", "kPa", 1, 6.894757293168},
+		{"bar", "hPa", 1, 1000},
+		{"ft", "m", 10, 3.048},
+		{"lb", "kg", 1, 0.45359237},
+		{"km/h", "m/s", 36, 10},
+		{"gal", "L", 1, 3.785411784},
+	}
+
+	for _, test := range tests {
+		t.Run(test.From+" to "+test.To, func(t *testing.T) {
+			convert, err := unitConverter(test.From, test.To)
+			require.NoError(t, err)
+			assert.InDelta(t, test.Expected, convert(test.Value), 1e-9)
+		})
+	}
+}
+
+func TestScaleReadings(t *testing.T) {
+	rules, err := ParseScalingRules("temperature: from=degF to=degC, pressure: from=psi to=kPa, level: scale=0.5 offset=-5, counter: scale=2, ratio: scale=10")
+	require.NoError(t, err)
+	scaling, err := NewScaling(rules)
+	require.NoError(t, err)
+
+	eventIn := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "2.12e+02", ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation},
+			{Name: "pressure", Value: base64Float64(10), ValueType: models.ValueTypeFloat64, FloatEncoding: models.Base64Encoding},
+			{Name: "level", Value: "30", ValueType: models.ValueTypeInt32},
+			{Name: "counter", Value: "21", ValueType: models.ValueTypeUint16},
+			{Name: "ratio", Value: base64Float32(0.25), ValueType: models.ValueTypeFloat32, FloatEncoding: models.Base64Encoding},
+			{Name: "status", Value: "ok", ValueType: models.ValueTypeString},
+		},
+	}
+
+	continuePipeline, result := scaling.ScaleReadings(context, eventIn)
+	require.True(t, continuePipeline, result)
+	event := result.(models.Event)
+
+	temperature := event.Readings[0]
+	assert.Equal(t, models.ValueTypeFloat64, temperature.ValueType)
+	assert.Equal(t, models.ENotation, temperature.FloatEncoding)
+	value, err := strconv.ParseFloat(temperature.Value, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, 100, value, 1e-9)
+
+	pressure := event.Readings[1]
+	assert.Equal(t, models.Base64Encoding, pressure.FloatEncoding)
+	value, err = decodeBase64Float(pressure.Value, models.ValueTypeFloat64)
+	require.NoError(t, err)
+	assert.InDelta(t, 68.94757293168, value, 1e-9)
+
+	// Integer readings are written back as Float64
+	level := event.Readings[2]
+	assert.Equal(t, models.ValueTypeFloat64, level.ValueType)
+	assert.Equal(t, models.ENotation, level.FloatEncoding)
+	assert.Equal(t, "1e+01", level.Value)
+
+	counter := event.Readings[3]
+	assert.Equal(t, models.ValueTypeFloat64, counter.ValueType)
+	assert.Equal(t, "4.2e+01", counter.Value)
+
+	ratio := event.Readings[4]
+	assert.Equal(t, models.ValueTypeFloat32, ratio.ValueType)
+	assert.Equal(t, base64Float32(2.5), ratio.Value)
+
+	// Readings without a rule are left unchanged
+	assert.Equal(t, eventIn.Readings[5], event.Readings[5])
+
+	assert.Equal(t, map[string]string{"temperature" + UnitsTagSuffix: "degC", "pressure" + UnitsTagSuffix: "kPa"}, event.Tags)
+
+	// The received Event isn't modified
+	assert.Equal(t, "2.12e+02", eventIn.Readings[0].Value)
+	assert.Nil(t, eventIn.Tags)
+}
+
+func TestScaleReadingsUnsetScale(t *testing.T) {
+	scaling, err := NewScaling([]ScalingRule{
+		{ReadingName: "temperature", FromUnits: "degF", ToUnits: "degC"},
+		{ReadingName: "level", Offset: -5},
+	})
+	require.NoError(t, err)
+
+	eventIn := models.Event{
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "2.12e+02", ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation},
+			{Name: "level", Value: "30", ValueType: models.ValueTypeInt32},
+		},
+	}
+
+	continuePipeline, result := scaling.ScaleReadings(context, eventIn)
+	require.True(t, continuePipeline, result)
+	event := result.(models.Event)
+
+	value, err := strconv.ParseFloat(event.Readings[0].Value, 64)
+	require.NoError(t, err)
+	assert.InDelta(t, 100, value, 1e-9)
+	assert.Equal(t, "2.5e+01", event.Readings[1].Value)
+}
+
+func TestScaleReadingsErrors(t *testing.T) {
+	scaling, err := NewScaling([]ScalingRule{{ReadingName: "temperature", Scale: 2}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name     string
+		Data     []interface{}
+		Expected string
+	}{
+		{"No data", nil, "No Event Received"},
+		{"Not an event", []interface{}{"data"}, "Unexpected type received"},
+		{"Bad value", []interface{}{models.Event{Readings: []models.Reading{{Name: "temperature", Value: "hot", ValueType: models.ValueTypeInt32}}}}, "unable to scale reading 'temperature'"},
+		{"Bad base64 value", []interface{}{models.Event{Readings: []models.Reading{{Name: "temperature", Value: "!!", ValueType: models.ValueTypeFloat64, FloatEncoding: models.Base64Encoding}}}}, "unable to scale reading 'temperature'"},
+		{"Non numeric value type", []interface{}{models.Event{Readings: []models.Reading{{Name: "temperature", Value: "true", ValueType: models.ValueTypeBool}}}}, "value type 'Bool' can't be scaled"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			continuePipeline, result := scaling.ScaleReadings(context, test.Data...)
+			assert.False(t, continuePipeline)
+			assert.Contains(t, result.(error).Error(), test.Expected)
+		})
+	}
+}
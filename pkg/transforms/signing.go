@@ -0,0 +1,324 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+const (
+	// SigningAlgorithmHMACSHA256 signs with HMAC-SHA256 using a shared secret key
+	SigningAlgorithmHMACSHA256 = "HMAC-SHA256"
+	// SigningAlgorithmEd25519 signs with an Ed25519 PEM encoded private key and verifies with the public key
+	SigningAlgorithmEd25519 = "Ed25519"
+	// SigningAlgorithmECDSA signs the SHA-256 digest with an ECDSA PEM encoded private key and verifies with the public key
+	SigningAlgorithmECDSA = "ECDSA-SHA256"
+
+	// SignatureModeHeader attaches the signature as the SignatureKey context value, which is sent as a header by exports
+	SignatureModeHeader = "header"
+	// SignatureModeWrapper wraps the data and the signature in a SignedData JSON object
+	SignatureModeWrapper = "wrapper"
+
+	// SignatureKey is the context value key, and HTTP header, for the base64 encoded signature
+	SignatureKey = internal.SignatureHeaderKey
+	// SignatureAlgorithmKey is the context value key, and HTTP header, for the signing algorithm
+	SignatureAlgorithmKey = internal.SignatureAlgorithmHeaderKey
+)
+
+// SignedData is the wrapper for signed data when using the "wrapper" signature mode
+type SignedData struct {
+	Algorithm string
+	Signature []byte
+	Payload   []byte
+}
+
+// Signing signs data and verifies signed data using a key retrieved from the SecretProvider. The key is
+// re-retrieved when the secrets are updated, so key rotation is picked up. For HMAC-SHA256 the secret is the shared
+// key. For Ed25519 and ECDSA the secret is the PEM encoded private key for signing and public key for verifying.
+type Signing struct {
+	Algorithm  string
+	Mode       string
+	SecretPath string
+	SecretName string
+
+	key              interface{}
+	keyLastRetrieved time.Time
+	lock             sync.Mutex
+}
+
+// NewSigning creates, initializes and returns a new instance of Signing. An error is returned if the
+// algorithm or mode is not supported. The mode defaults to "header" when empty.
+func NewSigning(algorithm string, mode string, secretPath string, secretName string) (*Signing, error) {
+	switch algorithm {
+	case SigningAlgorithmHMACSHA256, SigningAlgorithmEd25519, SigningAlgorithmECDSA:
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm '%s', must be '%s', '%s' or '%s'",
+			algorithm, SigningAlgorithmHMACSHA256, SigningAlgorithmEd25519, SigningAlgorithmECDSA)
+	}
+
+	mode = strings.ToLower(mode)
+	switch mode {
+	case "":
+		mode = SignatureModeHeader
+	case SignatureModeHeader, SignatureModeWrapper:
+	default:
+		return nil, fmt.Errorf("unsupported signature mode '%s', must be '%s' or '%s'",
+			mode, SignatureModeHeader, SignatureModeWrapper)
+	}
+
+	return &Signing{
+		Algorithm:  algorithm,
+		Mode:       mode,
+		SecretPath: secretPath,
+		SecretName: secretName,
+	}, nil
+}
+
+// SignData signs a string, []byte, or json.Marshaller type. In "header" mode the data is returned as a []byte
+// and the base64 encoded signature is set as the SignatureKey context value. In "wrapper" mode the JSON encoded
+// SignedData is returned.
+func (signing *Signing) SignData(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("no data received to sign")
+	}
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Signing with %s", signing.Algorithm))
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	key, err := signing.getKey(edgexcontext, true)
+	if err != nil {
+		return false, err
+	}
+
+	signature, err := signing.sign(key, data)
+	if err != nil {
+		return false, err
+	}
+
+	if signing.Mode == SignatureModeWrapper {
+		wrapped, err := json.Marshal(SignedData{
+			Algorithm: signing.Algorithm,
+			Signature: signature,
+			Payload:   data,
+		})
+		if err != nil {
+			return false, fmt.Errorf("unable to marshal signed data: %s", err.Error())
+		}
+
+		edgexcontext.ResponseContentType = clients.ContentTypeJSON
+		return true, wrapped
+	}
+
+	edgexcontext.AddValue(SignatureKey, base64.StdEncoding.EncodeToString(signature))
+	edgexcontext.AddValue(SignatureAlgorithmKey, signing.Algorithm)
+
+	return true, data
+}
+
+// VerifySignature verifies the signature of the data, rejecting unsigned or tampered data. In "header" mode the
+// base64 encoded signature is read from the SignatureKey context value, i.e. the X-Signature HTTP header, and the
+// data is returned as a []byte. In "wrapper" mode the data must be a JSON encoded SignedData and the payload is
+// returned as a []byte. The data must be the bytes which were signed, so the target type must be a byte array
+// when verifying data received by the trigger.
+func (signing *Signing) VerifySignature(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("no data received to verify")
+	}
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Verifying signature with %s", signing.Algorithm))
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+
+	var signature []byte
+	var algorithm string
+
+	if signing.Mode == SignatureModeWrapper {
+		signed := SignedData{}
+		if err := json.Unmarshal(data, &signed); err != nil {
+			return false, fmt.Errorf("unable to unmarshal signed data: %s", err.Error())
+		}
+
+		data = signed.Payload
+		signature = signed.Signature
+		algorithm = signed.Algorithm
+	} else {
+		encoded, ok := edgexcontext.GetValue(SignatureKey)
+		if !ok {
+			return false, errors.New("data is not signed, no signature found")
+		}
+
+		signature, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return false, fmt.Errorf("unable to decode signature: %s", err.Error())
+		}
+
+		algorithm, _ = edgexcontext.GetValue(SignatureAlgorithmKey)
+	}
+
+	if len(signature) == 0 {
+		return false, errors.New("data is not signed, no signature found")
+	}
+
+	if len(algorithm) > 0 && algorithm != signing.Algorithm {
+		return false, fmt.Errorf("data signed with '%s', expected '%s'", algorithm, signing.Algorithm)
+	}
+
+	key, err := signing.getKey(edgexcontext, false)
+	if err != nil {
+		return false, err
+	}
+
+	if !signing.verify(key, data, signature) {
+		return false, errors.New("signature verification failed")
+	}
+
+	// The signature has been consumed and must not be passed on with the data
+	edgexcontext.RemoveValue(SignatureKey)
+	edgexcontext.RemoveValue(SignatureAlgorithmKey)
+
+	return true, data
+}
+
+func (signing *Signing) sign(key interface{}, data []byte) ([]byte, error) {
+	switch signingKey := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, signingKey)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+
+	case ed25519.PrivateKey:
+		return ed25519.Sign(signingKey, data), nil
+
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.SignASN1(rand.Reader, signingKey, digest[:])
+
+	default:
+		return nil, fmt.Errorf("unable to sign with key of type %T", key)
+	}
+}
+
+func (signing *Signing) verify(key interface{}, data []byte, signature []byte) bool {
+	switch verifyKey := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, verifyKey)
+		mac.Write(data)
+		return hmac.Equal(signature, mac.Sum(nil))
+
+	case ed25519.PublicKey:
+		return ed25519.Verify(verifyKey, data, signature)
+
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(verifyKey, digest[:], signature)
+
+	default:
+		return false
+	}
+}
+
+// getKey returns the signing or verification key, retrieving it from the SecretProvider when not yet
+// retrieved or when the secrets have been updated since.
+func (signing *Signing) getKey(edgexcontext *appcontext.Context, private bool) (interface{}, error) {
+	signing.lock.Lock()
+	defer signing.lock.Unlock()
+
+	if signing.key != nil && !signing.keyLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
+		return signing.key, nil
+	}
+
+	secrets, err := edgexcontext.GetSecrets(signing.SecretPath, signing.SecretName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve signing key from SecretPath '%s': %s", signing.SecretPath, err.Error())
+	}
+
+	value, ok := secrets[signing.SecretName]
+	if !ok || len(value) == 0 {
+		return nil, fmt.Errorf("signing key secret '%s' not found in SecretPath '%s'", signing.SecretName, signing.SecretPath)
+	}
+
+	key, err := parseSigningKey(signing.Algorithm, value, private)
+	if err != nil {
+		return nil, err
+	}
+
+	signing.key = key
+	signing.keyLastRetrieved = time.Now()
+
+	return signing.key, nil
+}
+
+func parseSigningKey(algorithm string, value string, private bool) (interface{}, error) {
+	if algorithm == SigningAlgorithmHMACSHA256 {
+		return []byte(value), nil
+	}
+
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, fmt.Errorf("%s key must be PEM encoded", algorithm)
+	}
+
+	var key interface{}
+	var err error
+
+	switch {
+	case private && block.Type == "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case private:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s key: %s", algorithm, err.Error())
+	}
+
+	switch key.(type) {
+	case ed25519.PrivateKey, ed25519.PublicKey:
+		if algorithm == SigningAlgorithmEd25519 {
+			return key, nil
+		}
+	case *ecdsa.PrivateKey, *ecdsa.PublicKey:
+		if algorithm == SigningAlgorithmECDSA {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key of type %T can not be used for %s", key, algorithm)
+}
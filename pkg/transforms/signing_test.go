@@ -0,0 +1,265 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	bootstrapConfig "github.com/edgexfoundry/go-mod-bootstrap/config"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/security"
+)
+
+const (
+	signingSecretPath  = "signing"
+	signingPrivateName = "private"
+	signingPublicName  = "public"
+)
+
+func newSigningContext(t *testing.T, secrets map[string]string) (*appcontext.Context, *security.SecretProviderMock) {
+	secretProvider := security.NewSecretProviderMock(&common.ConfigurationStruct{
+		SecretStoreExclusive: bootstrapConfig.SecretStoreInfo{Path: "/app/"},
+	})
+	require.NoError(t, secretProvider.StoreSecrets(signingSecretPath, secrets))
+
+	return &appcontext.Context{
+		LoggingClient:  logClient,
+		SecretProvider: secretProvider,
+	}, secretProvider
+}
+
+func encodePEM(t *testing.T, blockType string, der []byte, err error) string {
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+}
+
+func newSigningKeys(t *testing.T, algorithm string) map[string]string {
+	switch algorithm {
+	case SigningAlgorithmEd25519:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+		privatePEM := encodePEM(t, "PRIVATE KEY", privateDER, err)
+		publicDER, err := x509.MarshalPKIXPublicKey(public)
+		publicPEM := encodePEM(t, "PUBLIC KEY", publicDER, err)
+		return map[string]string{signingPrivateName: privatePEM, signingPublicName: publicPEM}
+
+	case SigningAlgorithmECDSA:
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		privateDER, err := x509.MarshalECPrivateKey(private)
+		privatePEM := encodePEM(t, "EC PRIVATE KEY", privateDER, err)
+		publicDER, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+		publicPEM := encodePEM(t, "PUBLIC KEY", publicDER, err)
+		return map[string]string{signingPrivateName: privatePEM, signingPublicName: publicPEM}
+
+	default:
+		return map[string]string{signingPrivateName: "shared-secret", signingPublicName: "shared-secret"}
+	}
+}
+
+func TestNewSigning(t *testing.T) {
+	tests := []struct {
+		Name         string
+		Algorithm    string
+		Mode         string
+		ExpectedMode string
+		ExpectError  bool
+	}{
+		{"Default mode", SigningAlgorithmHMACSHA256, "", SignatureModeHeader, false},
+		{"Header mode", SigningAlgorithmEd25519, "Header", SignatureModeHeader, false},
+		{"Wrapper mode", SigningAlgorithmECDSA, SignatureModeWrapper, SignatureModeWrapper, false},
+		{"Bad algorithm", "MD5", "", "", true},
+		{"Bad mode", SigningAlgorithmHMACSHA256, "body", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			signing, err := NewSigning(test.Algorithm, test.Mode, signingSecretPath, signingPrivateName)
+			if test.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedMode, signing.Mode)
+		})
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	algorithms := []string{SigningAlgorithmHMACSHA256, SigningAlgorithmEd25519, SigningAlgorithmECDSA}
+	modes := []string{SignatureModeHeader, SignatureModeWrapper}
+
+	for _, algorithm := range algorithms {
+		for _, mode := range modes {
+			t.Run(algorithm+" "+mode, func(t *testing.T) {
+				edgexContext, _ := newSigningContext(t, newSigningKeys(t, algorithm))
+
+				signer, err := NewSigning(algorithm, mode, signingSecretPath, signingPrivateName)
+				require.NoError(t, err)
+				verifier, err := NewSigning(algorithm, mode, signingSecretPath, signingPublicName)
+				require.NoError(t, err)
+
+				continuePipeline, signed := signer.SignData(edgexContext, plainString)
+				require.True(t, continuePipeline, signed)
+
+				if mode == SignatureModeHeader {
+					assert.Equal(t, plainString, string(signed.([]byte)))
+					_, ok := edgexContext.GetValue(SignatureKey)
+					assert.True(t, ok)
+					algorithmValue, _ := edgexContext.GetValue(SignatureAlgorithmKey)
+					assert.Equal(t, algorithm, algorithmValue)
+				} else {
+					assert.Equal(t, clients.ContentTypeJSON, edgexContext.ResponseContentType)
+				}
+
+				continuePipeline, verified := verifier.VerifySignature(edgexContext, signed)
+				require.True(t, continuePipeline, verified)
+				assert.Equal(t, plainString, string(verified.([]byte)))
+
+				_, ok := edgexContext.GetValue(SignatureKey)
+				assert.False(t, ok, "signature should be removed once verified")
+			})
+		}
+	}
+}
+
+func TestVerifySignatureRejectsTamperedData(t *testing.T) {
+	algorithms := []string{SigningAlgorithmHMACSHA256, SigningAlgorithmEd25519, SigningAlgorithmECDSA}
+
+	for _, algorithm := range algorithms {
+		t.Run(algorithm+" header", func(t *testing.T) {
+			edgexContext, _ := newSigningContext(t, newSigningKeys(t, algorithm))
+			signer, _ := NewSigning(algorithm, SignatureModeHeader, signingSecretPath, signingPrivateName)
+			verifier, _ := NewSigning(algorithm, SignatureModeHeader, signingSecretPath, signingPublicName)
+
+			continuePipeline, _ := signer.SignData(edgexContext, plainString)
+			require.True(t, continuePipeline)
+
+			continuePipeline, result := verifier.VerifySignature(edgexContext, plainString+"tampered")
+			assert.False(t, continuePipeline)
+			assert.EqualError(t, result.(error), "signature verification failed")
+		})
+
+		t.Run(algorithm+" wrapper", func(t *testing.T) {
+			edgexContext, _ := newSigningContext(t, newSigningKeys(t, algorithm))
+			signer, _ := NewSigning(algorithm, SignatureModeWrapper, signingSecretPath, signingPrivateName)
+			verifier, _ := NewSigning(algorithm, SignatureModeWrapper, signingSecretPath, signingPublicName)
+
+			continuePipeline, signed := signer.SignData(edgexContext, plainString)
+			require.True(t, continuePipeline)
+
+			wrapper := SignedData{}
+			require.NoError(t, json.Unmarshal(signed.([]byte), &wrapper))
+			wrapper.Payload = []byte(plainString + "tampered")
+			tampered, _ := json.Marshal(wrapper)
+
+			continuePipeline, result := verifier.VerifySignature(edgexContext, tampered)
+			assert.False(t, continuePipeline)
+			assert.EqualError(t, result.(error), "signature verification failed")
+		})
+	}
+}
+
+func TestVerifySignatureRejectsUnsignedData(t *testing.T) {
+	edgexContext, _ := newSigningContext(t, newSigningKeys(t, SigningAlgorithmHMACSHA256))
+
+	verifier, _ := NewSigning(SigningAlgorithmHMACSHA256, SignatureModeHeader, signingSecretPath, signingPublicName)
+	continuePipeline, result := verifier.VerifySignature(edgexContext, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "data is not signed, no signature found")
+
+	verifier, _ = NewSigning(SigningAlgorithmHMACSHA256, SignatureModeWrapper, signingSecretPath, signingPublicName)
+	continuePipeline, result = verifier.VerifySignature(edgexContext, `{"Payload":"dGVzdA=="}`)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "data is not signed, no signature found")
+}
+
+func TestVerifySignatureRejectsWrongAlgorithm(t *testing.T) {
+	edgexContext, _ := newSigningContext(t, newSigningKeys(t, SigningAlgorithmHMACSHA256))
+	signer, _ := NewSigning(SigningAlgorithmHMACSHA256, SignatureModeHeader, signingSecretPath, signingPrivateName)
+	verifier, _ := NewSigning(SigningAlgorithmEd25519, SignatureModeHeader, signingSecretPath, signingPublicName)
+
+	continuePipeline, signed := signer.SignData(edgexContext, plainString)
+	require.True(t, continuePipeline)
+
+	continuePipeline, result := verifier.VerifySignature(edgexContext, signed)
+	assert.False(t, continuePipeline)
+	assert.Contains(t, result.(error).Error(), "data signed with 'HMAC-SHA256'")
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	edgexContext, secretProvider := newSigningContext(t, newSigningKeys(t, SigningAlgorithmHMACSHA256))
+	signer, _ := NewSigning(SigningAlgorithmHMACSHA256, SignatureModeHeader, signingSecretPath, signingPrivateName)
+
+	continuePipeline, _ := signer.SignData(edgexContext, plainString)
+	require.True(t, continuePipeline)
+	signature1, _ := edgexContext.GetValue(SignatureKey)
+
+	require.NoError(t, secretProvider.StoreSecrets(signingSecretPath, map[string]string{signingPrivateName: "rotated-secret"}))
+	secretProvider.InsecureSecretsUpdated()
+
+	continuePipeline, _ = signer.SignData(edgexContext, plainString)
+	require.True(t, continuePipeline)
+	signature2, _ := edgexContext.GetValue(SignatureKey)
+
+	assert.NotEqual(t, signature1, signature2)
+}
+
+func TestSigningBadKey(t *testing.T) {
+	edgexContext, _ := newSigningContext(t, map[string]string{signingPrivateName: "not a PEM key"})
+	signer, _ := NewSigning(SigningAlgorithmEd25519, SignatureModeHeader, signingSecretPath, signingPrivateName)
+
+	continuePipeline, result := signer.SignData(edgexContext, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Ed25519 key must be PEM encoded")
+
+	// An ECDSA key can't be used for Ed25519
+	ecdsaKeys := newSigningKeys(t, SigningAlgorithmECDSA)
+	edgexContext, _ = newSigningContext(t, ecdsaKeys)
+	signer, _ = NewSigning(SigningAlgorithmEd25519, SignatureModeHeader, signingSecretPath, signingPrivateName)
+
+	continuePipeline, result = signer.SignData(edgexContext, plainString)
+	assert.False(t, continuePipeline)
+	assert.Contains(t, result.(error).Error(), "can not be used for Ed25519")
+}
+
+func TestSignDataNoData(t *testing.T) {
+	edgexContext, _ := newSigningContext(t, newSigningKeys(t, SigningAlgorithmHMACSHA256))
+	signing, _ := NewSigning(SigningAlgorithmHMACSHA256, "", signingSecretPath, signingPrivateName)
+
+	continuePipeline, result := signing.SignData(edgexContext)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "no data received to sign")
+
+	continuePipeline, result = signing.VerifySignature(edgexContext)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "no data received to verify")
+}
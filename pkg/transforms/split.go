@@ -0,0 +1,72 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+)
+
+// EventSplitter splits Events into single reading Events
+type EventSplitter struct {
+}
+
+// NewEventSplitter creates, initializes and returns a new instance of EventSplitter
+func NewEventSplitter() EventSplitter {
+	return EventSplitter{}
+}
+
+// SplitByReading splits the Event into an Event for each of its readings, with the rest of the pipeline executed once
+// for each of the Events. The Events keep the received Event's device, origin and tags. Each Event gets its own ID,
+// the received Event's ID followed by the reading's index, i.e. "<id>-0", so Deduplicate keyed by eventid after the
+// split doesn't drop them as duplicates of each other, while still dropping them when the Event is received again.
+// The items share the received Event's checksum and correlation ID though, so Deduplicate keyed by checksum or
+// correlationid must come before the split. The pipeline is stopped when the Event has no readings.
+// It will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (splitter EventSplitter) SplitByReading(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("Unexpected type received")
+	}
+
+	if len(event.Readings) == 0 {
+		edgexcontext.LoggingClient.Debug("Event has no readings to split")
+		return false, nil
+	}
+
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Splitting Event into %d Events", len(event.Readings)))
+
+	result := make(appcontext.SplitResult, len(event.Readings))
+	for index, reading := range event.Readings {
+		split := event
+		split.Readings = []models.Reading{reading}
+		split.Tags = copyTags(event.Tags)
+		if len(event.ID) > 0 {
+			split.ID = fmt.Sprintf("%s-%d", event.ID, index)
+		}
+		result[index] = split
+	}
+
+	return true, result
+}
@@ -0,0 +1,104 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitByReading(t *testing.T) {
+	event := models.Event{
+		ID:     "event1",
+		Device: devID1,
+		Origin: 1000,
+		Tags:   map[string]string{"site": "north"},
+		Readings: []models.Reading{
+			{Device: devID1, Name: "temperature", Value: "20"},
+			{Device: devID1, Name: "humidity", Value: "40"},
+		},
+	}
+
+	continuePipeline, result := NewEventSplitter().SplitByReading(context, event)
+	require.True(t, continuePipeline)
+
+	split, ok := result.(appcontext.SplitResult)
+	require.True(t, ok, "result should be a SplitResult")
+	require.Len(t, split, 2)
+
+	for index, item := range split {
+		splitEvent := item.(models.Event)
+		assert.Equal(t, fmt.Sprintf("event1-%d", index), splitEvent.ID)
+		assert.Equal(t, devID1, splitEvent.Device)
+		assert.Equal(t, int64(1000), splitEvent.Origin)
+		assert.Equal(t, event.Tags, splitEvent.Tags)
+		assert.Equal(t, []models.Reading{event.Readings[index]}, splitEvent.Readings)
+	}
+
+	// The split Events don't share the received Event's tags
+	split[0].(models.Event).Tags["site"] = "south"
+	assert.Equal(t, "north", event.Tags["site"])
+}
+
+func TestSplitByReadingDeduplicate(t *testing.T) {
+	event := models.Event{
+		ID:     "event1",
+		Device: devID1,
+		Readings: []models.Reading{
+			{Device: devID1, Name: "temperature", Value: "20"},
+			{Device: devID1, Name: "humidity", Value: "40"},
+		},
+	}
+
+	dedup, err := NewDeduplication("1h", DeduplicationKeyEventID, 0)
+	require.NoError(t, err)
+	splitContext := &appcontext.Context{EventID: event.ID, LoggingClient: context.LoggingClient}
+
+	deduplicate := func() []bool {
+		_, result := NewEventSplitter().SplitByReading(splitContext, event)
+		var passed []bool
+		for _, item := range result.(appcontext.SplitResult) {
+			continuePipeline, _ := dedup.Deduplicate(splitContext, item)
+			passed = append(passed, continuePipeline)
+		}
+		return passed
+	}
+
+	assert.Equal(t, []bool{true, true}, deduplicate(), "split Events should not be duplicates of each other")
+	assert.Equal(t, []bool{false, false}, deduplicate(), "split Events of a redelivered Event should be duplicates")
+}
+
+func TestSplitByReadingNoReadings(t *testing.T) {
+	continuePipeline, result := NewEventSplitter().SplitByReading(context, models.Event{Device: devID1})
+	assert.False(t, continuePipeline)
+	assert.Nil(t, result)
+}
+
+func TestSplitByReadingErrors(t *testing.T) {
+	continuePipeline, result := NewEventSplitter().SplitByReading(context)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "No Event Received")
+
+	continuePipeline, result = NewEventSplitter().SplitByReading(context, plainString)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "Unexpected type received")
+}
@@ -17,8 +17,11 @@
 package transforms
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strings"
+	"text/template"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 
@@ -28,6 +31,22 @@ import (
 // Tags contains the list of Tag key/values
 type Tags struct {
 	tags map[string]string
+	// templates are the tag values rendered for each Event, keyed by tag
+	templates map[string]*template.Template
+}
+
+// TagTemplateData is the data the templated tag values are rendered with
+type TagTemplateData struct {
+	// Device is the name of the Event's device
+	Device string
+	// ReadingCount is the number of readings in the Event
+	ReadingCount int
+	// ReceivedTopic is the topic the message was received on, empty for triggers without topics
+	ReceivedTopic string
+	// CorrelationID is the correlation id of the message being processed
+	CorrelationID string
+	// Event is the received Event
+	Event models.Event
 }
 
 // NewTags creates, initializes and returns a new instance of Tags
@@ -37,7 +56,38 @@ func NewTags(tags map[string]string) Tags {
 	}
 }
 
-// AddTags adds the pre-configured list of tags to the Event's tags collection.
+// NewTemplatedTags creates, initializes and returns a new instance of Tags where the values containing "{{" are
+// Go text/templates rendered for each Event with the TagTemplateData, i.e. {{.Device}}, {{.ReadingCount}} or
+// {{.ReceivedTopic}}. The functions available to Template are also available, i.e. {{now "2006-01-02"}},
+// {{env "SITE_ID"}}, {{setting "Region"}} and {{secret "tenant" "id"}}.
+func NewTemplatedTags(tags map[string]string) (Tags, error) {
+	staticTags := make(map[string]string)
+	templates := make(map[string]*template.Template)
+
+	for tag, value := range tags {
+		if !strings.Contains(value, "{{") {
+			staticTags[tag] = value
+			continue
+		}
+
+		parsed, err := template.New(tag).
+			Option("missingkey=error").
+			Funcs(templateFuncs(nil)).
+			Parse(value)
+		if err != nil {
+			return Tags{}, fmt.Errorf("unable to parse template of tag '%s': %s", tag, err.Error())
+		}
+		templates[tag] = parsed
+	}
+
+	return Tags{
+		tags:      staticTags,
+		templates: templates,
+	}, nil
+}
+
+// AddTags adds the pre-configured list of tags to the Event's tags collection. Templated tag values are rendered
+// for the Event, a value which fails to render stops the pipeline with an error.
 func (t *Tags) AddTags(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
 	edgexcontext.LoggingClient.Debug("Adding tags to Event")
 
@@ -50,7 +100,12 @@ func (t *Tags) AddTags(edgexcontext *appcontext.Context, params ...interface{})
 		return false, errors.New("type received is not an Event")
 	}
 
-	if len(t.tags) > 0 {
+	if len(t.tags) > 0 || len(t.templates) > 0 {
+		rendered, err := t.renderTemplates(edgexcontext, event)
+		if err != nil {
+			return false, err
+		}
+
 		if event.Tags == nil {
 			event.Tags = make(map[string]string)
 		}
@@ -58,6 +113,10 @@ func (t *Tags) AddTags(edgexcontext *appcontext.Context, params ...interface{})
 		for tag, value := range t.tags {
 			event.Tags[tag] = value
 		}
+
+		for tag, value := range rendered {
+			event.Tags[tag] = value
+		}
 		edgexcontext.LoggingClient.Debug(fmt.Sprintf("Tags added to Event. Event tags=%v", event.Tags))
 	} else {
 		edgexcontext.LoggingClient.Debug("No tags added to Event. Add tags list is empty.")
@@ -65,3 +124,98 @@ func (t *Tags) AddTags(edgexcontext *appcontext.Context, params ...interface{})
 
 	return true, event
 }
+
+// renderTemplates renders the templated tag values for the Event
+func (t *Tags) renderTemplates(edgexcontext *appcontext.Context, event models.Event) (map[string]string, error) {
+	if len(t.templates) == 0 {
+		return nil, nil
+	}
+
+	data := TagTemplateData{
+		Device:        event.Device,
+		ReadingCount:  len(event.Readings),
+		ReceivedTopic: edgexcontext.ReceivedTopic,
+		CorrelationID: edgexcontext.CorrelationID,
+		Event:         event,
+	}
+
+	rendered := make(map[string]string, len(t.templates))
+	for tag, tagTemplate := range t.templates {
+		// The context, setting and secret functions are bound to the message being processed
+		executable, err := tagTemplate.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("unable to clone template of tag '%s': %s", tag, err.Error())
+		}
+		executable.Funcs(templateFuncs(edgexcontext))
+
+		buffer := &bytes.Buffer{}
+		if err := executable.Execute(buffer, data); err != nil {
+			return nil, fmt.Errorf("unable to render template of tag '%s': %s", tag, err.Error())
+		}
+		rendered[tag] = buffer.String()
+	}
+
+	return rendered, nil
+}
+
+// TagEditor removes and renames the tags of Events
+type TagEditor struct {
+	remove []string
+	// rename is keyed by the current tag name, the values are the new names
+	rename map[string]string
+}
+
+// NewTagEditor creates, initializes and returns a new instance of TagEditor which removes the tags to remove and
+// renames the tags which are keys of rename to the values of rename.
+func NewTagEditor(remove []string, rename map[string]string) (TagEditor, error) {
+	if len(remove) == 0 && len(rename) == 0 {
+		return TagEditor{}, errors.New("no tags to remove or rename")
+	}
+
+	return TagEditor{
+		remove: remove,
+		rename: rename,
+	}, nil
+}
+
+// EditTags removes the tags to remove from the Event's tags and then renames the tags to rename, replacing any
+// existing tag with the new name. Tags which the Event doesn't have are ignored.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (editor *TagEditor) EditTags(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	edgexcontext.LoggingClient.Debug("Editing Event tags")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	if len(event.Tags) == 0 {
+		return true, event
+	}
+
+	event.Tags = copyTags(event.Tags)
+
+	for _, tag := range editor.remove {
+		delete(event.Tags, tag)
+	}
+
+	renamed := make(map[string]string)
+	for tag, newName := range editor.rename {
+		if value, ok := event.Tags[tag]; ok {
+			renamed[newName] = value
+			delete(event.Tags, tag)
+		}
+	}
+
+	// Renamed tags are added once all are removed so swapping the names of two tags works
+	for tag, value := range renamed {
+		event.Tags[tag] = value
+	}
+
+	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Event tags edited. Event tags=%v", event.Tags))
+	return true, event
+}
@@ -17,15 +17,20 @@
 package transforms
 
 import (
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	bootstrapConfig "github.com/edgexfoundry/go-mod-bootstrap/config"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 
 	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+	"github.com/jcerato/app-functions-sdk-go/internal/security"
 )
 
 var tagsToAdd = map[string]string{
@@ -97,3 +102,139 @@ func TestTags_AddTags(t *testing.T) {
 		})
 	}
 }
+
+func TestTags_AddTemplatedTags(t *testing.T) {
+	configuration := &common.ConfigurationStruct{
+		ApplicationSettings:  map[string]string{"Region": "south"},
+		SecretStoreExclusive: bootstrapConfig.SecretStoreInfo{Path: "/app/"},
+	}
+	secretProvider := security.NewSecretProviderMock(configuration)
+	require.NoError(t, secretProvider.StoreSecrets("tenant", map[string]string{"id": "tenant-42"}))
+	require.NoError(t, os.Setenv("TAGS_TEST_SITE", "plant-1"))
+	defer os.Unsetenv("TAGS_TEST_SITE")
+
+	appContext := appcontext.Context{
+		LoggingClient:  logger.NewMockClient(),
+		CorrelationID:  "123-abc",
+		ReceivedTopic:  "edgex/events/Boiler-01",
+		Configuration:  configuration,
+		SecretProvider: secretProvider,
+	}
+
+	target, err := NewTemplatedTags(map[string]string{
+		"GatewayId": "HoustonStore000123",
+		"device":    "{{.Device}}",
+		"readings":  "{{.ReadingCount}}",
+		"topic":     "{{.ReceivedTopic}} {{.CorrelationID}}",
+		"day":       `{{now "2006-01-02"}}`,
+		"site":      `{{env "TAGS_TEST_SITE"}}`,
+		"region":    `{{setting "Region"}}`,
+		"tenant":    `{{secret "tenant" "id"}}`,
+	})
+	require.NoError(t, err)
+
+	event := models.Event{Device: devID1, Readings: []models.Reading{{Name: "temperature"}, {Name: "humidity"}}}
+	continuePipeline, result := target.AddTags(&appContext, event)
+	require.True(t, continuePipeline)
+
+	expected := map[string]string{
+		"GatewayId": "HoustonStore000123",
+		"device":    devID1,
+		"readings":  "2",
+		"topic":     "edgex/events/Boiler-01 123-abc",
+		"day":       time.Now().UTC().Format("2006-01-02"),
+		"site":      "plant-1",
+		"region":    "south",
+		"tenant":    "tenant-42",
+	}
+	assert.Equal(t, expected, result.(models.Event).Tags)
+}
+
+func TestTags_AddTemplatedTagsErrors(t *testing.T) {
+	_, err := NewTemplatedTags(map[string]string{"device": "{{.Device"})
+	require.Error(t, err)
+
+	appContext := appcontext.Context{
+		LoggingClient: logger.NewMockClient(),
+	}
+
+	tests := []struct {
+		Name  string
+		Value string
+	}{
+		{"Unknown field", "{{.Site}}"},
+		{"Missing environment variable", `{{env "TAGS_TEST_MISSING"}}`},
+		{"Missing setting", `{{setting "Region"}}`},
+		{"No secret provider", `{{secret "tenant" "id"}}`},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.Name, func(t *testing.T) {
+			target, err := NewTemplatedTags(map[string]string{"tag": testCase.Value})
+			require.NoError(t, err)
+
+			continuePipeline, result := target.AddTags(&appContext, models.Event{Device: devID1})
+			assert.False(t, continuePipeline)
+			assert.Contains(t, result.(error).Error(), "unable to render template of tag 'tag'")
+		})
+	}
+}
+
+func TestTagEditor_EditTags(t *testing.T) {
+	appContext := appcontext.Context{
+		LoggingClient: logger.NewMockClient(),
+	}
+	existingTags := map[string]string{"Tag1": "Value1", "Tag2": "Value2"}
+
+	tests := []struct {
+		Name     string
+		Remove   []string
+		Rename   map[string]string
+		Input    map[string]string
+		Expected map[string]string
+	}{
+		{"Remove", []string{"Tag1", "Missing"}, nil, existingTags, map[string]string{"Tag2": "Value2"}},
+		{"Rename", nil, map[string]string{"Tag1": "NewTag1", "Missing": "NewMissing"}, existingTags,
+			map[string]string{"NewTag1": "Value1", "Tag2": "Value2"}},
+		{"Rename replaces existing", nil, map[string]string{"Tag1": "Tag2"}, existingTags,
+			map[string]string{"Tag2": "Value1"}},
+		{"Swap", nil, map[string]string{"Tag1": "Tag2", "Tag2": "Tag1"}, existingTags,
+			map[string]string{"Tag1": "Value2", "Tag2": "Value1"}},
+		{"Remove before rename", []string{"Tag1"}, map[string]string{"Tag1": "NewTag1"}, existingTags,
+			map[string]string{"Tag2": "Value2"}},
+		{"No tags", []string{"Tag1"}, nil, nil, nil},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.Name, func(t *testing.T) {
+			target, err := NewTagEditor(testCase.Remove, testCase.Rename)
+			require.NoError(t, err)
+
+			continuePipeline, result := target.EditTags(&appContext, models.Event{Tags: testCase.Input})
+			require.True(t, continuePipeline)
+			assert.Equal(t, testCase.Expected, result.(models.Event).Tags)
+		})
+	}
+
+	assert.Equal(t, map[string]string{"Tag1": "Value1", "Tag2": "Value2"}, existingTags,
+		"tags of the received event should not be modified")
+}
+
+func TestTagEditor_EditTagsErrors(t *testing.T) {
+	_, err := NewTagEditor(nil, nil)
+	require.Error(t, err)
+
+	appContext := appcontext.Context{
+		LoggingClient: logger.NewMockClient(),
+	}
+	target, err := NewTagEditor([]string{"Tag1"}, nil)
+	require.NoError(t, err)
+
+	continuePipeline, result := target.EditTags(&appContext)
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "no Event Received")
+
+	continuePipeline, result = target.EditTags(&appContext, "Not an Event")
+	assert.False(t, continuePipeline)
+	assert.EqualError(t, result.(error), "type received is not an Event")
+}
@@ -0,0 +1,245 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+// TemplateData is the data the template is rendered with
+type TemplateData struct {
+	// Data is the models.Event when an Event is received, otherwise the received data unmarshaled from JSON,
+	// or the received data as a string when it isn't JSON.
+	Data interface{}
+	// CorrelationID is the correlation id of the message being processed
+	CorrelationID string
+}
+
+// Template reshapes the data from the previous function by rendering it through a Go text/template.
+// Besides the text/template built-in functions the following functions are available to the template:
+//
+//	formatTime <layout> <nanoseconds>    formats a Unix time in nanoseconds, e.g. an Event's Origin, using the Go layout
+//	formatMillis <layout> <milliseconds> formats a Unix time in milliseconds, e.g. an Event's Created, using the Go layout
+//	now <layout>                         formats the current time using the Go layout
+//	json <value>                         marshals the value to JSON
+//	jsonEscape <string>                  escapes the string for use inside a JSON string, without the quotes
+//	context <key>                        returns the context value for the key, i.e. metadata added to the context
+//	setting <key>                        returns the ApplicationSettings value for the key
+//	env <name>                           returns the value of the environment variable
+//	secret <path> <key>                  returns the secret for the key from the secret path
+//
+// The times may be an Event's fields, numbers from JSON data or strings of digits.
+// Referencing a missing map key stops the pipeline with an error rather than rendering "<no value>".
+type Template struct {
+	// ContentType is set as the ResponseContentType, defaults to application/json
+	ContentType string
+
+	template *template.Template
+}
+
+// NewTemplate creates, initializes and returns a new instance of Template from the template text
+func NewTemplate(text string, contentType string) (*Template, error) {
+	if len(strings.TrimSpace(text)) == 0 {
+		return nil, errors.New("template must not be empty")
+	}
+
+	parsed, err := template.New("transform").
+		Option("missingkey=error").
+		Funcs(templateFuncs(nil)).
+		Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse template: %s", err.Error())
+	}
+
+	if len(contentType) == 0 {
+		contentType = clients.ContentTypeJSON
+	}
+
+	return &Template{
+		ContentType: contentType,
+		template:    parsed,
+	}, nil
+}
+
+// NewTemplateFromFile creates, initializes and returns a new instance of Template from the template file
+func NewTemplateFromFile(path string, contentType string) (*Template, error) {
+	text, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template file '%s': %s", path, err.Error())
+	}
+
+	return NewTemplate(string(text), contentType)
+}
+
+// RenderTemplate renders the Event, or other data, from the previous function through the template and
+// returns the result as a []byte.
+// It will return an error and stop the pipeline if no data is received or the template fails to render.
+func (t *Template) RenderTemplate(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("No Data Received")
+	}
+	edgexcontext.LoggingClient.Debug("Rendering template")
+
+	templateData := TemplateData{
+		CorrelationID: edgexcontext.CorrelationID,
+	}
+
+	if event, ok := params[0].(models.Event); ok {
+		templateData.Data = event
+	} else {
+		data, err := util.CoerceType(params[0])
+		if err != nil {
+			return false, err
+		}
+
+		// Numbers are kept as json.Number so large integers, i.e. timestamps, aren't rounded to float64
+		var unmarshaled interface{}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&unmarshaled); err == nil && !decoder.More() {
+			templateData.Data = unmarshaled
+		} else {
+			templateData.Data = string(data)
+		}
+	}
+
+	// The context and setting functions are bound to the message being processed
+	executable, err := t.template.Clone()
+	if err != nil {
+		return false, fmt.Errorf("unable to clone template: %s", err.Error())
+	}
+	executable.Funcs(templateFuncs(edgexcontext))
+
+	buffer := &bytes.Buffer{}
+	if err := executable.Execute(buffer, templateData); err != nil {
+		return false, fmt.Errorf("unable to render template: %s", err.Error())
+	}
+
+	edgexcontext.ResponseContentType = t.ContentType
+	return true, buffer.Bytes()
+}
+
+func templateFuncs(edgexcontext *appcontext.Context) template.FuncMap {
+	return template.FuncMap{
+		"formatTime": func(layout string, nanoseconds interface{}) (string, error) {
+			value, err := templateInt64(nanoseconds)
+			if err != nil {
+				return "", err
+			}
+			return time.Unix(0, value).UTC().Format(layout), nil
+		},
+		"formatMillis": func(layout string, milliseconds interface{}) (string, error) {
+			value, err := templateInt64(milliseconds)
+			if err != nil {
+				return "", err
+			}
+			return time.Unix(0, value*int64(time.Millisecond)).UTC().Format(layout), nil
+		},
+		"now": func(layout string) string {
+			return time.Now().UTC().Format(layout)
+		},
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"jsonEscape": func(value string) (string, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(data[1 : len(data)-1]), nil
+		},
+		"context": func(key string) string {
+			if edgexcontext == nil {
+				return ""
+			}
+			value, _ := edgexcontext.GetValue(key)
+			return value
+		},
+		"setting": func(key string) (string, error) {
+			if edgexcontext == nil || edgexcontext.Configuration == nil {
+				return "", fmt.Errorf("ApplicationSettings not available for setting '%s'", key)
+			}
+			value, ok := edgexcontext.Configuration.ApplicationSettings[key]
+			if !ok {
+				return "", fmt.Errorf("setting '%s' not found in ApplicationSettings", key)
+			}
+			return value, nil
+		},
+		"env": func(name string) (string, error) {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				return "", fmt.Errorf("environment variable '%s' not set", name)
+			}
+			return value, nil
+		},
+		"secret": func(path string, key string) (string, error) {
+			if edgexcontext == nil || edgexcontext.SecretProvider == nil {
+				return "", fmt.Errorf("secrets not available for secret '%s'", key)
+			}
+			secrets, err := edgexcontext.GetSecrets(path, key)
+			if err != nil {
+				return "", fmt.Errorf("unable to get secret '%s' from '%s': %s", key, path, err.Error())
+			}
+			return secrets[key], nil
+		},
+	}
+}
+
+// templateInt64 converts a template value, i.e. an Event's int64 Origin or a json.Number from the received data,
+// to an int64
+func templateInt64(value interface{}) (int64, error) {
+	switch typed := value.(type) {
+	case int64:
+		return typed, nil
+	case int:
+		return int64(typed), nil
+	case json.Number:
+		if integer, err := typed.Int64(); err == nil {
+			return integer, nil
+		}
+		float, err := typed.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert '%s' to a time: %s", typed, err.Error())
+		}
+		return int64(float), nil
+	case float64:
+		return int64(typed), nil
+	case string:
+		integer, err := strconv.ParseInt(typed, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert '%s' to a time: %s", typed, err.Error())
+		}
+		return integer, nil
+	default:
+		return 0, fmt.Errorf("unable to convert %T to a time", value)
+	}
+}
@@ -0,0 +1,194 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/internal/common"
+)
+
+func newTemplateContext() *appcontext.Context {
+	edgexContext := &appcontext.Context{
+		CorrelationID: "correlation-id",
+		LoggingClient: logClient,
+		Configuration: &common.ConfigurationStruct{
+			ApplicationSettings: map[string]string{"site": "north"},
+		},
+	}
+	edgexContext.AddValue("source", "line-2")
+	return edgexContext
+}
+
+func TestNewTemplate(t *testing.T) {
+	tests := []struct {
+		Name                string
+		Text                string
+		ContentType         string
+		ExpectedContentType string
+		ExpectError         bool
+	}{
+		{"Default content type", "{{ .Data }}", "", clients.ContentTypeJSON, false},
+		{"Specified content type", "{{ .Data }}", clients.ContentTypeText, clients.ContentTypeText, false},
+		{"Empty template", "  ", "", "", true},
+		{"Bad template", "{{ .Data ", "", "", true},
+		{"Unknown function", "{{ bogus .Data }}", "", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			transform, err := NewTemplate(test.Text, test.ContentType)
+			if test.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectedContentType, transform.ContentType)
+		})
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	event := models.Event{
+		Device:  devID1,
+		Origin:  1577836800000000000,
+		Created: 1577836800000,
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "21"},
+			{Name: "note", Value: `say "hi"`},
+		},
+	}
+
+	tests := []struct {
+		Name     string
+		Text     string
+		Data     interface{}
+		Expected string
+	}{
+		{
+			"Event fields and readings",
+			`{"id":"{{ .Data.Device }}","values":{ {{- range $i, $r := .Data.Readings }}{{ if $i }},{{ end }}"{{ $r.Name }}":{{ json $r.Value }}{{ end -}} }}`,
+			event,
+			`{"id":"id1","values":{"temperature":"21","note":"say \"hi\""}}`,
+		},
+		{
+			"Time formatting",
+			`{{ formatTime "2006-01-02T15:04:05Z07:00" .Data.Origin }} {{ formatMillis "2006-01-02" .Data.Created }}`,
+			event,
+			`2020-01-01T00:00:00Z 2020-01-01`,
+		},
+		{
+			"JSON escaping",
+			`"{{ jsonEscape (index .Data.Readings 1).Value }}"`,
+			event,
+			`"say \"hi\""`,
+		},
+		{
+			"Context, settings and correlation id",
+			`{{ context "source" }} {{ setting "site" }} {{ .CorrelationID }}`,
+			event,
+			`line-2 north correlation-id`,
+		},
+		{
+			"JSON data",
+			`{{ .Data.name }}={{ .Data.value }}`,
+			[]byte(`{"name":"temperature","value":21}`),
+			`temperature=21`,
+		},
+		{
+			"JSON data time formatting",
+			`{{ formatTime "2006-01-02T15:04:05.000000000Z" .Data.origin }} {{ formatMillis "2006-01-02" .Data.created }} {{ .Data.origin }}`,
+			[]byte(`{"origin":1577836800123456789,"created":1577836800000}`),
+			`2020-01-01T00:00:00.123456789Z 2020-01-01 1577836800123456789`,
+		},
+		{
+			"Non JSON data",
+			`[{{ .Data }}]`,
+			"plain text",
+			`[plain text]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			transform, err := NewTemplate(test.Text, "")
+			require.NoError(t, err)
+
+			edgexContext := newTemplateContext()
+			continuePipeline, result := transform.RenderTemplate(edgexContext, test.Data)
+			require.True(t, continuePipeline, result)
+			assert.Equal(t, test.Expected, string(result.([]byte)))
+			assert.Equal(t, clients.ContentTypeJSON, edgexContext.ResponseContentType)
+		})
+	}
+}
+
+func TestRenderTemplateFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.tmpl")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"device":"{{ .Data.Device }}"}`), 0644))
+
+	transform, err := NewTemplateFromFile(path, "")
+	require.NoError(t, err)
+
+	continuePipeline, result := transform.RenderTemplate(newTemplateContext(), models.Event{Device: devID2})
+	require.True(t, continuePipeline, result)
+	assert.Equal(t, `{"device":"id2"}`, string(result.([]byte)))
+
+	_, err = NewTemplateFromFile(filepath.Join(t.TempDir(), "missing.tmpl"), "")
+	assert.Error(t, err)
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	tests := []struct {
+		Name string
+		Text string
+	}{
+		{"Missing map key", `{{ .Data.missing }}`},
+		{"Missing setting", `{{ setting "missing" }}`},
+		{"Time not a number", `{{ formatTime "2006-01-02" .Data.name }}`},
+		{"Time wrong type", `{{ formatMillis "2006-01-02" .Data }}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			transform, err := NewTemplate(test.Text, "")
+			require.NoError(t, err)
+
+			continuePipeline, result := transform.RenderTemplate(newTemplateContext(), []byte(`{"name":"temperature"}`))
+			assert.False(t, continuePipeline)
+			assert.Contains(t, result.(error).Error(), "unable to render template")
+		})
+	}
+}
+
+func TestRenderTemplateNoData(t *testing.T) {
+	transform, err := NewTemplate("{{ .Data }}", "")
+	require.NoError(t, err)
+
+	continuePipeline, result := transform.RenderTemplate(newTemplateContext())
+	assert.False(t, continuePipeline)
+	assert.Equal(t, "No Data Received", result.(error).Error())
+}
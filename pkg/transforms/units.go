@@ -0,0 +1,102 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import "fmt"
+
+// unit converts a value to and from the base unit of its quantity: base = value * factor + offset
+type unit struct {
+	quantity string
+	factor   float64
+	offset   float64
+}
+
+const (
+	quantityTemperature = "temperature"
+	quantityPressure    = "pressure"
+	quantityLength      = "length"
+	quantityMass        = "mass"
+	quantitySpeed       = "speed"
+	quantityVolume      = "volume"
+)
+
+// units are the supported units, keyed by the names they can be specified with. The base units are
+// Kelvin, Pascal, metre, kilogram, metres per second and litre.
+var units = map[string]unit{
+	"K":    {quantityTemperature, 1, 0},
+	"degC": {quantityTemperature, 1, 273.15},
+	"°C":   {quantityTemperature, 1, 273.15},
+	"C":    {quantityTemperature, 1, 273.15},
+	"degF": {quantityTemperature, 5.0 / 9.0, 273.15 - 32*5.0/9.0},
+	"°F":   {quantityTemperature, 5.0 / 9.0, 273.15 - 32*5.0/9.0},
+	"F":    {quantityTemperature, 5.0 / 9.0, 273.15 - 32*5.0/9.0},
+
+	"Pa":   {quantityPressure, 1, 0},
+	"hPa":  {quantityPressure, 100, 0},
+	"kPa":  {quantityPressure, 1000, 0},
+	"MPa":  {quantityPressure, 1000000, 0},
+	"mbar": {quantityPressure, 100, 0},
+	"bar":  {quantityPressure, 100000, 0},
+	"psi":  {quantityPressure, 6894.757293168, 0},
+	"atm":  {quantityPressure, 101325, 0},
+
+	"mm": {quantityLength, 0.001, 0},
+	"cm": {quantityLength, 0.01, 0},
+	"m":  {quantityLength, 1, 0},
+	"km": {quantityLength, 1000, 0},
+	"in": {quantityLength, 0.0254, 0},
+	"ft": {quantityLength, 0.3048, 0},
+	"mi": {quantityLength, 1609.344, 0},
+
+	"g":  {quantityMass, 0.001, 0},
+	"kg": {quantityMass, 1, 0},
+	"lb": {quantityMass, 0.45359237, 0},
+	"oz": {quantityMass, 0.028349523125, 0},
+
+	"m/s":  {quantitySpeed, 1, 0},
+	"km/h": {quantitySpeed, 1000.0 / 3600.0, 0},
+	"mph":  {quantitySpeed, 0.44704, 0},
+	"kn":   {quantitySpeed, 1852.0 / 3600.0, 0},
+
+	"mL":  {quantityVolume, 0.001, 0},
+	"L":   {quantityVolume, 1, 0},
+	"m3":  {quantityVolume, 1000, 0},
+	"gal": {quantityVolume, 3.785411784, 0},
+}
+
+// unitConverter returns a function converting values from one unit to the other. An error is returned if
+// either unit isn't supported or the units are of different quantities, i.e. temperature and pressure.
+func unitConverter(from string, to string) (func(float64) float64, error) {
+	fromUnit, ok := units[from]
+	if !ok {
+		return nil, fmt.Errorf("unsupported unit '%s'", from)
+	}
+
+	toUnit, ok := units[to]
+	if !ok {
+		return nil, fmt.Errorf("unsupported unit '%s'", to)
+	}
+
+	if fromUnit.quantity != toUnit.quantity {
+		return nil, fmt.Errorf("unable to convert %s from '%s' to %s '%s'", fromUnit.quantity, from, toUnit.quantity, to)
+	}
+
+	return func(value float64) float64 {
+		base := value*fromUnit.factor + fromUnit.offset
+		return (base - toUnit.offset) / toUnit.factor
+	}, nil
+}
@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/jcerato/app-functions-sdk-go/appcontext"
+	"github.com/jcerato/app-functions-sdk-go/pkg/util"
+)
+
+// valueOperators are the supported comparison operators, two character operators first so they are matched
+// before their one character prefixes
+var valueOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// ValueCondition compares the numeric value of the readings with the ReadingName, or of all readings when the
+// ReadingName is "*", to the Value using the Operator, one of >, >=, <, <=, == or !=
+type ValueCondition struct {
+	ReadingName string
+	Operator    string
+	Value       float64
+}
+
+// ParseValueConditions parses a comma separated list of conditions in the form "readingname operator value",
+// i.e. "temperature > 80, humidity <= 20"
+func ParseValueConditions(conditions string) ([]ValueCondition, error) {
+	var result []ValueCondition
+
+	for _, text := range util.DeleteEmptyAndTrim(strings.FieldsFunc(conditions, util.SplitComma)) {
+		condition, err := parseValueCondition(text)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, condition)
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no value conditions specified")
+	}
+
+	return result, nil
+}
+
+func parseValueCondition(text string) (ValueCondition, error) {
+	for _, operator := range valueOperators {
+		index := strings.Index(text, operator)
+		if index < 0 {
+			continue
+		}
+
+		condition := ValueCondition{
+			ReadingName: strings.TrimSpace(text[:index]),
+			Operator:    operator,
+		}
+
+		if len(condition.ReadingName) == 0 {
+			return condition, fmt.Errorf("missing reading name in value condition '%s'", text)
+		}
+
+		var err error
+		condition.Value, err = strconv.ParseFloat(strings.TrimSpace(text[index+len(operator):]), 64)
+		if err != nil {
+			return condition, fmt.Errorf("invalid value in value condition '%s'", text)
+		}
+
+		return condition, nil
+	}
+
+	return ValueCondition{}, fmt.Errorf("missing operator in value condition '%s', must be one of %s",
+		text, strings.Join(valueOperators, " "))
+}
+
+func (condition ValueCondition) appliesTo(reading models.Reading) bool {
+	return condition.ReadingName == "*" || condition.ReadingName == reading.Name
+}
+
+func (condition ValueCondition) holds(value float64) bool {
+	switch condition.Operator {
+	case ">":
+		return value > condition.Value
+	case ">=":
+		return value >= condition.Value
+	case "<":
+		return value < condition.Value
+	case "<=":
+		return value <= condition.Value
+	case "==":
+		return value == condition.Value
+	default:
+		return value != condition.Value
+	}
+}
+
+// ValueFilter filters readings by their numeric value
+type ValueFilter struct {
+	Conditions []ValueCondition
+	FilterOut  bool
+}
+
+// NewValueFilter creates, initializes and returns a new instance of ValueFilter
+func NewValueFilter(conditions []ValueCondition, filterOut bool) (*ValueFilter, error) {
+	for _, condition := range conditions {
+		if len(condition.ReadingName) == 0 || !isValueOperator(condition.Operator) {
+			return nil, fmt.Errorf("invalid value condition for reading '%s' with operator '%s'", condition.ReadingName, condition.Operator)
+		}
+	}
+
+	return &ValueFilter{Conditions: conditions, FilterOut: filterOut}, nil
+}
+
+// FilterByValue filters for readings whose numeric value satisfies all of the Conditions for the reading's name,
+// so "temperature >= 10, temperature <= 20" keeps temperatures in the range 10 to 20. Readings without a condition
+// for their name or with a non-numeric value don't match and are removed. When FilterOut is true the matching readings
+// are removed instead. The Event is dropped when no readings remain.
+// This function will return an error and stop the pipeline if a non-edgex event is received or if no data is received.
+func (f *ValueFilter) FilterByValue(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
+
+	edgexcontext.LoggingClient.Debug("Filtering by Value")
+
+	if len(params) < 1 {
+		return false, errors.New("no Event Received")
+	}
+
+	event, ok := params[0].(models.Event)
+	if !ok {
+		return false, errors.New("type received is not an Event")
+	}
+
+	// No conditions, so pass all event and all readings thru, rather than filtering them all out.
+	if len(f.Conditions) == 0 {
+		return true, event
+	}
+
+	return filterEventReadings(edgexcontext, event, f.FilterOut, f.matches)
+}
+
+func (f *ValueFilter) matches(reading models.Reading) bool {
+	applied := false
+	var value float64
+
+	for _, condition := range f.Conditions {
+		if !condition.appliesTo(reading) {
+			continue
+		}
+
+		if !applied {
+			var err error
+			value, err = parseReadingValue(reading)
+			if err != nil {
+				return false
+			}
+			applied = true
+		}
+
+		if !condition.holds(value) {
+			return false
+		}
+	}
+
+	return applied
+}
+
+func isValueOperator(operator string) bool {
+	for _, valueOperator := range valueOperators {
+		if operator == valueOperator {
+			return true
+		}
+	}
+	return false
+}
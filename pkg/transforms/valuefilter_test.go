@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueConditions(t *testing.T) {
+	conditions, err := ParseValueConditions("temperature > 80, humidity<=20,* != -1.5")
+	require.NoError(t, err)
+
+	expected := []ValueCondition{
+		{ReadingName: "temperature", Operator: ">", Value: 80},
+		{ReadingName: "humidity", Operator: "<=", Value: 20},
+		{ReadingName: "*", Operator: "!=", Value: -1.5},
+	}
+	assert.Equal(t, expected, conditions)
+}
+
+func TestParseValueConditionsErrors(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Conditions string
+	}{
+		{"Empty", " , "},
+		{"Missing operator", "temperature 80"},
+		{"Missing reading name", "> 80"},
+		{"Bad value", "temperature > hot"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := ParseValueConditions(test.Conditions)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewValueFilterInvalidOperator(t *testing.T) {
+	_, err := NewValueFilter([]ValueCondition{{ReadingName: "temperature", Operator: "=>", Value: 1}}, false)
+	assert.Error(t, err)
+}
+
+func TestFilterByValue(t *testing.T) {
+	event := models.Event{
+		Device: devID1,
+		Readings: []models.Reading{
+			{Name: "temperature", Value: "85", ValueType: models.ValueTypeInt32},
+			{Name: "temperature", Value: "8.5e+00", ValueType: models.ValueTypeFloat64, FloatEncoding: models.ENotation},
+			{Name: "humidity", Value: "15", ValueType: models.ValueTypeUint8},
+			{Name: "status", Value: "on", ValueType: models.ValueTypeString},
+		},
+	}
+
+	tests := []struct {
+		Name       string
+		Conditions string
+		FilterOut  bool
+		Expected   []string
+	}{
+		{"Greater than", "temperature > 80", false, []string{"temperature"}},
+		{"Range", "temperature >= 5, temperature <= 10", false, []string{"temperature"}},
+		{"Multiple readings", "temperature > 80, humidity < 20", false, []string{"temperature", "humidity"}},
+		{"All readings", "* >= 15", false, []string{"temperature", "humidity"}},
+		{"Filter out", "temperature > 80", true, []string{"temperature", "humidity", "status"}},
+		{"No match", "temperature > 100", false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			conditions, err := ParseValueConditions(test.Conditions)
+			require.NoError(t, err)
+			filter, err := NewValueFilter(conditions, test.FilterOut)
+			require.NoError(t, err)
+
+			continuePipeline, result := filter.FilterByValue(context, event)
+			if test.Expected == nil {
+				assert.False(t, continuePipeline)
+				assert.Nil(t, result)
+				return
+			}
+
+			require.True(t, continuePipeline)
+			assert.Equal(t, test.Expected, readingNames(result.(models.Event)))
+		})
+	}
+}